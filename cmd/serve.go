@@ -24,7 +24,7 @@ var CmdServe = &cobra.Command{
 
 func runWeb(cmd *cobra.Command, args []string) {
 	// 5 注册路由
-	r := routes.Setup(settings.Conf.Mode)
+	r := routes.Setup(settings.Conf.Mode, settings.Conf.TrustedProxies)
 	// 6. 启动服务
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", settings.Conf.Port),
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+// GetAccessTokens: 获取当前用户所有的个人访问令牌
+//
+//	@Summary		获取当前用户所有的个人访问令牌
+//	@Description	只返回令牌的名称、权限范围、开头几位和使用时间， 不会返回令牌本身
+//	@Tags			User
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	[]models.AccessToken
+//	@Router			/user/tokens [get]
+func GetAccessTokens(ctx *gin.Context) {
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+
+	tokens, err := logic.GetAccessTokens(userID)
+	if err != nil {
+		zap.L().Error("logic.GetAccessTokens failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, tokens)
+}
+
+// CreateAccessToken: 创建个人访问令牌
+//
+//	@Summary		创建个人访问令牌
+//	@Description	给脚本和机器人使用， 请求时放在 Authorization: Bearer 中， 令牌只在创建时返回一次
+//	@Tags			User
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			object			body	models.ParamCreateAccessToken	false	"查询参数"
+//	@Param			Authorization	header	string							false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	models.AccessTokenCreated
+//	@Router			/user/tokens [post]
+func CreateAccessToken(ctx *gin.Context) {
+	// 1. 进行参数验证
+	p := new(models.ParamCreateAccessToken)
+	if ok := Validate(ctx, p, ValidateCreateAccessToken); !ok {
+		return
+	}
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+
+	// 2. 创建令牌
+	created, err := logic.CreateAccessToken(userID, p)
+	if err != nil {
+		zap.L().Error("logic.CreateAccessToken failed", zap.Error(err))
+		if errors.Is(err, logic.ErrorAccessTokenLimit) {
+			ResponseError(ctx, CodeAccessTokenLimit)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+
+	// 3. 返回响应
+	ResponseSuccess(ctx, created)
+}
+
+// RevokeAccessToken: 撤销个人访问令牌
+//
+//	@Summary		撤销个人访问令牌
+//	@Description	撤销之后使用该令牌的请求会立即失败
+//	@Tags			User
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	string	true	"令牌id"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/user/tokens/{id} [delete]
+func RevokeAccessToken(ctx *gin.Context) {
+	// 1. 获取参数
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	tokenID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+
+	// 2. 撤销令牌
+	if err := logic.RevokeAccessToken(userID, tokenID); err != nil {
+		zap.L().Error("logic.RevokeAccessToken failed", zap.Error(err))
+		if errors.Is(err, logic.ErrorAccessTokenNotExist) {
+			ResponseError(ctx, CodeAccessTokenNotExist)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+
+	// 3. 返回响应
+	ResponseSuccess(ctx, nil)
+}
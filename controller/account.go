@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+// ExportUserData: 导出当前用户的个人数据
+//
+//	@Summary		导出当前用户的个人数据
+//	@Description	返回zip压缩包， 包括个人信息、帖子、评论、投票记录和登录的设备
+//	@Tags			User
+//	@Accept			application/json
+//	@Produce		application/zip
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{file}	file
+//	@Router			/user/export [get]
+func ExportUserData(ctx *gin.Context) {
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+
+	data, err := logic.ExportUserData(userID)
+	if err != nil {
+		zap.L().Error("logic.ExportUserData failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+
+	filename := fmt.Sprintf("user-%d-%s.zip", userID, time.Now().Format("20060102150405"))
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Data(http.StatusOK, "application/zip", data)
+}
+
+// DeleteAccount: 注销当前账号
+//
+//	@Summary		注销当前账号
+//	@Description	需要再次输入密码， 所有设备会退出登录， 等待期结束之后删除个人信息， 等待期内重新登录会取消注销
+//	@Tags			User
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			object			body	models.ParamDeleteAccount	false	"查询参数"
+//	@Param			Authorization	header	string						false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/user [delete]
+func DeleteAccount(ctx *gin.Context) {
+	// 1. 进行参数验证
+	p := new(models.ParamDeleteAccount)
+	if ok := Validate(ctx, p, ValidateDeleteAccount); !ok {
+		return
+	}
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+
+	// 2. 申请注销
+	at, err := logic.RequestAccountDeletion(userID, p.Password)
+	if err != nil {
+		zap.L().Error("logic.RequestAccountDeletion failed", zap.Error(err))
+		if errors.Is(err, mysql.ErrorPasswordInvalid) {
+			ResponseError(ctx, CodeInvalidPassword)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+
+	// 3. 返回响应
+	ResponseSuccess(ctx, gin.H{
+		"deletion_scheduled_at": at,
+	})
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+// BlockUser: 屏蔽用户
+//
+//	@Summary		屏蔽用户
+//	@Description	看不到对方的帖子和评论， 对方也不能回复、关注自己， 同时会取消双方的关注
+//	@Tags			Users
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	string	true	"用户id"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/users/{id}/block [post]
+func BlockUser(ctx *gin.Context) {
+	setUserBlock(ctx, models.BlockTypeBlock)
+}
+
+// UnblockUser: 取消屏蔽
+//
+//	@Summary		取消屏蔽
+//	@Description	取消屏蔽用户， 没有屏蔽过也会返回成功
+//	@Tags			Users
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	string	true	"用户id"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/users/{id}/block [delete]
+func UnblockUser(ctx *gin.Context) {
+	unsetUserBlock(ctx, models.BlockTypeBlock)
+}
+
+// MuteUser: 静音用户
+//
+//	@Summary		静音用户
+//	@Description	只是看不到对方的帖子和评论， 对方不会受到影响
+//	@Tags			Users
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	string	true	"用户id"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/users/{id}/mute [post]
+func MuteUser(ctx *gin.Context) {
+	setUserBlock(ctx, models.BlockTypeMute)
+}
+
+// UnmuteUser: 取消静音
+//
+//	@Summary		取消静音
+//	@Description	取消静音用户， 没有静音过也会返回成功
+//	@Tags			Users
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	string	true	"用户id"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/users/{id}/mute [delete]
+func UnmuteUser(ctx *gin.Context) {
+	unsetUserBlock(ctx, models.BlockTypeMute)
+}
+
+// GetBlockedUsers: 分页获取屏蔽的人
+//
+//	@Summary		分页获取屏蔽的人
+//	@Description	最近屏蔽的在前面
+//	@Tags			User
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			page_num		query	int		false	"Page number"
+//	@Param			page_size		query	int		false	"Page size"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	[]models.BlockedUser
+//	@Router			/user/blocks [get]
+func GetBlockedUsers(ctx *gin.Context) {
+	getBlockedUsers(ctx, models.BlockTypeBlock)
+}
+
+// GetMutedUsers: 分页获取静音的人
+//
+//	@Summary		分页获取静音的人
+//	@Description	最近静音的在前面
+//	@Tags			User
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			page_num		query	int		false	"Page number"
+//	@Param			page_size		query	int		false	"Page size"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	[]models.BlockedUser
+//	@Router			/user/mutes [get]
+func GetMutedUsers(ctx *gin.Context) {
+	getBlockedUsers(ctx, models.BlockTypeMute)
+}
+
+func setUserBlock(ctx *gin.Context, blockType string) {
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	targetID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	if err = logic.BlockUser(userID, targetID, blockType); err != nil {
+		zap.L().Error("logic.BlockUser failed", zap.String("type", blockType), zap.Error(err))
+		switch {
+		case errors.Is(err, logic.ErrorBlockSelf):
+			ResponseError(ctx, CodeBlockSelf)
+		case errors.Is(err, mysql.ErrorUserNotExist):
+			ResponseError(ctx, CodeUserNotExist)
+		default:
+			ResponseError(ctx, CodeServerBusy)
+		}
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+func unsetUserBlock(ctx *gin.Context, blockType string) {
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	targetID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	if err = logic.UnblockUser(userID, targetID, blockType); err != nil {
+		zap.L().Error("logic.UnblockUser failed", zap.String("type", blockType), zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+func getBlockedUsers(ctx *gin.Context, blockType string) {
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	pageNum, pageSize := getPageInfo(ctx)
+	users, err := logic.GetBlockedUsers(userID, blockType, pageNum, pageSize)
+	if err != nil {
+		zap.L().Error("logic.GetBlockedUsers failed", zap.String("type", blockType), zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, users)
+}
@@ -24,6 +24,47 @@ const (
 	CodeCommunityNotEXist
 	CodeNotPerm
 	CodeCommentNotFound
+	CodeSessionNotExist
+	CodeInvalidTOTPCode
+	CodeTOTPAlreadyEnabled
+	CodeTOTPNotEnabled
+	CodeTOTPNotEnrolled
+	CodeInvalidChallenge
+	CodeTooManyRequests
+	CodeInvalidRevertToken
+	CodeInsufficientScope
+	CodeAccessTokenNotExist
+	CodeAccessTokenLimit
+	CodeOIDCProviderNotExist
+	CodeInvalidOIDCState
+	CodeOIDCLoginFailed
+	CodeVotesHidden
+	CodeFollowSelf
+	CodeBlockSelf
+	CodeBlocked
+	CodeKarmaTooLow
+	CodeAccountTooNew
+	CodeRegistrationClosed
+	CodeInvitationRequired
+	CodeInvalidInvitation
+	CodeInvitationNotExist
+	CodePostNotExist
+	CodeRevisionNotExist
+	CodeNotDeleted
+	CodeRestoreExpired
+	CodePostLocked
+	CodeInvalidPostStatus
+	CodeNotDraft
+	CodeInvalidPublishTime
+	CodeInvalidPostKind
+	CodeInvalidURL
+	CodeDuplicateLink
+	CodeInvalidImages
+	CodeInvalidPoll
+	CodeInvalidPollOption
+	CodePollClosed
+	CodePollVoted
+	CodeOIDCEmailRequired
 )
 
 var codeMsgMap = map[ResCode]string{
@@ -46,6 +87,56 @@ var codeMsgMap = map[ResCode]string{
 	CodeCommunityNotEXist:  "该社区不存在",
 	CodeNotPerm:            "没有操作权限",
 	CodeCommentNotFound:    "没有找到该评论",
+	CodeSessionNotExist:    "登录会话不存在",
+	CodeInvalidTOTPCode:    "两步验证码错误",
+	CodeTOTPAlreadyEnabled: "已经开启了两步验证",
+	CodeTOTPNotEnabled:     "没有开启两步验证",
+	CodeTOTPNotEnrolled:    "请先获取两步验证密钥",
+	CodeInvalidChallenge:   "登录已过期， 请重新登录",
+	CodeTooManyRequests:    "请求过于频繁， 请稍后再试",
+	CodeInvalidRevertToken: "撤销链接无效或已过期",
+
+	CodeInsufficientScope:   "访问令牌没有该操作的权限",
+	CodeAccessTokenNotExist: "访问令牌不存在",
+	CodeAccessTokenLimit:    "访问令牌数量已达上限",
+
+	CodeOIDCProviderNotExist: "不支持该第三方登录",
+	CodeInvalidOIDCState:     "第三方登录已过期， 请重新登录",
+	CodeOIDCLoginFailed:      "第三方登录失败",
+	CodeVotesHidden:          "该用户隐藏了点赞记录",
+	CodeFollowSelf:           "不能关注自己",
+	CodeBlockSelf:            "不能屏蔽或静音自己",
+	CodeBlocked:              "对方已经屏蔽了你",
+	CodeKarmaTooLow:          "karma不足， 不能在该社区发帖",
+	CodeAccountTooNew:        "注册时间太短， 不能在该社区发帖",
+
+	CodeRegistrationClosed: "暂不开放注册",
+	CodeInvitationRequired: "需要邀请码才能注册",
+	CodeInvalidInvitation:  "邀请码无效或已过期",
+	CodeInvitationNotExist: "邀请码不存在",
+
+	CodePostNotExist:     "该帖子不存在",
+	CodeRevisionNotExist: "该版本不存在",
+
+	CodeNotDeleted:     "没有被删除， 不需要恢复",
+	CodeRestoreExpired: "已经超过恢复期限",
+
+	CodePostLocked:        "帖子已锁定， 不能评论",
+	CodeInvalidPostStatus: "帖子当前的状态不能执行该操作",
+
+	CodeNotDraft:           "该帖子不是草稿",
+	CodeInvalidPublishTime: "发布时间需要在未来一年之内",
+
+	CodeInvalidPostKind:   "不支持的帖子类型",
+	CodeInvalidURL:        "无效的链接地址",
+	CodeDuplicateLink:     "该链接已经在社区中发布过",
+	CodeInvalidImages:     "图片帖子需要1~20张自己上传的图片",
+	CodeInvalidPoll:       "投票需要2~10个不重复的选项， 截止时间需要在未来90天之内",
+	CodeInvalidPollOption: "无效的投票选项",
+	CodePollClosed:        "投票已经截止",
+	CodePollVoted:         "已经投过票了",
+
+	CodeOIDCEmailRequired: "第三方账号没有验证过的邮箱， 不能创建新用户",
 }
 
 func (c ResCode) Msg() string {
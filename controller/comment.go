@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -42,6 +43,18 @@ func CreateComment(ctx *gin.Context) {
 
 	//2. 处理业务逻辑
 	if err := logic.CreateComment(postID, userID, p); err != nil {
+		if errors.Is(err, logic.ErrorBlocked) {
+			ResponseError(ctx, CodeBlocked)
+			return
+		}
+		if errors.Is(err, logic.ErrorPostLocked) {
+			ResponseError(ctx, CodePostLocked)
+			return
+		}
+		if errors.Is(err, mysql.ErrorPostNotExist) {
+			ResponseError(ctx, CodePostNotExist)
+			return
+		}
 		ResponseError(ctx, CodeServerBusy)
 		return
 	}
@@ -66,28 +79,30 @@ func CreateComment(ctx *gin.Context) {
 func GetComment(ctx *gin.Context) {
 	//1. 进行参数验证
 	postIDStr := ctx.Param("post_id")
-	pageNumStr, pageSizeStr := ctx.Query("page_num"), ctx.Query("page_size")
 	postID, err := strconv.ParseInt(postIDStr, 10, 64)
 	if err != nil {
 		ResponseError(ctx, CodeInvalidParam)
 		return
 	}
-	pageNum, err := strconv.ParseInt(pageNumStr, 10, 64)
-	if err != nil {
-		pageNum = 1
-	}
-	pageSize, err := strconv.ParseInt(pageSizeStr, 10, 64)
+	pageNum, pageSize := getPageInfo(ctx)
+
+	viewerID, err := getCurrentUser(ctx)
 	if err != nil {
-		pageSize = 10
+		ResponseError(ctx, CodeNeedLogin)
+		return
 	}
 
 	//2. 处理业务逻辑
-	commentList, err := logic.GetComment(postID, pageNum, pageSize)
+	commentList, err := logic.GetComment(viewerID, postID, pageNum, pageSize)
 	if err != nil {
 		if err == mysql.ErrorCommentNotFound {
 			ResponseError(ctx, CodeCommentNotFound)
 			return
 		}
+		if err == mysql.ErrorPostNotExist {
+			ResponseError(ctx, CodePostNotExist)
+			return
+		}
 		ResponseError(ctx, CodeServerBusy)
 		return
 	}
@@ -98,7 +113,7 @@ func GetComment(ctx *gin.Context) {
 
 // DeleteComment： 删除某条评论
 //	@Summary		删除某条评论
-//	@Description	删除某条评论
+//	@Description	作者和社区的版主可以删除评论， 删除之后讨论串中显示为占位符
 //	@Tags			Comment
 //	@Accept			application/json
 //	@Produce		application/json
@@ -130,6 +145,10 @@ func DeleteComment(ctx *gin.Context) {
 			ResponseError(ctx, CodeCommentNotFound)
 			return
 		}
+		if err == logic.ErrorNotPerm {
+			ResponseError(ctx, CodeNotPerm)
+			return
+		}
 		ResponseError(ctx, CodeServerBusy)
 		return
 	}
@@ -137,3 +156,33 @@ func DeleteComment(ctx *gin.Context) {
 	//3. 返回响应
 	ResponseSuccess(ctx, nil)
 }
+
+// RestoreComment: 恢复删除的评论
+//
+//	@Summary		恢复删除的评论
+//	@Description	作者可以恢复自己删除的评论， 社区的版主可以恢复所有删除的评论， 只能在恢复期限内恢复
+//	@Tags			Comment
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			comment_id		path	int		true	"comment ID"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/post/comment/restore/{comment_id} [post]
+func RestoreComment(ctx *gin.Context) {
+	commentID, err := strconv.ParseInt(ctx.Param("comment_id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	if err = logic.RestoreComment(userID, commentID); err != nil {
+		responsePostError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
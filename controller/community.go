@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -75,8 +76,14 @@ func CreateNewCommunity(ctx *gin.Context) {
 		return
 	}
 
-	// 处理业务： 创建新的社区
-	if err := logic.CreateNewCommunity(p); err != nil {
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+
+	// 处理业务： 创建新的社区， 创建者成为社区的owner
+	if err := logic.CreateNewCommunity(p, userID); err != nil {
 		if err == mysql.ErrorCommunityExist {
 			ResponseError(ctx, CodeCommunityExist)
 			return
@@ -126,6 +133,44 @@ func UpdateCommunity(ctx *gin.Context) {
 	}
 }
 
+// SetCommunityRequirements: 设置在社区中发帖的门槛
+//
+//	@Summary		设置在社区中发帖的门槛
+//	@Description	设置发帖需要的最低karma和最低注册天数， 为0时不限制
+//	@Tags			Community
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int									true	"Community ID"
+//	@Param			object			body	models.ParamCommunityRequirements	true	"发帖门槛"
+//	@Param			Authorization	header	string								false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	models.Community
+//	@Router			/community/{id}/requirements [put]
+func SetCommunityRequirements(ctx *gin.Context) {
+	communityID := ctx.Param("id")
+	if communityID == "" {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+
+	p := new(models.ParamCommunityRequirements)
+	if ok := Validate(ctx, p, ValidateCommunityRequirements); !ok {
+		return
+	}
+
+	community, err := logic.SetCommunityRequirements(communityID, p)
+	if err != nil {
+		zap.L().Error("logic.SetCommunityRequirements failed", zap.Error(err))
+		if errors.Is(err, mysql.ErrorCommunityNotExist) {
+			ResponseError(ctx, CodeCommunityNotEXist)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, community)
+}
+
 // DeleteCommunity： 删除某个社区的信息
 //	@Summary		删除某个社区的信息
 //	@Description	删除某个社区的信息
@@ -153,3 +198,120 @@ func DeleteCommunity(ctx *gin.Context) {
 
 	ResponseSuccess(ctx, nil)
 }
+
+// GetCommunityMembers: 获取社区的owner和版主
+//
+//	@Summary		获取社区的owner和版主
+//	@Description	获取社区中所有拥有角色的用户， 按照角色从高到低排列
+//	@Tags			Community
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int		true	"Community ID"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	[]models.CommunityMember
+//	@Router			/community/{id}/members [get]
+func GetCommunityMembers(ctx *gin.Context) {
+	communityID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	members, err := logic.GetCommunityMembers(communityID)
+	if err != nil {
+		zap.L().Error("logic.GetCommunityMembers failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, members)
+}
+
+// SetCommunityMemberRole: 设置用户在社区中的角色
+//
+//	@Summary		设置用户在社区中的角色
+//	@Description	社区owner或站点管理员任免版主， role为moderator或member， 不能修改owner
+//	@Tags			Community
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int								true	"Community ID"
+//	@Param			user_id			path	int								true	"User ID"
+//	@Param			object			body	models.ParamCommunityMemberRole	true	"参数"
+//	@Param			Authorization	header	string							false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/community/{id}/members/{user_id} [put]
+func SetCommunityMemberRole(ctx *gin.Context) {
+	// 1. 获取参数
+	communityID, userID, ok := getCommunityMemberParam(ctx)
+	if !ok {
+		return
+	}
+	p := new(models.ParamCommunityMemberRole)
+	if ok := Validate(ctx, p, ValidateCommunityMemberRole); !ok {
+		return
+	}
+
+	// 2. 设置角色
+	if err := logic.SetCommunityMemberRole(communityID, userID, p.Role); err != nil {
+		zap.L().Error("logic.SetCommunityMemberRole failed", zap.Error(err))
+		responseCommunityMemberError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// RemoveCommunityMember: 移除用户在社区中的角色
+//
+//	@Summary		移除用户在社区中的角色
+//	@Description	社区owner或站点管理员移除版主， 不能移除owner
+//	@Tags			Community
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int		true	"Community ID"
+//	@Param			user_id			path	int		true	"User ID"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/community/{id}/members/{user_id} [delete]
+func RemoveCommunityMember(ctx *gin.Context) {
+	communityID, userID, ok := getCommunityMemberParam(ctx)
+	if !ok {
+		return
+	}
+	if err := logic.RemoveCommunityMember(communityID, userID); err != nil {
+		zap.L().Error("logic.RemoveCommunityMember failed", zap.Error(err))
+		responseCommunityMemberError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// getCommunityMemberParam: 获取路径中的社区id和用户id
+func getCommunityMemberParam(ctx *gin.Context) (communityID, userID int64, ok bool) {
+	communityID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return 0, 0, false
+	}
+	userID, err = strconv.ParseInt(ctx.Param("user_id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return 0, 0, false
+	}
+	return communityID, userID, true
+}
+
+func responseCommunityMemberError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mysql.ErrorCommunityNotExist):
+		ResponseError(ctx, CodeCommunityNotEXist)
+	case errors.Is(err, mysql.ErrorUserNotExist):
+		ResponseError(ctx, CodeUserNotExist)
+	case errors.Is(err, logic.ErrorInvalidRole):
+		ResponseError(ctx, CodeInvalidParam)
+	case errors.Is(err, logic.ErrorNotPerm):
+		ResponseError(ctx, CodeNotPerm)
+	default:
+		ResponseError(ctx, CodeServerBusy)
+	}
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"go.uber.org/zap"
+)
+
+// revertContactPage: 撤销链接打开的确认页面， 点击按钮之后才会提交撤销请求
+var revertContactPage = template.Must(template.New("revert").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>撤销联系方式的修改</title></head>
+<body>
+<p>如果不是你本人修改了邮箱或手机号码， 请点击下面的按钮撤销修改， 撤销之后所有设备都需要重新登录。</p>
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">撤销修改</button>
+</form>
+</body>
+</html>
+`))
+
+// ShowRevertContactChange: 撤销链接的确认页面
+//
+//	@Summary		撤销链接的确认页面
+//	@Description	邮件客户端和安全扫描会自动打开链接， 所以GET请求只显示确认页面， 不会修改账号
+//	@Tags			Auth
+//	@Produce		text/html
+//	@Param			token	query	string	true	"撤销凭证"
+//	@Success		200
+//	@Router			/auth/contact/revert [get]
+func ShowRevertContactChange(ctx *gin.Context) {
+	token := ctx.Query("token")
+	if token == "" {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	// 页面中带有撤销凭证， 不能被缓存， 也不能通过Referer泄露
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Referrer-Policy", "no-referrer")
+	ctx.Header("Content-Type", "text/html; charset=utf-8")
+	ctx.Status(http.StatusOK)
+	data := struct{ Action, Token string }{ctx.Request.URL.Path, token}
+	if err := revertContactPage.Execute(ctx.Writer, data); err != nil {
+		zap.L().Error("revertContactPage.Execute failed", zap.Error(err))
+	}
+}
+
+// RevertContactChange: 撤销邮箱或手机号码的修改
+//
+//	@Summary		撤销邮箱或手机号码的修改
+//	@Description	修改邮箱或手机号码之后， 旧的联系方式会收到带有撤销链接的通知， 撤销之后所有设备都需要重新登录
+//	@Tags			Auth
+//	@Accept			application/x-www-form-urlencoded
+//	@Produce		application/json
+//	@Param			token	formData	string	true	"撤销凭证"
+//	@Success		200		{object}	map[string]bool
+//	@Router			/auth/contact/revert [post]
+func RevertContactChange(ctx *gin.Context) {
+	// 1. 获取参数
+	token := ctx.PostForm("token")
+	if token == "" {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+
+	// 2. 恢复旧的联系方式
+	if err := logic.RevertContactChange(token); err != nil {
+		zap.L().Error("logic.RevertContactChange failed", zap.Error(err))
+		switch {
+		case errors.Is(err, logic.ErrorInvalidRevertToken):
+			ResponseError(ctx, CodeInvalidRevertToken)
+		case errors.Is(err, mysql.ErrorEmailExist):
+			ResponseError(ctx, CodeEmailExist)
+		case errors.Is(err, mysql.ErrorPhoneExist):
+			ResponseError(ctx, CodePhoneExist)
+		default:
+			ResponseError(ctx, CodeServerBusy)
+		}
+		return
+	}
+
+	// 3. 返回响应
+	ResponseSuccess(ctx, nil)
+}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+// GetDrafts: 获取当前用户的草稿
+//
+//	@Summary		获取当前用户的草稿
+//	@Description	包括定时发布的草稿， 最新的在前面
+//	@Tags			Post
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			page_num		query	int		false	"Page number"
+//	@Param			page_size		query	int		false	"Page size"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	[]models.Post
+//	@Router			/user/drafts [get]
+func GetDrafts(ctx *gin.Context) {
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	pageNum, pageSize := getPageInfo(ctx)
+	drafts, err := logic.GetDrafts(userID, pageNum, pageSize)
+	if err != nil {
+		zap.L().Error("logic.GetDrafts failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, drafts)
+}
+
+// SchedulePost: 设置草稿定时发布的时间
+//
+//	@Summary		设置草稿定时发布的时间
+//	@Description	只有作者可以设置， publish_at为空时取消定时发布
+//	@Tags			Post
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int							true	"Post ID"
+//	@Param			object			body	models.ParamSchedulePost	true	"发布时间"
+//	@Param			Authorization	header	string						false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/post/{id}/schedule [put]
+func SchedulePost(ctx *gin.Context) {
+	postID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	p := new(models.ParamSchedulePost)
+	if err = ctx.ShouldBindJSON(p); err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	if err = logic.SchedulePost(userID, postID, p); err != nil {
+		zap.L().Error("logic.SchedulePost failed", zap.Error(err))
+		responsePostError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// PublishPost: 立即发布草稿
+//
+//	@Summary		立即发布草稿
+//	@Description	只有作者可以发布， 社区开启审核时可能需要先审核
+//	@Tags			Post
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int		true	"Post ID"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/post/{id}/publish [post]
+func PublishPost(ctx *gin.Context) {
+	postID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	if err = logic.PublishPost(userID, postID); err != nil {
+		zap.L().Error("logic.PublishPost failed", zap.Error(err))
+		responsePostError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
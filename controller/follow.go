@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+// FollowUser: 关注用户
+//
+//	@Summary		关注用户
+//	@Description	关注之后可以在 /feed/following 中看到该用户发布的帖子
+//	@Tags			Users
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	string	true	"用户id"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/users/{id}/follow [post]
+func FollowUser(ctx *gin.Context) {
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	followeeID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	if err = logic.FollowUser(userID, followeeID); err != nil {
+		zap.L().Error("logic.FollowUser failed", zap.Error(err))
+		switch {
+		case errors.Is(err, logic.ErrorFollowSelf):
+			ResponseError(ctx, CodeFollowSelf)
+		case errors.Is(err, logic.ErrorBlocked):
+			ResponseError(ctx, CodeBlocked)
+		case errors.Is(err, mysql.ErrorUserNotExist):
+			ResponseError(ctx, CodeUserNotExist)
+		default:
+			ResponseError(ctx, CodeServerBusy)
+		}
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// UnfollowUser: 取消关注
+//
+//	@Summary		取消关注
+//	@Description	取消关注用户， 没有关注过也会返回成功
+//	@Tags			Users
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	string	true	"用户id"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/users/{id}/follow [delete]
+func UnfollowUser(ctx *gin.Context) {
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	followeeID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	if err = logic.UnfollowUser(userID, followeeID); err != nil {
+		zap.L().Error("logic.UnfollowUser failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// GetFollowers: 分页获取用户的粉丝
+//
+//	@Summary		分页获取用户的粉丝
+//	@Description	最近关注的在前面
+//	@Tags			Users
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	string	true	"用户id"
+//	@Param			page_num		query	int		false	"Page number"
+//	@Param			page_size		query	int		false	"Page size"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	[]models.FollowUser
+//	@Router			/users/{id}/followers [get]
+func GetFollowers(ctx *gin.Context) {
+	userID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	pageNum, pageSize := getPageInfo(ctx)
+	users, err := logic.GetFollowers(userID, pageNum, pageSize)
+	if err != nil {
+		responseProfileError(ctx, "logic.GetFollowers failed", err)
+		return
+	}
+	ResponseSuccess(ctx, users)
+}
+
+// GetFollowing: 分页获取用户关注的人
+//
+//	@Summary		分页获取用户关注的人
+//	@Description	最近关注的在前面
+//	@Tags			Users
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	string	true	"用户id"
+//	@Param			page_num		query	int		false	"Page number"
+//	@Param			page_size		query	int		false	"Page size"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	[]models.FollowUser
+//	@Router			/users/{id}/following [get]
+func GetFollowing(ctx *gin.Context) {
+	userID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	pageNum, pageSize := getPageInfo(ctx)
+	users, err := logic.GetFollowing(userID, pageNum, pageSize)
+	if err != nil {
+		responseProfileError(ctx, "logic.GetFollowing failed", err)
+		return
+	}
+	ResponseSuccess(ctx, users)
+}
+
+// GetFollowingFeed: 获取关注的人最近发布的帖子
+//
+//	@Summary		获取关注的人最近发布的帖子
+//	@Description	可按时间或分数排序， 结果会缓存60秒
+//	@Tags			Feed
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			object			query	models.ParamPostList	false	"查询参数"
+//	@Param			Authorization	header	string					false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	[]models.ApiPostDetail2
+//	@Router			/feed/following [get]
+func GetFollowingFeed(ctx *gin.Context) {
+	p := &models.ParamPostList{
+		Page:  1,
+		Size:  10,
+		Order: models.OrderTime,
+	}
+	if err := ctx.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetFollowingFeed ctx.ShouldBindQuery failed.", zap.Error(err))
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+
+	data, err := logic.GetFollowingFeed(userID, p)
+	if err != nil {
+		zap.L().Error("logic.GetFollowingFeed failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, data)
+}
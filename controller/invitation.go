@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+// GetRegistrationMode: 获取当前的注册方式
+//
+//	@Summary		获取当前的注册方式
+//	@Description	open: 开放注册， invite: 需要邀请码， closed: 关闭注册
+//	@Tags			Auth
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Success		200	{object}	map[string]string
+//	@Router			/auth/signup/mode [get]
+func GetRegistrationMode(ctx *gin.Context) {
+	ResponseSuccess(ctx, gin.H{
+		"mode": logic.RegistrationMode(),
+	})
+}
+
+// CreateInvitation: 生成邀请码
+//
+//	@Summary		生成邀请码
+//	@Description	只有站点管理员可以生成， 使用邀请码注册的用户会记录生成邀请码的管理员
+//	@Tags			Admin
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			object			body	models.ParamCreateInvitation	true	"邀请码参数"
+//	@Param			Authorization	header	string							false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	models.Invitation
+//	@Router			/admin/invitations [post]
+func CreateInvitation(ctx *gin.Context) {
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	p := &models.ParamCreateInvitation{MaxUses: 1}
+	if ok := Validate(ctx, p, ValidateCreateInvitation); !ok {
+		return
+	}
+	invitation, err := logic.CreateInvitation(userID, p)
+	if err != nil {
+		zap.L().Error("logic.CreateInvitation failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, invitation)
+}
+
+// GetInvitations: 分页获取所有的邀请码
+//
+//	@Summary		分页获取所有的邀请码
+//	@Description	最近生成的在前面， 包括已经撤销和过期的邀请码
+//	@Tags			Admin
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			page_num		query	int		false	"Page number"
+//	@Param			page_size		query	int		false	"Page size"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	[]models.Invitation
+//	@Router			/admin/invitations [get]
+func GetInvitations(ctx *gin.Context) {
+	pageNum, pageSize := getPageInfo(ctx)
+	invitations, err := logic.GetInvitations(pageNum, pageSize)
+	if err != nil {
+		zap.L().Error("logic.GetInvitations failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, invitations)
+}
+
+// RevokeInvitation: 撤销邀请码
+//
+//	@Summary		撤销邀请码
+//	@Description	撤销之后不能再用来注册， 已经注册的用户不受影响
+//	@Tags			Admin
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	string	true	"邀请码id"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/admin/invitations/{id} [delete]
+func RevokeInvitation(ctx *gin.Context) {
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	invitationID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	if err = logic.RevokeInvitation(userID, invitationID); err != nil {
+		zap.L().Error("logic.RevokeInvitation failed", zap.Error(err))
+		if errors.Is(err, logic.ErrorInvitationNotExist) {
+			ResponseError(ctx, CodeInvitationNotExist)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// responseRegistrationError: 注册方式和邀请码相关的错误， 已经返回响应时返回true
+func responseRegistrationError(ctx *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, logic.ErrorRegistrationClosed):
+		ResponseError(ctx, CodeRegistrationClosed)
+	case errors.Is(err, logic.ErrorInvitationRequired):
+		ResponseError(ctx, CodeInvitationRequired)
+	case errors.Is(err, mysql.ErrorInvalidInvitation):
+		ResponseError(ctx, CodeInvalidInvitation)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/jwt"
+)
+
+// JWKS: 返回验证token使用的公钥集合
+//
+//	@Summary		返回验证token使用的公钥集合
+//	@Description	按照 RFC 7517 的格式返回所有非对称签名密钥的公钥， 下游服务可以用来验证access token
+//	@Tags			Auth
+//	@Produce		application/json
+//	@Success		200	{object}	map[string]interface{}
+//	@Router			/.well-known/jwks.json [get]
+func JWKS(ctx *gin.Context) {
+	JSON(ctx, gin.H{
+		"keys": jwt.JWKS(),
+	})
+}
@@ -3,15 +3,12 @@ package controller
 import (
 	"errors"
 	"fmt"
-	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
 	"github.com/xiaorui/reddit-async/reddit-backend/logic"
 	"github.com/xiaorui/reddit-async/reddit-backend/models"
-	"github.com/xiaorui/reddit-async/reddit-backend/pkg/jwt"
 	"go.uber.org/zap"
 )
 
@@ -35,9 +32,12 @@ func LoginHandler(ctx *gin.Context) {
 	}
 
 	//2. 业务处理
-	user, err := logic.Login(p) // 登录之后获取一个token
+	user, err := logic.Login(p, getDevice(ctx)) // 登录之后获取一个token
 	if err != nil {
 		zap.L().Error("Login with invalid data...", zap.String("username", p.Username), zap.Error(err))
+		if responseIfRateLimited(ctx, err) {
+			return
+		}
 		//ctx.JSON(http.StatusOK, gin.H{
 		//	"msg": "登录失败",
 		//})
@@ -52,6 +52,12 @@ func LoginHandler(ctx *gin.Context) {
 		return
 	}
 
+	// 开启了两步验证的用户需要再调用 /auth/login/2fa 获取token
+	if user.TOTPEnabled {
+		responseTwoFactorChallenge(ctx, user)
+		return
+	}
+
 	//3. 返回响应
 	ResponseSuccess(ctx, gin.H{
 		"user_id":   fmt.Sprintf("%d", user.ID),
@@ -87,19 +93,8 @@ func LoginUsingPhone(ctx *gin.Context) {
 		ResponseError(ctx, CodeServerBusy)
 		return
 	}
-	// 生成token
-	accessToken, refreshToken, err := jwt.GenToken(user.ID, user.Username)
-	if err != nil {
-		ResponseError(ctx, CodeServerBusy)
-		return
-	}
-	// 3. 返回执行响应
-	ResponseSuccess(ctx, gin.H{
-		"user_id":       user.ID,
-		"username":      user.Username,
-		"access_token":  accessToken,
-		"refresh_token": refreshToken,
-	})
+	// 3. 生成token并返回响应， 开启了两步验证时返回登录挑战
+	responseLogin(ctx, user)
 }
 
 // LoginUsingEmail: 使用邮箱+密码的方式进行登陆
@@ -119,8 +114,11 @@ func LoginUsingEmail(ctx *gin.Context) {
 	}
 
 	// 2. 进行登录操作
-	user, err := logic.LoginUsingEmail(p)
+	user, err := logic.LoginUsingEmail(p, getDevice(ctx))
 	if err != nil {
+		if responseIfRateLimited(ctx, err) {
+			return
+		}
 		if errors.Is(err, mysql.ErrorEmailNotExist) {
 			ResponseError(ctx, CodeEmailNotExist)
 			return
@@ -131,13 +129,63 @@ func LoginUsingEmail(ctx *gin.Context) {
 		ResponseError(ctx, CodeServerBusy)
 		return
 	}
-	// 生成token
-	accessToken, refreshToken, err := jwt.GenToken(user.ID, user.Username)
+	// 3. 生成token并返回响应， 开启了两步验证时返回登录挑战
+	responseLogin(ctx, user)
+}
+
+// LoginUsingPassword: 使用用户名、邮箱或手机号码+密码的方式进行登陆
+//
+//	@Summary		使用用户名、邮箱或手机号码+密码的方式进行登陆
+//	@Description	identifier可以是用户名、邮箱或者手机号码， 返回和其他登录方式相同的access token和refresh token
+//	@Tags			Auth
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			object	body	models.ParamLoginUsingPassword	false	"查询参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/auth/login/password [post]
+func LoginUsingPassword(ctx *gin.Context) {
+	// 1. 进行参数验证
+	p := new(models.ParamLoginUsingPassword)
+	if ok := Validate(ctx, p, ValidateLoginUsingPassword); !ok {
+		return
+	}
+
+	// 2. 进行登录操作
+	user, err := logic.LoginUsingPassword(p, getDevice(ctx))
+	if err != nil {
+		zap.L().Error("logic.LoginUsingPassword failed", zap.Error(err))
+		if responseIfRateLimited(ctx, err) {
+			return
+		}
+		if errors.Is(err, mysql.ErrorUserNotExist) {
+			ResponseError(ctx, CodeUserNotExist)
+			return
+		} else if errors.Is(err, mysql.ErrorPasswordInvalid) {
+			ResponseError(ctx, CodeInvalidPassword)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+
+	// 3. 生成token并返回响应， 开启了两步验证时返回登录挑战
+	responseLogin(ctx, user)
+}
+
+// responseLogin: 登录验证通过之后签发token
+// 开启了两步验证的用户只返回登录挑战， 需要调用 /auth/login/2fa 换取真正的token
+func responseLogin(ctx *gin.Context, user *models.User) {
+	if user.TOTPEnabled {
+		responseTwoFactorChallenge(ctx, user)
+		return
+	}
+	accessToken, refreshToken, err := logic.IssueTokens(user, getDevice(ctx))
 	if err != nil {
+		zap.L().Error("logic.IssueTokens failed", zap.Error(err))
 		ResponseError(ctx, CodeServerBusy)
 		return
 	}
-	// 3. 返回执行响应
 	ResponseSuccess(ctx, gin.H{
 		"user_id":       user.ID,
 		"username":      user.Username,
@@ -146,38 +194,86 @@ func LoginUsingEmail(ctx *gin.Context) {
 	})
 }
 
+// responseTwoFactorChallenge: 返回两步验证的登录挑战
+func responseTwoFactorChallenge(ctx *gin.Context, user *models.User) {
+	challenge, err := logic.CreateLoginChallenge(user.ID)
+	if err != nil {
+		zap.L().Error("logic.CreateLoginChallenge failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, gin.H{
+		"user_id":             user.ID,
+		"username":            user.Username,
+		"two_factor_required": true,
+		"challenge_token":     challenge,
+	})
+}
+
 // RefreshToken: 刷新token
-// 如果访问令牌（Access Token）尚未过期，并且调用刷新令牌（Refresh Token）时，根据常规情况，不会生成新的令牌。
+// refresh token每次使用之后都会轮换， 客户端需要保存新的refresh token， 旧的refresh token再次使用会导致整个会话被注销
+//
 //	@Summary		使用refresh token来获取新的access token
-//	@Description	使用refresh token来获取新的access token
+//	@Description	使用refresh token来获取新的access token和refresh token
 //	@Tags			Auth
 //	@Accept			application/json
 //	@Produce		application/json
-//	@Param			refresh_token	query	string	false	"刷新Token"
+//	@Param			object	body	models.ParamRefreshToken	false	"查询参数"
 //	@Security		ApiKeyAuth
 //	@Success		200	{object}	map[string]bool
-//	@Router			/auth//login/refresh-token [get]
+//	@Router			/auth/login/refresh-token [post]
 func RefreshToken(ctx *gin.Context) {
-	rt := ctx.Query("refresh_token")
-	authHeader := ctx.Request.Header.Get("Authorization")
-	if authHeader == "" {
-		ResponseErrorWithMsg(ctx, CodeInvalidToken, "缺少Auth Token")
-		ctx.Abort()
-		return
-	}
-	parts := strings.SplitN(authHeader, " ", 2)
-	if !(len(parts) == 2 && parts[0] == "Bearer") {
-		ResponseErrorWithMsg(ctx, CodeInvalidToken, "Token 格式不正确")
-		ctx.Abort()
+	// 1. 进行参数验证
+	p := new(models.ParamRefreshToken)
+	if ok := Validate(ctx, p, ValidateRefreshToken); !ok {
 		return
 	}
-	accessToken, refreshToken, err := jwt.RefreshToken(parts[1], rt)
+
+	// 2. 轮换refresh token并签发新的access token
+	accessToken, refreshToken, err := logic.RefreshToken(p.RefreshToken)
 	if err != nil {
+		zap.L().Error("logic.RefreshToken failed", zap.Error(err))
+		if errors.Is(err, logic.ErrorInvalidToken) || errors.Is(err, logic.ErrorTokenRevoked) {
+			ResponseError(ctx, CodeInvalidToken)
+			return
+		}
 		ResponseError(ctx, CodeServerBusy)
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{
+
+	// 3. 返回响应
+	ResponseSuccess(ctx, gin.H{
 		"access_token":  accessToken,
 		"refresh_token": refreshToken,
 	})
 }
+
+// Logout: 注销登录， 当前的access token会立即失效
+//
+//	@Summary		注销登录
+//	@Description	注销当前的access token， 同一个登录会话下的refresh token也一起失效
+//	@Tags			Auth
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/auth/logout [post]
+func Logout(ctx *gin.Context) {
+	// 1. 获取当前token的信息
+	mc, err := getCurrentClaims(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+
+	// 2. 将token加入黑名单， 并且删除登录会话
+	if err := logic.Logout(mc); err != nil {
+		zap.L().Error("logic.Logout failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+
+	// 3. 返回响应
+	ResponseSuccess(ctx, nil)
+}
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+// GetModerationQueue: 获取社区中待审核的帖子
+//
+//	@Summary		获取社区中待审核的帖子
+//	@Description	只有社区的版主和站点管理员可以查看， 先发布的在前面
+//	@Tags			Moderation
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int		true	"Community ID"
+//	@Param			page_num		query	int		false	"Page number"
+//	@Param			page_size		query	int		false	"Page size"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	[]models.ApiPostDetail2
+//	@Router			/community/{id}/queue [get]
+func GetModerationQueue(ctx *gin.Context) {
+	communityID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	pageNum, pageSize := getPageInfo(ctx)
+	posts, err := logic.GetModerationQueue(communityID, pageNum, pageSize)
+	if err != nil {
+		zap.L().Error("logic.GetModerationQueue failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, posts)
+}
+
+// ApprovePost: 审核通过帖子
+//
+//	@Summary		审核通过帖子
+//	@Description	待审核的帖子通过之后才会出现在列表中， 被移除的帖子也可以重新通过
+//	@Tags			Moderation
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int		true	"Post ID"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/post/{id}/approve [post]
+func ApprovePost(ctx *gin.Context) {
+	postID, userID, ok := getModerationParams(ctx)
+	if !ok {
+		return
+	}
+	if err := logic.ApprovePost(userID, postID); err != nil {
+		zap.L().Error("logic.ApprovePost failed", zap.Error(err))
+		responsePostError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// RejectPost: 拒绝或移除帖子
+//
+//	@Summary		拒绝或移除帖子
+//	@Description	拒绝待审核的帖子， 或者把已经发布的帖子从列表中移除， 原因会显示给作者
+//	@Tags			Moderation
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int						true	"Post ID"
+//	@Param			object			body	models.ParamRejectPost	true	"原因"
+//	@Param			Authorization	header	string					false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/post/{id}/reject [post]
+func RejectPost(ctx *gin.Context) {
+	postID, userID, ok := getModerationParams(ctx)
+	if !ok {
+		return
+	}
+	p := new(models.ParamRejectPost)
+	if ok := Validate(ctx, p, ValidateRejectPost); !ok {
+		return
+	}
+	if err := logic.RejectPost(userID, postID, p); err != nil {
+		zap.L().Error("logic.RejectPost failed", zap.Error(err))
+		responsePostError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// LockPost: 锁定帖子
+//
+//	@Summary		锁定帖子
+//	@Description	锁定之后不能评论， 仍然出现在列表中
+//	@Tags			Moderation
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int		true	"Post ID"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/post/{id}/lock [post]
+func LockPost(ctx *gin.Context) {
+	setPostLocked(ctx, true)
+}
+
+// UnlockPost: 解锁帖子
+//
+//	@Summary		解锁帖子
+//	@Description	解锁之后可以继续评论
+//	@Tags			Moderation
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int		true	"Post ID"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/post/{id}/lock [delete]
+func UnlockPost(ctx *gin.Context) {
+	setPostLocked(ctx, false)
+}
+
+func setPostLocked(ctx *gin.Context, locked bool) {
+	postID, userID, ok := getModerationParams(ctx)
+	if !ok {
+		return
+	}
+	if err := logic.LockPost(userID, postID, locked); err != nil {
+		zap.L().Error("logic.LockPost failed", zap.Error(err))
+		responsePostError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// getModerationParams: 获取帖子id和当前用户id， 失败时已经返回了错误响应
+func getModerationParams(ctx *gin.Context) (postID, userID int64, ok bool) {
+	postID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return 0, 0, false
+	}
+	userID, err = getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return 0, 0, false
+	}
+	return postID, userID, true
+}
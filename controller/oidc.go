@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+const (
+	oidcStateCookie     = "oidc_state" // 发起第三方登录的浏览器保存的state， 回调时必须一致
+	oidcStateCookiePath = "/api/v1/auth/oidc"
+)
+
+// setOIDCStateCookie: 保存或者清除(state为空)浏览器中的state
+func setOIDCStateCookie(ctx *gin.Context, state string) {
+	maxAge := int(logic.OIDCStateExpire / time.Second)
+	if state == "" {
+		maxAge = -1
+	}
+	secure := ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https"
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, state, maxAge, oidcStateCookiePath, "", secure, true)
+}
+
+// OIDCLogin: 获取第三方登录的跳转地址
+//
+//	@Summary		获取第三方登录的跳转地址
+//	@Description	前端跳转到返回的url， 用户在第三方登录之后会带着code和state回到配置的redirect_url， 同时在cookie中保存state， 回调时需要带上
+//	@Tags			Auth
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			provider	path	string	true	"第三方登录的名称， 例如 google"
+//	@Success		200			{object}	map[string]string
+//	@Router			/auth/oidc/{provider}/login [get]
+func OIDCLogin(ctx *gin.Context) {
+	url, state, err := logic.StartOIDCLogin(ctx.Param("provider"))
+	if err != nil {
+		zap.L().Error("logic.StartOIDCLogin failed", zap.Error(err))
+		if errors.Is(err, logic.ErrorOIDCProviderNotExist) {
+			ResponseError(ctx, CodeOIDCProviderNotExist)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	setOIDCStateCookie(ctx, state)
+	ResponseSuccess(ctx, gin.H{"url": url})
+}
+
+// OIDCCallback: 第三方登录回调
+//
+//	@Summary		第三方登录回调
+//	@Description	使用第三方返回的code和state登录， state需要和发起登录时cookie中保存的一致， 第一次登录时会绑定邮箱相同的用户或者创建新用户
+//	@Tags			Auth
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			provider	path	string						true	"第三方登录的名称， 例如 google"
+//	@Param			object		body	models.ParamOIDCCallback	false	"查询参数"
+//	@Success		200			{object}	map[string]bool
+//	@Router			/auth/oidc/{provider}/callback [post]
+func OIDCCallback(ctx *gin.Context) {
+	// 1. 进行参数验证
+	p := new(models.ParamOIDCCallback)
+	if ok := Validate(ctx, p, ValidateOIDCCallback); !ok {
+		return
+	}
+	// 只有发起登录的浏览器可以完成回调， 否则攻击者可以让别人登录到攻击者的账号
+	cookieState, err := ctx.Cookie(oidcStateCookie)
+	setOIDCStateCookie(ctx, "")
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookieState), []byte(p.State)) != 1 {
+		ResponseError(ctx, CodeInvalidOIDCState)
+		return
+	}
+
+	// 2. 业务处理
+	user, err := logic.LoginUsingOIDC(ctx.Param("provider"), p)
+	if err != nil {
+		zap.L().Error("logic.LoginUsingOIDC failed", zap.Error(err))
+		switch {
+		case errors.Is(err, logic.ErrorOIDCProviderNotExist):
+			ResponseError(ctx, CodeOIDCProviderNotExist)
+		case errors.Is(err, logic.ErrorInvalidOIDCState):
+			ResponseError(ctx, CodeInvalidOIDCState)
+		case errors.Is(err, logic.ErrorOIDCEmailConflict):
+			ResponseError(ctx, CodeEmailExist)
+		case errors.Is(err, logic.ErrorRegistrationClosed):
+			ResponseError(ctx, CodeRegistrationClosed)
+		case errors.Is(err, logic.ErrorOIDCEmailRequired):
+			ResponseError(ctx, CodeOIDCEmailRequired)
+		default:
+			ResponseError(ctx, CodeOIDCLoginFailed)
+		}
+		return
+	}
+
+	// 3. 生成token并返回响应， 开启了两步验证时返回登录挑战
+	responseLogin(ctx, user)
+}
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOIDCCallbackRequiresStateCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	url := "/api/v1/auth/oidc/google/callback"
+	r.POST("/api/v1/auth/oidc/:provider/callback", OIDCCallback)
+
+	cases := []struct {
+		name   string
+		cookie string
+	}{
+		{"no cookie", ""},
+		{"other browser", "other-state"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(`{"code":"code","state":"state"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if c.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: c.cookie})
+			}
+			r.ServeHTTP(w, req)
+
+			res := new(ResponseData)
+			if err := json.Unmarshal(w.Body.Bytes(), res); err != nil {
+				t.Fatalf("json.Unmarshal failed, err: %v", err)
+			}
+			assert.Equal(t, CodeInvalidOIDCState, res.Code)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+// ResetPasswordUsingPhone: 使用手机验证码重置密码
+//
+//	@Summary		使用手机验证码重置密码
+//	@Description	1. 获取图形验证码 2. 通过 /auth/code/phone 获取短信验证码 3. 重新获取图形验证码， 提交图形验证码、验证码和新密码
+//	@Tags			Auth
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			object	body	models.ParamResetPasswordUsingPhone	false	"查询参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/auth/password/phone [post]
+func ResetPasswordUsingPhone(ctx *gin.Context) {
+	// 1. 进行参数验证, 这里会校验短信验证码
+	p := new(models.ParamResetPasswordUsingPhone)
+	if ok := Validate(ctx, p, ValidateResetPasswordUsingPhone); !ok {
+		return
+	}
+
+	// 2. 处理业务逻辑：重置密码
+	if err := logic.ResetPasswordUsingPhone(p); err != nil {
+		zap.L().Error("logic.ResetPasswordUsingPhone failed..", zap.Error(err))
+		if errors.Is(err, mysql.ErrorPhoneNotExist) {
+			ResponseError(ctx, CodePhoneNotExist)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+
+	// 3. 返回响应
+	ResponseSuccess(ctx, nil)
+}
+
+// ResetPasswordUsingEmail: 使用邮箱验证码重置密码
+//
+//	@Summary		使用邮箱验证码重置密码
+//	@Description	1. 获取图形验证码 2. 通过 /auth/code/email 获取邮箱验证码 3. 重新获取图形验证码， 提交图形验证码、验证码和新密码
+//	@Tags			Auth
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			object	body	models.ParamResetPasswordUsingEmail	false	"查询参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/auth/password/email [post]
+func ResetPasswordUsingEmail(ctx *gin.Context) {
+	// 1. 进行参数验证, 这里会校验邮箱验证码
+	p := new(models.ParamResetPasswordUsingEmail)
+	if ok := Validate(ctx, p, ValidateResetPasswordUsingEmail); !ok {
+		return
+	}
+
+	// 2. 处理业务逻辑：重置密码
+	if err := logic.ResetPasswordUsingEmail(p); err != nil {
+		zap.L().Error("logic.ResetPasswordUsingEmail failed..", zap.Error(err))
+		if errors.Is(err, mysql.ErrorEmailNotExist) {
+			ResponseError(ctx, CodeEmailNotExist)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+
+	// 3. 返回响应
+	ResponseSuccess(ctx, nil)
+}
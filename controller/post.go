@@ -1,25 +1,30 @@
 package controller
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
 	"github.com/xiaorui/reddit-async/reddit-backend/logic"
 	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/urlnorm"
 	"go.uber.org/zap"
 )
 
 // CreatePostHandler: 创建帖子
 //	@Summary		创建帖子
-//	@Description	创建帖子
+//	@Description	创建帖子， 社区开启审核时新用户的帖子需要版主审核通过之后才会出现在列表中
+//	@Description	draft为true时保存为草稿， 设置了publish_at时到时间之后自动发布
+//	@Description	kind为link时需要url， 为image时需要先上传图片再传入image_ids， 为poll时需要poll
+//	@Description	content使用markdown， 返回的content_html是渲染和过滤之后的HTML
 //	@Tags			Post
 //	@Accept			application/json
 //	@Produce		application/json
 //	@Param			object			body	models.Post	true	"参数"
 //	@Param			Authorization	header	string		false	"Bearer 用户令牌"
 //	@Security		ApiKeyAuth
-//	@Success		200	{object}	map[string]bool
+//	@Success		200	{object}	models.Post
 //	@Router			/post [post]
 func CreatePostHandler(ctx *gin.Context) {
 	// 1. 进行参数校验
@@ -40,11 +45,35 @@ func CreatePostHandler(ctx *gin.Context) {
 	// 2. 进行业务处理， 也就是说创建一个post
 	if err := logic.CreatePost(p); err != nil {
 		zap.L().Error("logic.CreatePost failed", zap.Error(err))
-		ResponseError(ctx, CodeServerBusy) // 不要将太多的后端错误暴露给前端
+		switch {
+		case errors.Is(err, logic.ErrorKarmaTooLow):
+			ResponseError(ctx, CodeKarmaTooLow)
+		case errors.Is(err, logic.ErrorAccountTooNew):
+			ResponseError(ctx, CodeAccountTooNew)
+		case errors.Is(err, mysql.ErrorCommunityNotExist):
+			ResponseError(ctx, CodeCommunityNotEXist)
+		case errors.Is(err, logic.ErrorInvalidPublishTime):
+			ResponseError(ctx, CodeInvalidPublishTime)
+		case errors.Is(err, logic.ErrorInvalidPostKind):
+			ResponseError(ctx, CodeInvalidPostKind)
+		case errors.Is(err, urlnorm.ErrorInvalidURL):
+			ResponseError(ctx, CodeInvalidURL)
+		case errors.Is(err, logic.ErrorDuplicateLink):
+			ResponseError(ctx, CodeDuplicateLink)
+		case errors.Is(err, logic.ErrorInvalidImages), errors.Is(err, mysql.ErrorImageNotExist):
+			ResponseError(ctx, CodeInvalidImages)
+		case errors.Is(err, logic.ErrorInvalidPoll):
+			ResponseError(ctx, CodeInvalidPoll)
+		case errors.Is(err, logic.ErrorBlocked):
+			ResponseError(ctx, CodeBlocked)
+		default:
+			ResponseError(ctx, CodeServerBusy) // 不要将太多的后端错误暴露给前端
+		}
+		return
 	}
 
-	// 3. 返回数据
-	ResponseSuccess(ctx, nil)
+	// 3. 返回数据， 需要审核的帖子status为待审核
+	ResponseSuccess(ctx, p)
 }
 
 // GetPostHandler: 获取某个帖子的信息
@@ -68,10 +97,11 @@ func GetPostHandler(ctx *gin.Context) {
 		return
 	}
 	//2. 处理业务逻辑， 也就是从数据库中获取数据
-	data, err := logic.GetPostByID(pid)
+	viewerID, _ := getCurrentUser(ctx)
+	data, err := logic.GetPostByID(viewerID, pid)
 	if err != nil {
 		zap.L().Error("GetPostHandler  logic.GetPostByID failed.", zap.Error(err))
-		ResponseError(ctx, CodeServerBusy)
+		responsePostError(ctx, err)
 		return
 	}
 	//3. 返回数据
@@ -177,9 +207,11 @@ func GetPostListHandler0(ctx *gin.Context) {
 		return
 	}
 	// zap.L().Info("param", zap.Any("param", p))
+	// 周报的端点不需要登录， 此时不过滤屏蔽的用户
+	viewerID, _ := getCurrentUser(ctx)
 
 	//处理业务逻辑
-	data, err := logic.GetPostList0(p)
+	data, err := logic.GetPostList0(viewerID, p)
 	if err != nil {
 		zap.L().Error("GetPostListHandler0 logic.GetCommunityPostList failed.", zap.Error(err))
 		ResponseError(ctx, CodeServerBusy)
@@ -191,7 +223,7 @@ func GetPostListHandler0(ctx *gin.Context) {
 
 // DeletePost: 删除post
 //	@Summary		删除post
-//	@Description	删除post
+//	@Description	作者和社区的版主可以删除帖子， 删除之后在恢复期限内可以恢复
 //	@Tags			Post
 //	@Accept			application/json
 //	@Produce		application/json
@@ -201,7 +233,6 @@ func GetPostListHandler0(ctx *gin.Context) {
 //	@Success		200	{object}	map[string]bool
 //	@Router			/post/{id} [delete]
 func DeletePost(ctx *gin.Context) {
-	// TODO:: 忘记删除redis中的数据了
 	// 1. 获取postid
 	postIDStr := ctx.Param("id")
 	postID, _ := strconv.ParseInt(postIDStr, 10, 64)
@@ -219,13 +250,119 @@ func DeletePost(ctx *gin.Context) {
 
 	// 3. 处理业务逻辑
 	if err := logic.DeletePost(postID, userID); err != nil {
-		if err == mysql.ErrorNotPermission {
-			ResponseError(ctx, CodeNotPerm)
-			return
-		}
-		ResponseError(ctx, CodeServerBusy)
+		zap.L().Error("logic.DeletePost failed.", zap.Error(err))
+		responsePostError(ctx, err)
 		return
 	}
 
 	ResponseSuccess(ctx, nil)
 }
+
+// RestorePost: 恢复删除的帖子
+//
+//	@Summary		恢复删除的帖子
+//	@Description	作者可以恢复自己删除的帖子， 社区的版主可以恢复所有删除的帖子， 只能在恢复期限内恢复
+//	@Tags			Post
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int		true	"Post ID"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/post/{id}/restore [post]
+func RestorePost(ctx *gin.Context) {
+	postID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	if err = logic.RestorePost(userID, postID); err != nil {
+		zap.L().Error("logic.RestorePost failed.", zap.Error(err))
+		responsePostError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// UpdatePost: 编辑帖子
+//
+//	@Summary		编辑帖子
+//	@Description	只有作者可以编辑标题和内容， 每次编辑都会保存一个新的版本
+//	@Tags			Post
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int						true	"Post ID"
+//	@Param			object			body	models.ParamUpdatePost	true	"新的标题和内容"
+//	@Param			Authorization	header	string					false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	models.Post
+//	@Router			/post/{id} [put]
+func UpdatePost(ctx *gin.Context) {
+	postID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamUpdatePost)
+	if ok := Validate(ctx, p, ValidateUpdatePost); !ok {
+		return
+	}
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+
+	post, err := logic.UpdatePost(userID, postID, p)
+	if err != nil {
+		zap.L().Error("logic.UpdatePost failed", zap.Error(err))
+		responsePostError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, post)
+}
+
+// responsePostError: 帖子不存在、没有权限等错误
+func responsePostError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mysql.ErrorPostNotExist):
+		ResponseError(ctx, CodePostNotExist)
+	case errors.Is(err, logic.ErrorNotPerm):
+		ResponseError(ctx, CodeNotPerm)
+	case errors.Is(err, logic.ErrorRevisionNotExist):
+		ResponseError(ctx, CodeRevisionNotExist)
+	case errors.Is(err, logic.ErrorNotDeleted):
+		ResponseError(ctx, CodeNotDeleted)
+	case errors.Is(err, logic.ErrorDuplicateLink):
+		ResponseError(ctx, CodeDuplicateLink)
+	case errors.Is(err, logic.ErrorRestoreExpired):
+		ResponseError(ctx, CodeRestoreExpired)
+	case errors.Is(err, mysql.ErrorCommentNotFound):
+		ResponseError(ctx, CodeCommentNotFound)
+	case errors.Is(err, logic.ErrorPostLocked):
+		ResponseError(ctx, CodePostLocked)
+	case errors.Is(err, logic.ErrorInvalidPostStatus):
+		ResponseError(ctx, CodeInvalidPostStatus)
+	case errors.Is(err, logic.ErrorNotDraft):
+		ResponseError(ctx, CodeNotDraft)
+	case errors.Is(err, logic.ErrorInvalidPublishTime):
+		ResponseError(ctx, CodeInvalidPublishTime)
+	case errors.Is(err, logic.ErrorInvalidPostKind):
+		ResponseError(ctx, CodeInvalidPostKind)
+	case errors.Is(err, logic.ErrorInvalidPollOption):
+		ResponseError(ctx, CodeInvalidPollOption)
+	case errors.Is(err, logic.ErrorPollClosed):
+		ResponseError(ctx, CodePollClosed)
+	case errors.Is(err, logic.ErrorPollVoted):
+		ResponseError(ctx, CodePollVoted)
+	case errors.Is(err, logic.ErrorBlocked):
+		ResponseError(ctx, CodeBlocked)
+	default:
+		ResponseError(ctx, CodeServerBusy)
+	}
+}
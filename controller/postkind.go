@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/file"
+	"go.uber.org/zap"
+)
+
+// UploadPostImage: 上传图片帖子的图片
+//
+//	@Summary		上传图片帖子的图片
+//	@Description	每次上传一张图片， 创建图片帖子时把返回的image_id放在image_ids中
+//	@Tags			Post
+//	@Accept			multipart/form-data
+//	@Produce		application/json
+//	@Param			image			formData	file	true	"Image file"
+//	@Param			Authorization	header		string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	models.PostImage
+//	@Router			/post/images [post]
+func UploadPostImage(ctx *gin.Context) {
+	p := new(models.ParamUploadPostImage)
+	if ok := Validate(ctx, p, ValidateUploadPostImage); !ok {
+		return
+	}
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	path, width, height, err := file.SavePostImage(ctx, p.Image, userID)
+	if err != nil {
+		zap.L().Error("file.SavePostImage failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	img, err := logic.CreatePostImage(userID, path, width, height)
+	if err != nil {
+		zap.L().Error("logic.CreatePostImage failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, img)
+}
+
+// VotePoll: 投票帖子投票
+//
+//	@Summary		投票帖子投票
+//	@Description	每个用户只能投一次， 单选时只能选择一个选项， 截止之后不能再投票
+//	@Tags			Post
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int						true	"Post ID"
+//	@Param			object			body	models.ParamVotePoll	true	"选项的编号"
+//	@Param			Authorization	header	string					false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/post/{id}/poll [post]
+func VotePoll(ctx *gin.Context) {
+	postID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	p := new(models.ParamVotePoll)
+	if err = ctx.ShouldBindJSON(p); err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	if err = logic.VotePoll(userID, postID, p); err != nil {
+		zap.L().Error("logic.VotePoll failed", zap.Error(err))
+		responsePostError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+// GetUserProfile: 获取用户公开的个人信息
+//
+//	@Summary		获取用户公开的个人信息
+//	@Description	返回头像、城市、简介、注册时间和karma， 不包括邮箱和手机号码
+//	@Tags			Users
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	string	true	"用户id"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	models.PublicProfile
+//	@Router			/users/{id} [get]
+func GetUserProfile(ctx *gin.Context) {
+	userID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	profile, err := logic.GetPublicProfileByID(userID)
+	if err != nil {
+		responseProfileError(ctx, "logic.GetPublicProfileByID failed", err)
+		return
+	}
+	ResponseSuccess(ctx, profile)
+}
+
+// GetUserProfileByName: 根据用户名获取用户公开的个人信息
+//
+//	@Summary		根据用户名获取用户公开的个人信息
+//	@Description	返回头像、城市、简介、注册时间和karma， 不包括邮箱和手机号码
+//	@Tags			Users
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			username		path	string	true	"用户名"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	models.PublicProfile
+//	@Router			/users/by-name/{username} [get]
+func GetUserProfileByName(ctx *gin.Context) {
+	profile, err := logic.GetPublicProfileByName(ctx.Param("username"))
+	if err != nil {
+		responseProfileError(ctx, "logic.GetPublicProfileByName failed", err)
+		return
+	}
+	ResponseSuccess(ctx, profile)
+}
+
+// GetUserPosts: 分页获取用户发布的帖子
+//
+//	@Summary		分页获取用户发布的帖子
+//	@Description	最新发布的在前面
+//	@Tags			Users
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	string	true	"用户id"
+//	@Param			page_num		query	int		false	"Page number"
+//	@Param			page_size		query	int		false	"Page size"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	[]models.ApiPostDetail2
+//	@Router			/users/{id}/posts [get]
+func GetUserPosts(ctx *gin.Context) {
+	userID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	pageNum, pageSize := getPageInfo(ctx)
+	viewerID, _ := getCurrentUser(ctx)
+	posts, err := logic.GetUserPosts(viewerID, userID, pageNum, pageSize)
+	if err != nil {
+		responseProfileError(ctx, "logic.GetUserPosts failed", err)
+		return
+	}
+	ResponseSuccess(ctx, posts)
+}
+
+// GetUserComments: 分页获取用户发布的评论
+//
+//	@Summary		分页获取用户发布的评论
+//	@Description	最新发布的在前面
+//	@Tags			Users
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	string	true	"用户id"
+//	@Param			page_num		query	int		false	"Page number"
+//	@Param			page_size		query	int		false	"Page size"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	[]models.Comment
+//	@Router			/users/{id}/comments [get]
+func GetUserComments(ctx *gin.Context) {
+	userID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	pageNum, pageSize := getPageInfo(ctx)
+	comments, err := logic.GetUserComments(userID, pageNum, pageSize)
+	if err != nil {
+		responseProfileError(ctx, "logic.GetUserComments failed", err)
+		return
+	}
+	ResponseSuccess(ctx, comments)
+}
+
+// GetUserUpvotedPosts: 分页获取用户点赞过的帖子
+//
+//	@Summary		分页获取用户点赞过的帖子
+//	@Description	最近点赞的在前面， 用户在隐私设置中隐藏了点赞记录时只有自己可以查看
+//	@Tags			Users
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	string	true	"用户id"
+//	@Param			page_num		query	int		false	"Page number"
+//	@Param			page_size		query	int		false	"Page size"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	[]models.ApiPostDetail2
+//	@Router			/users/{id}/upvoted [get]
+func GetUserUpvotedPosts(ctx *gin.Context) {
+	viewerID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	userID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	pageNum, pageSize := getPageInfo(ctx)
+	posts, err := logic.GetUserUpvotedPosts(viewerID, userID, pageNum, pageSize)
+	if err != nil {
+		responseProfileError(ctx, "logic.GetUserUpvotedPosts failed", err)
+		return
+	}
+	ResponseSuccess(ctx, posts)
+}
+
+// UpdatePrivacy: 修改隐私设置
+//
+//	@Summary		修改隐私设置
+//	@Description	hide_votes为true时其他用户不能查看自己点赞过的帖子
+//	@Tags			User
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			object			body	models.ParamPrivacy	false	"查询参数"
+//	@Param			Authorization	header	string				false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/user/privacy [put]
+func UpdatePrivacy(ctx *gin.Context) {
+	p := new(models.ParamPrivacy)
+	if err := ctx.ShouldBindJSON(p); err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	if err = logic.UpdatePrivacy(userID, p); err != nil {
+		zap.L().Error("logic.UpdatePrivacy failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+func responseProfileError(ctx *gin.Context, msg string, err error) {
+	zap.L().Error(msg, zap.Error(err))
+	switch {
+	case errors.Is(err, mysql.ErrorUserNotExist):
+		ResponseError(ctx, CodeUserNotExist)
+	case errors.Is(err, logic.ErrorVotesHidden):
+		ResponseError(ctx, CodeVotesHidden)
+	default:
+		ResponseError(ctx, CodeServerBusy)
+	}
+}
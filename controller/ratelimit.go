@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+)
+
+// responseIfRateLimited: 请求被限流时返回CodeTooManyRequests和需要等待的秒数
+// 返回true说明已经写入了响应
+func responseIfRateLimited(ctx *gin.Context, err error) bool {
+	var rlErr *logic.RateLimitError
+	if !errors.As(err, &rlErr) {
+		return false
+	}
+	seconds := rlErr.Seconds()
+	ctx.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	ctx.JSON(http.StatusOK, ResponseData{
+		Code: CodeTooManyRequests,
+		Msg:  CodeTooManyRequests.Msg(),
+		Data: gin.H{"retry_after": seconds},
+	})
+	return true
+}
@@ -5,9 +5,17 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/jwt"
 )
 
-const CtxUserIDKey = "userID"
+const (
+	CtxUserIDKey = "userID"
+	CtxClaimsKey = "claims"
+
+	// 使用个人访问令牌认证时保存令牌信息， 使用JWT认证时不存在
+	CtxAccessTokenKey = "accessToken"
+)
 
 var ErrorUserNotLogin = errors.New("用户没有登录")
 
@@ -25,19 +33,46 @@ func getCurrentUser(c *gin.Context) (userID int64, err error) {
 	return
 }
 
+// getCurrentClaims: 获取当前请求携带的access token的claims
+func getCurrentClaims(c *gin.Context) (*jwt.MyClaims, error) {
+	v, ok := c.Get(CtxClaimsKey)
+	if !ok {
+		return nil, ErrorUserNotLogin
+	}
+	mc, ok := v.(*jwt.MyClaims)
+	if !ok {
+		return nil, ErrorUserNotLogin
+	}
+	return mc, nil
+}
+
+// getDevice: 获取发起当前请求的设备信息， 登录时记录到会话中
+func getDevice(c *gin.Context) *models.Device {
+	return &models.Device{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
+
+const maxPageSize = 100 // 每页最多返回的数量
+
+// getPageInfo: 获取分页参数， 页码至少为1， 每页的数量在1~maxPageSize之间
 func getPageInfo(ctx *gin.Context) (int64, int64) {
 	pageNumStr := ctx.Query("page_num")
 	pageSizeStr := ctx.Query("page_size")
 
 	pageNum, err := strconv.ParseInt(pageNumStr, 10, 64)
-	if err != nil {
+	if err != nil || pageNum < 1 {
 		pageNum = 1 // 如果说没有传输， 或者传输错误， 就设置一个默认值
 	}
 
 	pageSize, err := strconv.ParseInt(pageSizeStr, 10, 64)
-	if err != nil {
+	if err != nil || pageSize < 1 {
 		pageSize = 10
 	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
 
 	return pageNum, pageSize
 }
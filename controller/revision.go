@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"go.uber.org/zap"
+)
+
+// GetPostRevisions: 获取帖子所有的版本
+//
+//	@Summary		获取帖子所有的版本
+//	@Description	只有作者、社区的版主和站点管理员可以查看， 版本1是原始内容
+//	@Tags			Post
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int		true	"Post ID"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	[]models.PostRevision
+//	@Router			/post/{id}/revisions [get]
+func GetPostRevisions(ctx *gin.Context) {
+	postID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+
+	revisions, err := logic.GetPostRevisions(userID, postID)
+	if err != nil {
+		zap.L().Error("logic.GetPostRevisions failed", zap.Error(err))
+		responsePostError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, revisions)
+}
+
+// GetPostRevisionDiff: 比较某个版本和上一个版本
+//
+//	@Summary		比较某个版本和上一个版本
+//	@Description	按行比较标题和内容， op 为 = 表示没有修改， - 表示删除， + 表示新增
+//	@Tags			Post
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id				path	int		true	"Post ID"
+//	@Param			version			path	int		true	"版本号"
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	models.PostRevisionDiff
+//	@Router			/post/{id}/revisions/{version}/diff [get]
+func GetPostRevisionDiff(ctx *gin.Context) {
+	postID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	version, err := strconv.Atoi(ctx.Param("version"))
+	if err != nil || version < 1 {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+
+	data, err := logic.GetPostRevisionDiff(userID, postID, version)
+	if err != nil {
+		zap.L().Error("logic.GetPostRevisionDiff failed", zap.Error(err))
+		responsePostError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, data)
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"go.uber.org/zap"
+)
+
+// GetSessions: 获取当前用户所有登录的设备
+//
+//	@Summary		获取当前用户所有登录的设备
+//	@Description	列出当前用户所有有效的登录会话， current为true的是发起当前请求的会话
+//	@Tags			User
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	[]models.Session
+//	@Router			/user/sessions [get]
+func GetSessions(ctx *gin.Context) {
+	// 1. 获取当前token的信息
+	mc, err := getCurrentClaims(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+
+	// 2. 查询所有的登录会话
+	sessions, err := logic.GetSessions(mc.UserID, mc.SessionID)
+	if err != nil {
+		zap.L().Error("logic.GetSessions failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+
+	// 3. 返回响应
+	ResponseSuccess(ctx, sessions)
+}
+
+// DeleteSession: 远程注销某个登录的设备
+//
+//	@Summary		远程注销某个登录的设备
+//	@Description	删除指定的登录会话， 这个会话下的access token和refresh token立即失效
+//	@Tags			User
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Param			id				path	string	true	"会话id"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/user/sessions/{id} [delete]
+func DeleteSession(ctx *gin.Context) {
+	// 1. 获取参数
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	sessionID := ctx.Param("id")
+
+	// 2. 删除登录会话
+	if err := logic.DeleteSession(userID, sessionID); err != nil {
+		zap.L().Error("logic.DeleteSession failed", zap.Error(err))
+		if errors.Is(err, logic.ErrorSessionNotExist) {
+			ResponseError(ctx, CodeSessionNotExist)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+
+	// 3. 返回响应
+	ResponseSuccess(ctx, nil)
+}
@@ -58,6 +58,9 @@ func SignUpHandler(ctx *gin.Context) {
 			ResponseError(ctx, CodeUserExist)
 			return
 		}
+		if responseRegistrationError(ctx, err) {
+			return
+		}
 		ResponseError(ctx, CodeServerBusy)
 		return
 	}
@@ -170,8 +173,11 @@ func SendPhoneCode(ctx *gin.Context) {
 		return
 	}
 	// 2. 进行业务处理：发送验证码， 保存验证码
-	if err := logic.SendPhoneCode(p.Phone); err != nil {
+	if err := logic.SendPhoneCode(p.Phone, ctx.ClientIP()); err != nil {
 		zap.L().Error("send phone code failed..", zap.Error(err))
+		if responseIfRateLimited(ctx, err) {
+			return
+		}
 		ResponseError(ctx, CodePhoneCodeSendError)
 		return
 	}
@@ -197,8 +203,11 @@ func SendEmailCode(ctx *gin.Context) {
 	}
 
 	// 2. 业务逻辑：发送验证码给指定邮箱
-	if err := logic.SendEmailCode(p.Email); err != nil {
+	if err := logic.SendEmailCode(p.Email, ctx.ClientIP()); err != nil {
 		zap.L().Error("send email code failed..", zap.Error(err))
+		if responseIfRateLimited(ctx, err) {
+			return
+		}
 		ResponseError(ctx, CodeEmailCodeSendError)
 		return
 	}
@@ -232,6 +241,9 @@ func SignupUsingPhone(ctx *gin.Context) {
 			ResponseError(ctx, CodePhoneExist)
 			return
 		}
+		if responseRegistrationError(ctx, err) {
+			return
+		}
 		ResponseError(ctx, CodeServerBusy)
 		return
 	}
@@ -266,6 +278,9 @@ func SignupUsingEmail(ctx *gin.Context) {
 			ResponseError(ctx, CodeEmailExist)
 			return
 		}
+		if responseRegistrationError(ctx, err) {
+			return
+		}
 		ResponseError(ctx, CodeServerBusy)
 		return
 	}
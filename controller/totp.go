@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+// LoginTwoFactor: 开启两步验证的用户使用登录挑战和验证码完成登录
+//
+//	@Summary		两步验证登录
+//	@Description	登录接口返回 two_factor_required 时， 使用 challenge_token 和TOTP验证码(或恢复码)换取token
+//	@Tags			Auth
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			object	body	models.ParamLoginTwoFactor	false	"查询参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/auth/login/2fa [post]
+func LoginTwoFactor(ctx *gin.Context) {
+	// 1. 进行参数验证
+	p := new(models.ParamLoginTwoFactor)
+	if ok := Validate(ctx, p, ValidateLoginTwoFactor); !ok {
+		return
+	}
+
+	// 2. 校验验证码并签发token
+	user, accessToken, refreshToken, err := logic.LoginWithTwoFactor(p, getDevice(ctx))
+	if err != nil {
+		zap.L().Error("logic.LoginWithTwoFactor failed", zap.Error(err))
+		responseTOTPError(ctx, err)
+		return
+	}
+
+	// 3. 返回响应
+	ResponseSuccess(ctx, gin.H{
+		"user_id":       user.ID,
+		"username":      user.Username,
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	})
+}
+
+// EnrollTOTP: 获取两步验证的密钥
+//
+//	@Summary		获取两步验证的密钥
+//	@Description	生成新的TOTP密钥和otpauth地址， 前端把uri渲染成二维码， 之后调用 /user/2fa/totp/confirm 确认开启
+//	@Tags			User
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string	false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	models.TOTPEnrollment
+//	@Router			/user/2fa/totp [post]
+func EnrollTOTP(ctx *gin.Context) {
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+
+	enrollment, err := logic.EnrollTOTP(userID)
+	if err != nil {
+		zap.L().Error("logic.EnrollTOTP failed", zap.Error(err))
+		responseTOTPError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, enrollment)
+}
+
+// ConfirmTOTP: 确认开启两步验证
+//
+//	@Summary		确认开启两步验证
+//	@Description	提交验证器App生成的验证码确认开启两步验证， 返回的恢复码只会显示这一次
+//	@Tags			User
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			object			body	models.ParamTOTPCode	false	"查询参数"
+//	@Param			Authorization	header	string					false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/user/2fa/totp/confirm [post]
+func ConfirmTOTP(ctx *gin.Context) {
+	// 1. 进行参数验证
+	p := new(models.ParamTOTPCode)
+	if ok := Validate(ctx, p, ValidateTOTPCode); !ok {
+		return
+	}
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+
+	// 2. 开启两步验证并生成恢复码
+	codes, err := logic.ConfirmTOTP(userID, p.Code)
+	if err != nil {
+		zap.L().Error("logic.ConfirmTOTP failed", zap.Error(err))
+		responseTOTPError(ctx, err)
+		return
+	}
+
+	// 3. 返回响应
+	ResponseSuccess(ctx, gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTOTP: 关闭两步验证
+//
+//	@Summary		关闭两步验证
+//	@Description	提交TOTP验证码或者恢复码关闭两步验证， 同时删除所有的恢复码
+//	@Tags			User
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			object			body	models.ParamTOTPCode	false	"查询参数"
+//	@Param			Authorization	header	string					false	"Bearer 用户令牌"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	map[string]bool
+//	@Router			/user/2fa/totp [delete]
+func DisableTOTP(ctx *gin.Context) {
+	// 1. 进行参数验证
+	p := new(models.ParamTOTPCode)
+	if ok := Validate(ctx, p, ValidateTOTPCode); !ok {
+		return
+	}
+	userID, err := getCurrentUser(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+
+	// 2. 关闭两步验证
+	if err := logic.DisableTOTP(userID, p.Code); err != nil {
+		zap.L().Error("logic.DisableTOTP failed", zap.Error(err))
+		responseTOTPError(ctx, err)
+		return
+	}
+
+	// 3. 返回响应
+	ResponseSuccess(ctx, nil)
+}
+
+// responseTOTPError: 把两步验证相关的错误转换成响应码
+func responseTOTPError(ctx *gin.Context, err error) {
+	if responseIfRateLimited(ctx, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, logic.ErrorInvalidTOTPCode):
+		ResponseError(ctx, CodeInvalidTOTPCode)
+	case errors.Is(err, logic.ErrorTOTPAlreadyEnabled):
+		ResponseError(ctx, CodeTOTPAlreadyEnabled)
+	case errors.Is(err, logic.ErrorTOTPNotEnabled):
+		ResponseError(ctx, CodeTOTPNotEnabled)
+	case errors.Is(err, logic.ErrorTOTPNotEnrolled):
+		ResponseError(ctx, CodeTOTPNotEnrolled)
+	case errors.Is(err, logic.ErrorInvalidChallenge):
+		ResponseError(ctx, CodeInvalidChallenge)
+	default:
+		ResponseError(ctx, CodeServerBusy)
+	}
+}
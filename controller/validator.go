@@ -359,6 +359,38 @@ func ValidateLoginUsingEmail(data interface{}, ctx *gin.Context) map[string][]st
 	return errs
 }
 
+// ValidateLoginUsingPassword: 使用用户名、邮箱或手机号码+密码进行登陆
+func ValidateLoginUsingPassword(data interface{}, ctx *gin.Context) map[string][]string {
+	rules := govalidator.MapData{
+		"identifier": []string{"required", "min:3", "max:30"},
+		"password":   []string{"required", "min:6"},
+	}
+	messages := govalidator.MapData{
+		"identifier": []string{
+			"required:用户名/邮箱/手机号为必填项",
+			"min:用户名/邮箱/手机号长度需大于 3",
+			"max:用户名/邮箱/手机号长度需小于 30",
+		},
+		"password": []string{
+			"required:密码为必填项",
+			"min:密码长度需大于 6",
+		},
+	}
+	return validate(data, rules, messages)
+}
+
+func ValidateDeleteAccount(data interface{}, ctx *gin.Context) map[string][]string {
+	rules := govalidator.MapData{
+		"password": []string{"required"},
+	}
+	messages := govalidator.MapData{
+		"password": []string{
+			"required:密码为必填项",
+		},
+	}
+	return validate(data, rules, messages)
+}
+
 // ValidateLoginUsingPhone: 使用手机号码+验证码进行登陆
 func ValidateLoginUsingPhoneWithCode(data interface{}, ctx *gin.Context) map[string][]string {
 	rules := govalidator.MapData{
@@ -384,6 +416,178 @@ func ValidateLoginUsingPhoneWithCode(data interface{}, ctx *gin.Context) map[str
 	return errs
 }
 
+// ValidateRefreshToken: 验证刷新token的参数
+func ValidateRefreshToken(data interface{}, ctx *gin.Context) map[string][]string {
+	rules := govalidator.MapData{
+		"refresh_token": []string{"required"},
+	}
+	messages := govalidator.MapData{
+		"refresh_token": []string{
+			"required:refresh_token 为必填项",
+		},
+	}
+	return validate(data, rules, messages)
+}
+
+func ValidateLoginTwoFactor(data interface{}, ctx *gin.Context) map[string][]string {
+	rules := govalidator.MapData{
+		"challenge_token": []string{"required"},
+		"code":            []string{"required", "between:6,16"},
+	}
+	messages := govalidator.MapData{
+		"challenge_token": []string{
+			"required:challenge_token 为必填项",
+		},
+		"code": []string{
+			"required:验证码为必填项",
+			"between:验证码长度需在 6~16 之间",
+		},
+	}
+	return validate(data, rules, messages)
+}
+
+func ValidateTOTPCode(data interface{}, ctx *gin.Context) map[string][]string {
+	rules := govalidator.MapData{
+		"code": []string{"required", "between:6,16"},
+	}
+	messages := govalidator.MapData{
+		"code": []string{
+			"required:验证码为必填项",
+			"between:验证码长度需在 6~16 之间",
+		},
+	}
+	return validate(data, rules, messages)
+}
+
+func ValidateCommunityMemberRole(data interface{}, ctx *gin.Context) map[string][]string {
+	rules := govalidator.MapData{
+		"role": []string{"required", "in:moderator,member"},
+	}
+	messages := govalidator.MapData{
+		"role": []string{
+			"required:角色为必填项",
+			"in:角色只能是 moderator 或 member",
+		},
+	}
+	return validate(data, rules, messages)
+}
+
+// ValidateCreateInvitation: 管理员生成邀请码
+func ValidateCreateInvitation(data interface{}, ctx *gin.Context) map[string][]string {
+	errs := validate(data, govalidator.MapData{}, govalidator.MapData{})
+
+	p := data.(*models.ParamCreateInvitation)
+	if p.MaxUses < 1 || p.MaxUses > 1000 {
+		errs["max_uses"] = append(errs["max_uses"], "最多使用次数需在 1~1000 之间")
+	}
+	if p.ExpiresIn < 0 || p.ExpiresIn > 365 {
+		errs["expires_in"] = append(errs["expires_in"], "有效天数需在 0~365 之间， 0 表示永不过期")
+	}
+	return errs
+}
+
+// ValidateCreateAccessToken: 创建个人访问令牌
+func ValidateCreateAccessToken(data interface{}, ctx *gin.Context) map[string][]string {
+	rules := govalidator.MapData{
+		"name": []string{"required", "between:1,64"},
+	}
+	messages := govalidator.MapData{
+		"name": []string{
+			"required:令牌名称为必填项",
+			"between:令牌名称长度需在 1~64 之间",
+		},
+	}
+	errs := validate(data, rules, messages)
+
+	p := data.(*models.ParamCreateAccessToken)
+	if len(p.Scopes) == 0 {
+		errs["scopes"] = append(errs["scopes"], "权限范围为必填项")
+	}
+	for _, scope := range p.Scopes {
+		valid := false
+		for _, s := range models.AccessScopes {
+			if scope == s {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			errs["scopes"] = append(errs["scopes"], "权限范围只能是 read, post, vote 或 moderate")
+			break
+		}
+	}
+	if p.ExpiresIn < 0 || p.ExpiresIn > 365 {
+		errs["expires_in"] = append(errs["expires_in"], "有效天数需在 0~365 之间， 0 表示永不过期")
+	}
+	return errs
+}
+
+// ValidateOIDCCallback: 第三方登录回调
+func ValidateOIDCCallback(data interface{}, ctx *gin.Context) map[string][]string {
+	rules := govalidator.MapData{
+		"code":  []string{"required"},
+		"state": []string{"required"},
+	}
+	messages := govalidator.MapData{
+		"code": []string{
+			"required:code 为必填项",
+		},
+		"state": []string{
+			"required:state 为必填项",
+		},
+	}
+	return validate(data, rules, messages)
+}
+
+// ValidateUpdatePost: 编辑帖子
+func ValidateUpdatePost(data interface{}, ctx *gin.Context) map[string][]string {
+	rules := govalidator.MapData{
+		"title":   []string{"required", "between:1,300"},
+		"content": []string{"required"},
+	}
+	messages := govalidator.MapData{
+		"title": []string{
+			"required:标题为必填项",
+			"between:标题长度需在 1~300 之间",
+		},
+		"content": []string{
+			"required:内容为必填项",
+		},
+	}
+	return validate(data, rules, messages)
+}
+
+// ValidateCommunityRequirements: 设置社区的发帖门槛
+func ValidateCommunityRequirements(data interface{}, ctx *gin.Context) map[string][]string {
+	errs := validate(data, govalidator.MapData{}, govalidator.MapData{})
+
+	p := data.(*models.ParamCommunityRequirements)
+	if p.MinKarma < 0 {
+		errs["min_karma"] = append(errs["min_karma"], "最低karma不能小于 0")
+	}
+	if p.MinAccountAgeDays < 0 || p.MinAccountAgeDays > 3650 {
+		errs["min_account_age_days"] = append(errs["min_account_age_days"], "最低注册天数需在 0~3650 之间")
+	}
+	if p.ReviewAccountAgeDays < 0 || p.ReviewAccountAgeDays > 3650 {
+		errs["review_account_age_days"] = append(errs["review_account_age_days"], "需要审核的注册天数需在 0~3650 之间")
+	}
+	return errs
+}
+
+// ValidateRejectPost: 拒绝或移除帖子
+func ValidateRejectPost(data interface{}, ctx *gin.Context) map[string][]string {
+	rules := govalidator.MapData{
+		"reason": []string{"required", "between:1,200"},
+	}
+	messages := govalidator.MapData{
+		"reason": []string{
+			"required:原因为必填项",
+			"between:原因长度需在 1~200 之间",
+		},
+	}
+	return validate(data, rules, messages)
+}
+
 func ValidateCaptcha(captchaID, captchaAnswer string, errs map[string][]string) map[string][]string {
 	if ok := captcha.NewCaptcha().VerifyCaptcha(captchaID, captchaAnswer); !ok {
 		errs["captcha_answer"] = append(errs["captcha_answer"], "图片验证码错误")
@@ -521,6 +725,104 @@ func ValidateUpdatePassword(data interface{}, c *gin.Context) map[string][]strin
 	return errs
 }
 
+// ValidateResetPasswordUsingPhone: 验证使用手机验证码重置密码的参数
+func ValidateResetPasswordUsingPhone(data interface{}, ctx *gin.Context) map[string][]string {
+	rules := govalidator.MapData{
+		"phone":            []string{"required", "digits:11"},
+		"password":         []string{"required", "min:6"},
+		"password_confirm": []string{"required"},
+		"verify_code":      []string{"required", "digits:6"},
+		"captcha_id":       []string{"required"},
+		"captcha_answer":   []string{"required", "digits:6"},
+	}
+	messages := govalidator.MapData{
+		"phone": []string{
+			"required:手机号为必填项，参数名称 phone",
+			"digits:手机号长度必须为 11 位的数字",
+		},
+		"password": []string{
+			"required:密码为必填项",
+			"min:密码长度需大于 6",
+		},
+		"password_confirm": []string{
+			"required:确认密码框为必填项",
+		},
+		"verify_code": []string{
+			"required:验证码答案必填",
+			"digits:验证码长度必须为 6 位的数字",
+		},
+		"captcha_id": []string{
+			"required:图片验证码的 ID 为必填",
+		},
+		"captcha_answer": []string{
+			"required:图片验证码答案必填",
+			"digits:图片验证码长度必须为 6 位的数字",
+		},
+	}
+
+	errs := validate(data, rules, messages)
+	_data := data.(*models.ParamResetPasswordUsingPhone)
+	errs = ValidatePasswordConfirm(_data.Password, _data.PasswordConfirm, errs)
+	// 图片验证码只能使用一次， 正确时才检查短信或邮件验证码， 验证码输错的次数过多时会失效
+	if ok := captcha.NewCaptcha().VerifyCaptchaOnce(_data.CaptchaID, _data.CaptchaAnswer); !ok {
+		errs["captcha_answer"] = append(errs["captcha_answer"], "图片验证码错误")
+	} else {
+		errs = ValidateKeyCode(_data.Phone, _data.Code, errs)
+	}
+
+	return errs
+}
+
+// ValidateResetPasswordUsingEmail: 验证使用邮箱验证码重置密码的参数
+func ValidateResetPasswordUsingEmail(data interface{}, ctx *gin.Context) map[string][]string {
+	rules := govalidator.MapData{
+		"email":            []string{"required", "min:4", "max:30", "email"},
+		"password":         []string{"required", "min:6"},
+		"password_confirm": []string{"required"},
+		"verify_code":      []string{"required", "digits:6"},
+		"captcha_id":       []string{"required"},
+		"captcha_answer":   []string{"required", "digits:6"},
+	}
+	messages := govalidator.MapData{
+		"email": []string{
+			"required:Email 为必填项",
+			"min:Email 长度需大于 4",
+			"max:Email 长度需小于 30",
+			"email:Email 格式不正确，请提供有效的邮箱地址",
+		},
+		"password": []string{
+			"required:密码为必填项",
+			"min:密码长度需大于 6",
+		},
+		"password_confirm": []string{
+			"required:确认密码框为必填项",
+		},
+		"verify_code": []string{
+			"required:验证码答案必填",
+			"digits:验证码长度必须为 6 位的数字",
+		},
+		"captcha_id": []string{
+			"required:图片验证码的 ID 为必填",
+		},
+		"captcha_answer": []string{
+			"required:图片验证码答案必填",
+			"digits:图片验证码长度必须为 6 位的数字",
+		},
+	}
+
+	errs := validate(data, rules, messages)
+	_data := data.(*models.ParamResetPasswordUsingEmail)
+	errs = ValidatePasswordConfirm(_data.Password, _data.PasswordConfirm, errs)
+	// 图片验证码只能使用一次， 正确时才检查短信或邮件验证码， 验证码输错的次数过多时会失效
+	if ok := captcha.NewCaptcha().VerifyCaptchaOnce(_data.CaptchaID, _data.CaptchaAnswer); !ok {
+		errs["captcha_answer"] = append(errs["captcha_answer"], "图片验证码错误")
+	} else {
+		errs = ValidateKeyCode(_data.Email, _data.Code, errs)
+	}
+
+	return errs
+}
+
 func ValidateUpdateAvatar(data interface{}, ctx *gin.Context) map[string][]string {
 	rules := govalidator.MapData{
 		"file:avatar": []string{"ext:png,jpg,jpeg", "size:20971520", "required"},
@@ -535,6 +837,21 @@ func ValidateUpdateAvatar(data interface{}, ctx *gin.Context) map[string][]strin
 	return validateFile(ctx, data, rules, message)
 }
 
+// ValidateUploadPostImage: 上传图片帖子的图片
+func ValidateUploadPostImage(data interface{}, ctx *gin.Context) map[string][]string {
+	rules := govalidator.MapData{
+		"file:image": []string{"ext:png,jpg,jpeg,gif", "size:20971520", "required"},
+	}
+	message := govalidator.MapData{
+		"file:image": []string{
+			"ext:只能上传 png, jpg, jpeg, gif 任意一种的图片",
+			"size:图片文件最大不能超过 20MB",
+			"required:必须上传图片",
+		},
+	}
+	return validateFile(ctx, data, rules, message)
+}
+
 // validateFile: 验证上传文件是否有效
 func validateFile(c *gin.Context, data interface{}, rules govalidator.MapData, messages govalidator.MapData) map[string][]string {
 	opts := govalidator.Options{
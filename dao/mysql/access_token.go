@@ -0,0 +1,56 @@
+package mysql
+
+import (
+	"errors"
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"gorm.io/gorm"
+)
+
+// InsertAccessToken: 保存新的个人访问令牌
+func InsertAccessToken(token *models.AccessToken) error {
+	return DB.Create(token).Error
+}
+
+// GetAccessTokens: 获取用户所有的个人访问令牌， 按照创建时间倒序排列
+func GetAccessTokens(userID int64) ([]*models.AccessToken, error) {
+	tokens := []*models.AccessToken{}
+	err := DB.Where("user_id = ?", userID).Order("create_time DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// CountAccessTokens: 用户拥有的个人访问令牌数量
+func CountAccessTokens(userID int64) (int64, error) {
+	var count int64
+	err := DB.Model(&models.AccessToken{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// GetAccessTokenByHash: 根据令牌的hash查询， 不存在时返回nil
+func GetAccessTokenByHash(tokenHash string) (*models.AccessToken, error) {
+	token := new(models.AccessToken)
+	err := DB.Where("token_hash = ?", tokenHash).First(token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return token, err
+}
+
+// DeleteAccessToken: 撤销用户的某个令牌， 令牌不存在或者不属于该用户时返回false
+func DeleteAccessToken(userID, tokenID int64) (bool, error) {
+	res := DB.Where("token_id = ? AND user_id = ?", tokenID, userID).Delete(&models.AccessToken{})
+	return res.RowsAffected == 1, res.Error
+}
+
+// DeleteUserAccessTokens: 撤销用户所有的个人访问令牌
+func DeleteUserAccessTokens(userID int64) error {
+	return DB.Where("user_id = ?", userID).Delete(&models.AccessToken{}).Error
+}
+
+// TouchAccessToken: 更新令牌的最后使用时间， 距离上次更新不到interval时不更新， 避免每个请求都写一次数据库
+func TouchAccessToken(tokenID int64, now time.Time, interval time.Duration) error {
+	return DB.Model(&models.AccessToken{}).
+		Where("token_id = ? AND (last_used_at IS NULL OR last_used_at < ?)", tokenID, now.Add(-interval)).
+		Update("last_used_at", now).Error
+}
@@ -0,0 +1,126 @@
+package mysql
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"gorm.io/gorm"
+)
+
+// ScheduleUserDeletion: 记录账号的删除时间
+func ScheduleUserDeletion(userID int64, at time.Time) error {
+	return DB.Model(&models.User{}).Where("user_id = ?", userID).
+		Update("deletion_scheduled_at", at).Error
+}
+
+// CancelUserDeletion: 取消账号的注销
+func CancelUserDeletion(userID int64) error {
+	return DB.Model(&models.User{}).Where("user_id = ?", userID).
+		Update("deletion_scheduled_at", nil).Error
+}
+
+// GetUsersDueForDeletion: 获取已经过了等待期， 需要匿名化的账号
+// 按照(deletion_scheduled_at, user_id)排序， 从上一批的最后一个账号之后继续查询， 失败的账号不会挡住后面的账号
+func GetUsersDueForDeletion(now, afterTime time.Time, afterID int64, limit int) ([]*models.User, error) {
+	users := []*models.User{}
+	err := DB.Model(&models.User{}).
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", now).
+		Where("deletion_scheduled_at > ? OR (deletion_scheduled_at = ? AND user_id > ?)", afterTime, afterTime, afterID).
+		Order("deletion_scheduled_at ASC, user_id ASC").
+		Limit(limit).Find(&users).Error
+	return users, err
+}
+
+// initDeletedUser: 创建注销账号的内容转给的占位用户， 已经存在时不做修改
+// 这个id已经被普通用户使用时返回错误， 否则注销账号的内容会转给这个用户
+func initDeletedUser() error {
+	now := time.Now()
+	user := &models.User{
+		ID:          models.DeletedUserID,
+		Username:    models.DeletedPlaceholder,
+		Role:        models.RoleUser,
+		DeletedTime: &now,
+	}
+	if err := DB.Where("user_id = ?", models.DeletedUserID).FirstOrCreate(user).Error; err != nil {
+		return err
+	}
+	if user.Username != models.DeletedPlaceholder || user.DeletedTime == nil {
+		return fmt.Errorf("user_id %d is reserved for deleted accounts but belongs to user %q", models.DeletedUserID, user.Username)
+	}
+	return nil
+}
+
+// AnonymizeUser: 清除账号的所有个人信息
+// 帖子、评论和编辑记录转给占位用户， 其他记录还指向这个用户， 所以保留这条记录， 只清空其中的个人信息
+func AnonymizeUser(userID int64) error {
+	now := time.Now()
+	return DB.Transaction(func(tx *gorm.DB) error {
+		// 已经删除的帖子和评论也要转移， 恢复之后不能再关联到原来的用户
+		if err := tx.Unscoped().Model(&models.Post{}).Where("author_id = ?", userID).
+			Update("author_id", models.DeletedUserID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Comment{}).Where("author_id = ?", userID).
+			Update("author_id", models.DeletedUserID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PostRevision{}).Where("editor_id = ?", userID).
+			Update("editor_id", models.DeletedUserID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.OutboundLink{}).Where("author_id = ?", userID).
+			Update("author_id", models.DeletedUserID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PostImage{}).Where("uploader_id = ?", userID).
+			Update("uploader_id", models.DeletedUserID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("user_id = ?", userID).
+			Updates(map[string]interface{}{
+				"username":              "deleted_" + strconv.FormatInt(userID, 10),
+				"password":              "",
+				"salt":                  "",
+				"email":                 "",
+				"phone":                 "",
+				"city":                  "",
+				"introduction":          "",
+				"avatar":                "",
+				"totp_secret":           "",
+				"totp_enabled":          false,
+				"role":                  models.RoleUser,
+				"deletion_scheduled_at": nil,
+				"deleted_time":          now,
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.AccessToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.CommunityMember{}).Error
+	})
+}
+
+// GetPostsByAuthor: 获取用户发布的所有帖子
+func GetPostsByAuthor(userID int64) ([]*models.Post, error) {
+	posts := []*models.Post{}
+	err := DB.Model(&models.Post{}).Where("author_id = ?", userID).
+		Order("create_time").Find(&posts).Error
+	return posts, err
+}
+
+// GetCommentsByAuthor: 获取用户发布的所有评论
+func GetCommentsByAuthor(userID int64) ([]*models.Comment, error) {
+	comments := []*models.Comment{}
+	err := DB.Model(&models.Comment{}).Where("author_id = ?", userID).
+		Order("create_time").Find(&comments).Error
+	return comments, err
+}
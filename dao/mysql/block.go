@@ -0,0 +1,55 @@
+package mysql
+
+import (
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"gorm.io/gorm/clause"
+)
+
+// UpsertUserBlock: 屏蔽或静音用户， 已经存在时修改类型
+func UpsertUserBlock(userID, targetID int64, blockType string) error {
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "target_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"type"}),
+	}).Create(&models.UserBlock{
+		UserID:   userID,
+		TargetID: targetID,
+		Type:     blockType,
+	}).Error
+}
+
+// DeleteUserBlock: 取消屏蔽或静音， 返回是否删除了记录
+func DeleteUserBlock(userID, targetID int64, blockType string) (bool, error) {
+	result := DB.Where("user_id = ? AND target_id = ? AND type = ?", userID, targetID, blockType).
+		Delete(&models.UserBlock{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetUserBlocks: 获取用户所有的屏蔽和静音记录， 用于重建redis中的缓存
+func GetUserBlocks(userID int64) ([]*models.UserBlock, error) {
+	blocks := []*models.UserBlock{}
+	err := DB.Where("user_id = ?", userID).Find(&blocks).Error
+	return blocks, err
+}
+
+// GetBlockedUsers: 分页获取用户屏蔽或静音的人， 最近的在前面
+func GetBlockedUsers(userID int64, blockType string, pageNum, pageSize int64) ([]*models.BlockedUser, error) {
+	users := []*models.BlockedUser{}
+	err := DB.Model(&models.UserBlock{}).
+		Select("users.user_id, users.username, users.avatar, user_blocks.create_time AS blocked_at").
+		Joins("JOIN users ON users.user_id = user_blocks.target_id").
+		Where("user_blocks.user_id = ? AND user_blocks.type = ?", userID, blockType).
+		Order("user_blocks.create_time DESC").
+		Offset(int((pageNum - 1) * pageSize)).
+		Limit(int(pageSize)).
+		Scan(&users).Error
+	return users, err
+}
+
+// DeleteUserBlocks: 删除用户所有的屏蔽和静音关系， 返回屏蔽或静音了该用户的人的id
+func DeleteUserBlocks(userID int64) (blockers []int64, err error) {
+	if err = DB.Model(&models.UserBlock{}).Where("target_id = ?", userID).Pluck("user_id", &blockers).Error; err != nil {
+		return
+	}
+	err = DB.Where("user_id = ? OR target_id = ?", userID, userID).Delete(&models.UserBlock{}).Error
+	return
+}
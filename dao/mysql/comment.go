@@ -1,6 +1,8 @@
 package mysql
 
 import (
+	"time"
+
 	"github.com/xiaorui/reddit-async/reddit-backend/models"
 	"gorm.io/gorm"
 )
@@ -19,20 +21,38 @@ func GetCommentByID(commentID int64) (*models.Comment, error) {
 	return comment, err
 }
 
-// DeleteComment: 删除制定评论
-func DeleteComment(userID int64, comment *models.Comment) error {
-	if userID != comment.AuthorID {
-		return ErrorNotPermission
+// GetCommentByIDUnscoped: 返回Comment， 包括已经删除的评论
+func GetCommentByIDUnscoped(commentID int64) (*models.Comment, error) {
+	comment := &models.Comment{}
+	err := DB.Unscoped().Model(&models.Comment{}).Where("comment_id = ?", commentID).First(comment).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrorCommentNotFound
 	}
+	return comment, err
+}
+
+// SoftDeleteComment: 删除评论， 只是记录删除时间和删除的用户
+func SoftDeleteComment(commentID, deletedBy int64, now time.Time) error {
+	return DB.Model(&models.Comment{}).Where("comment_id = ?", commentID).Updates(map[string]interface{}{
+		"deleted_at": now,
+		"deleted_by": deletedBy,
+	}).Error
+}
 
-	return DB.Delete(comment).Error
+// RestoreComment: 恢复已经删除的评论
+func RestoreComment(commentID int64) error {
+	return DB.Unscoped().Model(&models.Comment{}).Where("comment_id = ?", commentID).Updates(map[string]interface{}{
+		"deleted_at": nil,
+		"deleted_by": 0,
+	}).Error
 }
 
-// GetComments: 返回评论
+// GetComments: 返回讨论串中的评论， 已经删除的评论也会返回， 保持分页和楼层不变
 func GetComments(postID, pageNum, pageSize int64) ([]*models.Comment, error) {
 	commentList := []*models.Comment{}
-	err := DB.Model(&models.Comment{}).Where("post_id = ?", postID).
-		Limit(int(pageSize)).Offset(int(pageNum - 1)).
+	err := DB.Unscoped().Model(&models.Comment{}).Where("post_id = ?", postID).
+		Order("create_time ASC").
+		Limit(int(pageSize)).Offset(int((pageNum - 1) * pageSize)).
 		Find(&commentList).Error
 	return commentList, err
 }
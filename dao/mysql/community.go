@@ -65,6 +65,12 @@ func SaveCommunity(comm *models.Community) (*models.Community, error) {
 	return comm, nil
 }
 
+// DeleteCommunity: 删除社区以及社区中所有的角色
 func DeleteCommunity(cid string) error {
-	return DB.Where("community_id = ?", cid).Delete(&models.Community{}).Error
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("community_id = ?", cid).Delete(&models.CommunityMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("community_id = ?", cid).Delete(&models.Community{}).Error
+	})
 }
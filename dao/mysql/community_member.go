@@ -0,0 +1,59 @@
+package mysql
+
+import (
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// InsertCommunityWithOwner: 创建社区， 创建者自动成为社区的owner
+func InsertCommunityWithOwner(comm *models.Community, ownerID int64) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comm).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.CommunityMember{
+			CommunityID: comm.ID,
+			UserID:      ownerID,
+			Role:        models.CommunityRoleOwner,
+		}).Error
+	})
+}
+
+// GetCommunityRole: 获取用户在社区中的角色， 不是社区成员时返回空字符串
+func GetCommunityRole(communityID, userID int64) (string, error) {
+	member := new(models.CommunityMember)
+	err := DB.Model(&models.CommunityMember{}).
+		Where("community_id = ? AND user_id = ?", communityID, userID).
+		First(member).Error
+	if err == gorm.ErrRecordNotFound {
+		return "", nil
+	}
+	return member.Role, err
+}
+
+// SetCommunityRole: 设置用户在社区中的角色， 不是成员时会新增
+func SetCommunityRole(communityID, userID int64, role string) error {
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "community_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_time"}),
+	}).Create(&models.CommunityMember{
+		CommunityID: communityID,
+		UserID:      userID,
+		Role:        role,
+	}).Error
+}
+
+// GetCommunityMembers: 获取社区中所有拥有角色的用户
+func GetCommunityMembers(communityID int64) ([]*models.CommunityMember, error) {
+	members := []*models.CommunityMember{}
+	err := DB.Model(&models.CommunityMember{}).Where("community_id = ?", communityID).
+		Order("create_time").Find(&members).Error
+	return members, err
+}
+
+// DeleteCommunityMember: 删除用户在社区中的角色
+func DeleteCommunityMember(communityID, userID int64) error {
+	return DB.Where("community_id = ? AND user_id = ?", communityID, userID).
+		Delete(&models.CommunityMember{}).Error
+}
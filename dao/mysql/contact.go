@@ -0,0 +1,23 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+)
+
+// UpdateContact: 修改用户的邮箱或手机号码， 同时写入验证时间
+func UpdateContact(userID int64, contactType, value string, verifiedAt *time.Time) error {
+	return DB.Model(&models.User{}).Where("user_id = ?", userID).
+		Updates(map[string]interface{}{
+			contactType:                  value,
+			contactType + "_verified_at": verifiedAt,
+		}).Error
+}
+
+// MarkContactVerified: 通过验证码验证了邮箱或手机号码之后， 记录验证时间
+func MarkContactVerified(userID int64, contactType string) error {
+	return DB.Model(&models.User{}).
+		Where("user_id = ? AND "+contactType+"_verified_at IS NULL", userID).
+		Update(contactType+"_verified_at", time.Now()).Error
+}
@@ -0,0 +1,88 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+)
+
+// GetDraftsByAuthor: 分页获取用户的草稿， 最新的在前面
+func GetDraftsByAuthor(userID, pageNum, pageSize int64) ([]*models.Post, error) {
+	posts := []*models.Post{}
+	err := DB.Model(&models.Post{}).
+		Where("author_id = ? AND status = ?", userID, models.PostStatusDraft).
+		Order("create_time DESC").
+		Offset(int((pageNum - 1) * pageSize)).
+		Limit(int(pageSize)).
+		Find(&posts).Error
+	return posts, err
+}
+
+// GetDuePosts: 获取已经到了发布时间的草稿， 先到时间的在前面
+// 按照(publish_at, post_id)分页， 只返回排在afterTime和afterID之后的草稿， 第一页传入零值
+func GetDuePosts(now, afterTime time.Time, afterID int64, limit int) ([]*models.Post, error) {
+	posts := make([]*models.Post, 0, limit)
+	err := DB.Model(&models.Post{}).
+		Where("status = ? AND publish_at IS NOT NULL AND publish_at <= ?", models.PostStatusDraft, now).
+		Where("publish_at > ? OR (publish_at = ? AND post_id > ?)", afterTime, afterTime, afterID).
+		Order("publish_at ASC, post_id ASC").
+		Limit(limit).
+		Find(&posts).Error
+	return posts, err
+}
+
+// GetRecentlyPublishedPosts: 获取发布时间在(since, until]之间、还在列表中的帖子
+// 按照(published_time, post_id)分页， 只返回排在afterTime和afterID之后的帖子， 第一页传入零值
+func GetRecentlyPublishedPosts(since, until, afterTime time.Time, afterID int64, limit int) ([]*models.Post, error) {
+	posts := make([]*models.Post, 0, limit)
+	err := DB.Model(&models.Post{}).
+		Where("status IN ? AND published_time > ? AND published_time <= ?", listedPostStatus, since, until).
+		Where("published_time > ? OR (published_time = ? AND post_id > ?)", afterTime, afterTime, afterID).
+		Order("published_time ASC, post_id ASC").
+		Limit(limit).
+		Find(&posts).Error
+	return posts, err
+}
+
+// PublishDraft: 把草稿改为status， 只有仍然是草稿时才会修改， 返回是否修改成功
+func PublishDraft(postID int64, status int32, publishedTime *time.Time) (bool, error) {
+	fields := map[string]interface{}{
+		"status":     status,
+		"publish_at": nil,
+	}
+	if publishedTime != nil {
+		fields["published_time"] = *publishedTime
+	}
+	res := DB.Model(&models.Post{}).
+		Where("post_id = ? AND status = ?", postID, models.PostStatusDraft).
+		Updates(fields)
+	return res.RowsAffected == 1, res.Error
+}
+
+// RevertPublishDraft: 发布之后写入redis失败时把帖子改回草稿， 恢复原来的定时发布时间
+func RevertPublishDraft(postID int64, publishAt *time.Time) error {
+	return DB.Model(&models.Post{}).
+		Where("post_id = ? AND status = ?", postID, models.PostStatusPublished).
+		Updates(map[string]interface{}{
+			"status":         models.PostStatusDraft,
+			"publish_at":     publishAt,
+			"published_time": nil,
+		}).Error
+}
+
+// SchedulePost: 设置草稿定时发布的时间
+func SchedulePost(postID int64, publishAt *time.Time) error {
+	return DB.Model(&models.Post{}).
+		Where("post_id = ? AND status = ?", postID, models.PostStatusDraft).
+		Update("publish_at", publishAt).Error
+}
+
+// UpdateDraft: 修改草稿的标题和内容， 草稿不保存编辑记录
+func UpdateDraft(post *models.Post, title, content string) error {
+	post.Title = title
+	post.Content = content
+	return DB.Model(&models.Post{}).Where("post_id = ?", post.ID).Updates(map[string]interface{}{
+		"title":   title,
+		"content": content,
+	}).Error
+}
@@ -1,6 +1,10 @@
 package mysql
 
-import "errors"
+import (
+	"errors"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
 
 var (
 	ErrorUserExist         = errors.New("用户已经存在.")
@@ -18,4 +22,13 @@ var (
 	ErrorPostNotExist      = errors.New("该帖子不存在")
 	ErrorNotPermission     = errors.New("无操作权限")
 	ErrorCommentNotFound   = errors.New("没有找到该评论")
+	ErrorInvalidInvitation = errors.New("邀请码无效或已过期")
+	ErrorImageNotExist     = errors.New("图片不存在或已经使用")
+	ErrorLinkExist         = errors.New("该链接已经在社区中发布过")
 )
+
+// isDuplicateKeyError: 是否违反了唯一索引
+func isDuplicateKeyError(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
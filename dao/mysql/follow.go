@@ -0,0 +1,67 @@
+package mysql
+
+import (
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"gorm.io/gorm/clause"
+)
+
+// InsertFollow: 关注用户， 已经关注过时不做任何修改
+func InsertFollow(followerID, followeeID int64) error {
+	return DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Follow{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	}).Error
+}
+
+// DeleteFollow: 取消关注
+func DeleteFollow(followerID, followeeID int64) error {
+	return DB.Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+		Delete(&models.Follow{}).Error
+}
+
+// DeleteUserFollows: 删除用户所有的关注和粉丝关系， 返回关注的用户和粉丝的id
+func DeleteUserFollows(userID int64) (following, followers []int64, err error) {
+	if err = DB.Model(&models.Follow{}).Where("follower_id = ?", userID).Pluck("followee_id", &following).Error; err != nil {
+		return
+	}
+	if err = DB.Model(&models.Follow{}).Where("followee_id = ?", userID).Pluck("follower_id", &followers).Error; err != nil {
+		return
+	}
+	err = DB.Where("follower_id = ? OR followee_id = ?", userID, userID).Delete(&models.Follow{}).Error
+	return
+}
+
+// GetFollowers: 分页获取用户的粉丝， 最近关注的在前面
+func GetFollowers(userID, pageNum, pageSize int64) ([]*models.FollowUser, error) {
+	users := []*models.FollowUser{}
+	err := DB.Model(&models.Follow{}).
+		Select("users.user_id, users.username, users.avatar, follows.create_time AS followed_at").
+		Joins("JOIN users ON users.user_id = follows.follower_id").
+		Where("follows.followee_id = ?", userID).
+		Order("follows.create_time DESC").
+		Offset(int((pageNum - 1) * pageSize)).
+		Limit(int(pageSize)).
+		Scan(&users).Error
+	return users, err
+}
+
+// GetFollowing: 分页获取用户关注的人， 最近关注的在前面
+func GetFollowing(userID, pageNum, pageSize int64) ([]*models.FollowUser, error) {
+	users := []*models.FollowUser{}
+	err := DB.Model(&models.Follow{}).
+		Select("users.user_id, users.username, users.avatar, follows.create_time AS followed_at").
+		Joins("JOIN users ON users.user_id = follows.followee_id").
+		Where("follows.follower_id = ?", userID).
+		Order("follows.create_time DESC").
+		Offset(int((pageNum - 1) * pageSize)).
+		Limit(int(pageSize)).
+		Scan(&users).Error
+	return users, err
+}
+
+// GetAllPostAuthors: 获取所有帖子的作者， 用于回填redis中每个作者的帖子集合
+func GetAllPostAuthors() ([]*models.Post, error) {
+	posts := []*models.Post{}
+	err := DB.Model(&models.Post{}).Select("post_id", "author_id").Find(&posts).Error
+	return posts, err
+}
@@ -0,0 +1,38 @@
+package mysql
+
+import (
+	"errors"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/password"
+	"gorm.io/gorm"
+)
+
+// GetUserIdentity: 查询绑定的第三方账号， 没有绑定时返回nil
+func GetUserIdentity(provider, subject string) (*models.UserIdentity, error) {
+	identity := new(models.UserIdentity)
+	err := DB.Where("provider = ? AND subject = ?", provider, subject).First(identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return identity, err
+}
+
+// InsertUserIdentity: 给已有的用户绑定第三方账号
+func InsertUserIdentity(identity *models.UserIdentity) error {
+	return DB.Create(identity).Error
+}
+
+// InsertUserWithIdentity: 第一次使用第三方登录时， 同时创建用户和绑定关系
+func InsertUserWithIdentity(user *models.User, identity *models.UserIdentity) (err error) {
+	if user.Password, user.Salt, err = password.Hash(user.Password); err != nil {
+		return
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
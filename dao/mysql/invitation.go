@@ -0,0 +1,74 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/password"
+	"gorm.io/gorm"
+)
+
+// InsertInvitation: 保存新的邀请码
+func InsertInvitation(invitation *models.Invitation) error {
+	return DB.Create(invitation).Error
+}
+
+// GetInvitations: 分页获取所有的邀请码， 最近生成的在前面
+func GetInvitations(pageNum, pageSize int64) ([]*models.Invitation, error) {
+	invitations := []*models.Invitation{}
+	err := DB.Order("create_time DESC").
+		Offset(int((pageNum - 1) * pageSize)).
+		Limit(int(pageSize)).
+		Find(&invitations).Error
+	return invitations, err
+}
+
+// RevokeInvitation: 撤销邀请码， 邀请码不存在或已经撤销时返回false
+// 撤销之后不删除记录， 已经注册的用户还可以查到邀请人
+func RevokeInvitation(invitationID int64, now time.Time) (bool, error) {
+	result := DB.Model(&models.Invitation{}).
+		Where("invitation_id = ? AND revoked_at IS NULL", invitationID).
+		Update("revoked_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// InsertUserWithInvitation: 使用邀请码注册， 邀请码的使用次数和新用户在同一个事务中保存
+// 邀请码不存在、已撤销、已过期或者次数用完时返回ErrorInvalidInvitation
+func InsertUserWithInvitation(user *models.User, code string, now time.Time) (err error) {
+	if user.Password, user.Salt, err = password.Hash(user.Password); err != nil {
+		return
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		// 条件更新保证并发注册时不会超过最大次数
+		result := tx.Model(&models.Invitation{}).
+			Where("code = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?) AND uses < max_uses", code, now).
+			Update("uses", gorm.Expr("uses + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrorInvalidInvitation
+		}
+		invitation := new(models.Invitation)
+		if err := tx.Where("code = ?", code).First(invitation).Error; err != nil {
+			return err
+		}
+		user.InviterID = invitation.InviterID
+		return tx.Create(user).Error
+	})
+}
+
+// CheckInvitation: 检查邀请码现在是否可以使用， 不可以使用时返回ErrorInvalidInvitation
+func CheckInvitation(code string, now time.Time) error {
+	var count int64
+	err := DB.Model(&models.Invitation{}).
+		Where("code = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?) AND uses < max_uses", code, now).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrorInvalidInvitation
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package mysql
+
+import (
+	"strconv"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+)
+
+// UpdateUserKarma: 把redis中的karma同步到数据库
+func UpdateUserKarma(userID, postKarma, commentKarma int64) error {
+	return DB.Model(&models.User{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"post_karma":    postKarma,
+		"comment_karma": commentKarma,
+	}).Error
+}
+
+// GetPostAuthorIDs: 获取帖子id到作者id的映射， 已经删除的帖子不在结果中
+func GetPostAuthorIDs(pids []string) (map[string]int64, error) {
+	authors := make(map[string]int64, len(pids))
+	if len(pids) == 0 {
+		return authors, nil
+	}
+	posts := []*models.Post{}
+	if err := DB.Model(&models.Post{}).Select("post_id", "author_id").Where("post_id IN ?", pids).Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range posts {
+		authors[strconv.FormatInt(p.ID, 10)] = p.AuthorID
+	}
+	return authors, nil
+}
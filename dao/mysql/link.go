@@ -0,0 +1,20 @@
+package mysql
+
+import (
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"gorm.io/gorm"
+)
+
+// ReplaceOutboundLinks: 用内容中最新的链接替换以前提取的链接
+func ReplaceOutboundLinks(sourceType string, sourceID int64, links []*models.OutboundLink) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source_type = ? AND source_id = ?", sourceType, sourceID).
+			Delete(&models.OutboundLink{}).Error; err != nil {
+			return err
+		}
+		if len(links) == 0 {
+			return nil
+		}
+		return tx.Create(links).Error
+	})
+}
@@ -0,0 +1,55 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+)
+
+// listedPostStatus: 会出现在列表中的帖子状态
+var listedPostStatus = []int32{models.PostStatusPublished, models.PostStatusLocked}
+
+// UpdatePostStatus: 修改帖子的审核状态和移除原因， publishedTime不为空时同时记录发布时间
+// 被移除的帖子不再占用链接， 清空url_hash
+func UpdatePostStatus(postID int64, status int32, reason string, publishedTime *time.Time) error {
+	fields := map[string]interface{}{
+		"status":         status,
+		"removal_reason": reason,
+	}
+	if publishedTime != nil {
+		fields["published_time"] = *publishedTime
+	}
+	if status == models.PostStatusRemoved || status == models.PostStatusSpam {
+		fields["url_hash"] = nil
+	}
+	return DB.Model(&models.Post{}).Where("post_id = ?", postID).Updates(fields).Error
+}
+
+// RepublishPost: 恢复被移除的帖子， 同时重新占用链接， 链接已经被其他帖子使用时返回ErrorLinkExist
+func RepublishPost(postID int64, publishedTime *time.Time, urlHash *string) error {
+	fields := map[string]interface{}{
+		"status":         models.PostStatusPublished,
+		"removal_reason": "",
+		"url_hash":       urlHash,
+	}
+	if publishedTime != nil {
+		fields["published_time"] = *publishedTime
+	}
+	err := DB.Model(&models.Post{}).Where("post_id = ?", postID).Updates(fields).Error
+	if isDuplicateKeyError(err) {
+		return ErrorLinkExist
+	}
+	return err
+}
+
+// GetCommunityPostsByStatus: 分页获取社区中某个状态的帖子， 先发布的在前面
+func GetCommunityPostsByStatus(communityID int64, status int32, pageNum, pageSize int64) ([]*models.Post, error) {
+	posts := []*models.Post{}
+	err := DB.Model(&models.Post{}).
+		Where("community_id = ? AND status = ?", communityID, status).
+		Order("create_time ASC").
+		Offset(int((pageNum - 1) * pageSize)).
+		Limit(int(pageSize)).
+		Find(&posts).Error
+	return posts, err
+}
@@ -41,10 +41,38 @@ func Init(cfg *settings.MySQLConfig) (err error) {
 	SQLDB.SetMaxIdleConns(cfg.MaxIdleConns) // 设置最大的空闲连接的数量， 为了避免空闲连接占用资源
 
 	// TODO:这里写数据库迁移的操作，后面进行更新
-	DB.AutoMigrate(&models.User{}, &models.Community{}, &models.Post{}, &models.Comment{}) // 会默认使用复数形式
+	if err = migratePostURLHash(); err != nil {
+		zap.L().Error("migrate post url_hash failed.", zap.Error(err))
+		return
+	}
+	DB.AutoMigrate(&models.User{}, &models.Community{}, &models.Post{}, &models.Comment{}, &models.RecoveryCode{}, &models.CommunityMember{}, &models.AccessToken{}, &models.UserIdentity{}, &models.Follow{}, &models.UserBlock{}, &models.Invitation{}, &models.PostRevision{}, &models.PostImage{}, &models.Poll{}, &models.PollOption{}, &models.OutboundLink{}) // 会默认使用复数形式
+	if err = initDeletedUser(); err != nil {
+		zap.L().Error("init deleted user failed.", zap.Error(err))
+	}
 	return
 }
 
+// migratePostURLHash: 添加唯一索引之前， 把不占用链接的帖子的url_hash改为NULL
+// 以前不是链接的帖子保存的是空字符串， 被移除和已经删除的帖子也保存着链接的sha1
+// 以前同时发布的相同链接只保留最早的帖子占用链接
+func migratePostURLHash() error {
+	if !DB.Migrator().HasColumn(&models.Post{}, "url_hash") {
+		return nil
+	}
+	err := DB.Unscoped().Model(&models.Post{}).
+		Where("url_hash = '' OR status IN ? OR deleted_at IS NOT NULL",
+			[]int32{models.PostStatusRemoved, models.PostStatusSpam}).
+		Update("url_hash", nil).Error
+	if err != nil {
+		return err
+	}
+	return DB.Exec(`UPDATE posts p JOIN (
+		SELECT community_id, url_hash, MIN(post_id) AS keep_id FROM posts
+		WHERE url_hash IS NOT NULL GROUP BY community_id, url_hash HAVING COUNT(*) > 1
+	) d ON p.community_id = d.community_id AND p.url_hash = d.url_hash
+	SET p.url_hash = NULL WHERE p.post_id <> d.keep_id`).Error
+}
+
 func Close() {
 	_ = SQLDB.Close()
 }
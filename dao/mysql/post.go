@@ -1,7 +1,9 @@
 package mysql
 
 import (
+	"strconv"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -14,9 +16,18 @@ func CreatePost(p *models.Post) (err error) {
 	// 	values (?,?,?,?,?)
 	// `
 	// 	_, err = db.Exec(sqlStr, p.ID, p.Title, p.Content, p.AuthorID, p.CommunityID)
-	res := DB.Create(p)
-	err = res.Error
-	return
+	// 图片和投票和帖子在同一个事务中保存
+	// 链接帖子的url_hash有唯一索引， 同时发布相同的链接时只有一个能成功
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(p).Error; err != nil {
+			return err
+		}
+		return createPostPayload(tx, p)
+	})
+	if isDuplicateKeyError(err) {
+		return ErrorLinkExist
+	}
+	return err
 }
 
 func GetPostByID(pid int64) (post *models.Post, err error) {
@@ -45,9 +56,10 @@ func GetPostList(pageNum, pageSize int64) (posts []*models.Post, err error) {
 	// 	DESC
 	// 	limit ?, ?
 	// `
-	posts = make([]*models.Post, 0, pageSize)
+	posts = []*models.Post{}
 	// 	err = db.Select(&posts, sqlStr, (pageNum-1)*pageSize, pageSize)
 	err = DB.Model(&models.Post{}).
+		Where("status IN ?", listedPostStatus).
 		Order("create_time DESC").
 		Offset((int(pageNum) - 1) * int(pageSize)).
 		Limit(int(pageSize)).
@@ -77,3 +89,58 @@ func GetPostListByIDs(pids []string) (posts []*models.Post, err error) {
 		Find(&posts).Error
 	return
 }
+
+// GetPostByIDUnscoped: 获取帖子， 包括已经删除的帖子， 不存在时返回ErrorPostNotExist
+func GetPostByIDUnscoped(pid int64) (*models.Post, error) {
+	post := new(models.Post)
+	err := DB.Unscoped().Where("post_id = ?", pid).First(post).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrorPostNotExist
+	}
+	return post, err
+}
+
+// SoftDeletePost: 删除帖子， 只是记录删除时间和删除的用户， 编辑记录也会保留， 方便恢复
+// 删除之后链接可以重新发布， 所以清空url_hash
+func SoftDeletePost(postID, deletedBy int64, now time.Time) error {
+	return DB.Model(&models.Post{}).Where("post_id = ?", postID).Updates(map[string]interface{}{
+		"deleted_at": now,
+		"deleted_by": deletedBy,
+		"url_hash":   nil,
+	}).Error
+}
+
+// RestorePost: 恢复已经删除的帖子， 链接已经被其他帖子使用时返回ErrorLinkExist
+func RestorePost(postID int64, urlHash *string) error {
+	err := DB.Unscoped().Model(&models.Post{}).Where("post_id = ?", postID).Updates(map[string]interface{}{
+		"deleted_at": nil,
+		"deleted_by": 0,
+		"url_hash":   urlHash,
+	}).Error
+	if isDuplicateKeyError(err) {
+		return ErrorLinkExist
+	}
+	return err
+}
+
+// GetMissingPostIDs: 返回数据库中不存在的帖子id， 软删除的帖子也算存在
+func GetMissingPostIDs(pids []string) ([]string, error) {
+	if len(pids) == 0 {
+		return nil, nil
+	}
+	existing := []int64{}
+	if err := DB.Unscoped().Model(&models.Post{}).Where("post_id IN ?", pids).Pluck("post_id", &existing).Error; err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		found[strconv.FormatInt(id, 10)] = true
+	}
+	missing := make([]string, 0)
+	for _, id := range pids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,97 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"gorm.io/gorm"
+)
+
+// createPostPayload: 保存图片帖子和投票帖子的内容， 图片需要是作者上传的、还没有使用过的图片
+func createPostPayload(tx *gorm.DB, p *models.Post) error {
+	if len(p.Images) > 0 {
+		for i, img := range p.Images {
+			res := tx.Model(&models.PostImage{}).
+				Where("image_id = ? AND uploader_id = ? AND post_id = 0", img.ID, p.AuthorID).
+				Updates(map[string]interface{}{"post_id": p.ID, "position": i})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected != 1 {
+				return ErrorImageNotExist
+			}
+		}
+		p.Images = p.Images[:0]
+		if err := tx.Where("post_id = ?", p.ID).Order("position").Find(&p.Images).Error; err != nil {
+			return err
+		}
+	}
+	if p.Poll != nil {
+		p.Poll.PostID = p.ID
+		if err := tx.Create(p.Poll).Error; err != nil {
+			return err
+		}
+		for i, option := range p.Poll.Options {
+			option.PostID = p.ID
+			option.Position = i
+		}
+		if err := tx.Create(p.Poll.Options).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InsertPostImage: 保存上传的图片， 创建帖子时再关联
+func InsertPostImage(img *models.PostImage) error {
+	return DB.Create(img).Error
+}
+
+// GetUnattachedPostImages: 获取上传之后一直没有关联到帖子的图片
+func GetUnattachedPostImages(before time.Time, limit int) ([]*models.PostImage, error) {
+	images := make([]*models.PostImage, 0)
+	err := DB.Where("post_id = 0 AND create_time < ?", before).
+		Order("create_time").Limit(limit).Find(&images).Error
+	return images, err
+}
+
+// DeleteUnattachedPostImage: 删除没有关联到帖子的图片， 已经被帖子使用时返回false
+func DeleteUnattachedPostImage(imageID int64) (bool, error) {
+	res := DB.Where("image_id = ? AND post_id = 0", imageID).Delete(&models.PostImage{})
+	return res.RowsAffected == 1, res.Error
+}
+
+// GetPostImages: 获取多个帖子的图片， 按照帖子和顺序排列
+func GetPostImages(postIDs []int64) ([]*models.PostImage, error) {
+	images := make([]*models.PostImage, 0)
+	if len(postIDs) == 0 {
+		return images, nil
+	}
+	err := DB.Where("post_id IN ?", postIDs).Order("post_id, position").Find(&images).Error
+	return images, err
+}
+
+// GetPolls: 获取多个帖子的投票和选项
+func GetPolls(postIDs []int64) ([]*models.Poll, error) {
+	polls := make([]*models.Poll, 0, len(postIDs))
+	if len(postIDs) == 0 {
+		return polls, nil
+	}
+	if err := DB.Where("post_id IN ?", postIDs).Find(&polls).Error; err != nil {
+		return nil, err
+	}
+	options := make([]*models.PollOption, 0)
+	if err := DB.Where("post_id IN ?", postIDs).Order("post_id, position").Find(&options).Error; err != nil {
+		return nil, err
+	}
+	byPost := make(map[int64]*models.Poll, len(polls))
+	for _, poll := range polls {
+		byPost[poll.PostID] = poll
+	}
+	for _, option := range options {
+		if poll, ok := byPost[option.PostID]; ok {
+			poll.Options = append(poll.Options, option)
+		}
+	}
+	return polls, nil
+}
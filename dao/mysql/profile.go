@@ -0,0 +1,61 @@
+package mysql
+
+import (
+	"errors"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"gorm.io/gorm"
+)
+
+// GetUserByUsername: 根据用户名查询用户
+func GetUserByUsername(username string) (*models.User, error) {
+	user := new(models.User)
+	err := DB.Model(&models.User{}).Where("username = ?", username).First(user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrorUserNotExist
+	}
+	return user, err
+}
+
+// GetUserIDsByUsernames: 根据用户名批量获取用户id， 不存在的用户名会被忽略
+func GetUserIDsByUsernames(usernames []string) ([]int64, error) {
+	ids := []int64{}
+	if len(usernames) == 0 {
+		return ids, nil
+	}
+	err := DB.Model(&models.User{}).Where("username IN ?", usernames).Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// GetPostsByAuthorPage: 分页获取用户发布的帖子， 最新的在前面， listedOnly为true时只返回会出现在列表中的帖子
+func GetPostsByAuthorPage(userID int64, listedOnly bool, pageNum, pageSize int64) ([]*models.Post, error) {
+	posts := []*models.Post{}
+	db := DB.Model(&models.Post{}).Where("author_id = ?", userID)
+	if listedOnly {
+		db = db.Where("status IN ?", listedPostStatus)
+	} else {
+		// 草稿有单独的列表
+		db = db.Where("status <> ?", models.PostStatusDraft)
+	}
+	err := db.Order("create_time DESC").
+		Offset(int((pageNum - 1) * pageSize)).
+		Limit(int(pageSize)).
+		Find(&posts).Error
+	return posts, err
+}
+
+// GetCommentsByAuthorPage: 分页获取用户发布的评论， 最新的在前面
+func GetCommentsByAuthorPage(userID, pageNum, pageSize int64) ([]*models.Comment, error) {
+	comments := []*models.Comment{}
+	err := DB.Model(&models.Comment{}).Where("author_id = ?", userID).
+		Order("create_time DESC").
+		Offset(int((pageNum - 1) * pageSize)).
+		Limit(int(pageSize)).
+		Find(&comments).Error
+	return comments, err
+}
+
+// UpdatePrivacy: 修改隐私设置
+func UpdatePrivacy(userID int64, hideVotes bool) error {
+	return DB.Model(&models.User{}).Where("user_id = ?", userID).Update("hide_votes", hideVotes).Error
+}
@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/snowflake"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpdatePostWithRevision: 修改帖子的标题和内容， 同时保存新的版本
+// 第一次编辑时先把原始内容保存为版本1， 锁住帖子所在的行， 避免同时编辑时版本号重复
+func UpdatePostWithRevision(postID, editorID int64, title, content string, voteNum int64, now time.Time) (*models.Post, error) {
+	post := new(models.Post)
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("post_id = ?", postID).First(post).Error; err != nil {
+			return err
+		}
+		var version int
+		if err := tx.Model(&models.PostRevision{}).Where("post_id = ?", postID).
+			Select("COALESCE(MAX(version), 0)").Scan(&version).Error; err != nil {
+			return err
+		}
+		if version == 0 {
+			version = 1
+			original := &models.PostRevision{
+				ID:         snowflake.GenID(),
+				PostID:     postID,
+				Version:    version,
+				EditorID:   post.AuthorID,
+				Title:      post.Title,
+				Content:    post.Content,
+				CreateTime: post.CreateTime,
+			}
+			if err := tx.Create(original).Error; err != nil {
+				return err
+			}
+		}
+
+		post.Title = title
+		post.Content = content
+		post.EditedTime = &now
+		if err := tx.Model(post).Updates(map[string]interface{}{
+			"title":       title,
+			"content":     content,
+			"edited_time": now,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PostRevision{
+			ID:         snowflake.GenID(),
+			PostID:     postID,
+			Version:    version + 1,
+			EditorID:   editorID,
+			Title:      title,
+			Content:    content,
+			VoteNum:    voteNum,
+			CreateTime: now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// GetPostRevisions: 获取帖子所有的版本， 按照版本号排列
+func GetPostRevisions(postID int64) ([]*models.PostRevision, error) {
+	revisions := []*models.PostRevision{}
+	err := DB.Where("post_id = ?", postID).Order("version ASC").Find(&revisions).Error
+	return revisions, err
+}
+
+// GetPostRevision: 获取帖子的某个版本， 不存在时返回nil
+func GetPostRevision(postID int64, version int) (*models.PostRevision, error) {
+	revision := new(models.PostRevision)
+	err := DB.Where("post_id = ? AND version = ?", postID, version).First(revision).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return revision, err
+}
@@ -0,0 +1,55 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"gorm.io/gorm"
+)
+
+// SetTOTPSecret: 保存待确认的两步验证密钥， 确认之前两步验证不生效
+func SetTOTPSecret(userID int64, secret string) error {
+	return DB.Model(&models.User{}).Where("user_id = ?", userID).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_enabled": false}).Error
+}
+
+// EnableTOTP: 开启两步验证， 同时替换掉之前所有的恢复码
+func EnableTOTP(userID int64, codeHashes []string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("user_id = ?", userID).
+			Update("totp_enabled", true).Error; err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+// DisableTOTP: 关闭两步验证， 删除密钥和所有的恢复码
+func DisableTOTP(userID int64) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("user_id = ?", userID).
+			Updates(map[string]interface{}{"totp_secret": "", "totp_enabled": false}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// UseRecoveryCode: 使用一个恢复码， 恢复码不存在或者已经使用过时返回false
+func UseRecoveryCode(userID int64, codeHash string) (bool, error) {
+	res := DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID int64, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]*models.RecoveryCode, 0, len(codeHashes))
+	for _, h := range codeHashes {
+		codes = append(codes, &models.RecoveryCode{UserID: userID, CodeHash: h})
+	}
+	return tx.Create(&codes).Error
+}
@@ -1,16 +1,13 @@
 package mysql
 
 import (
-	"crypto/md5"
-	"encoding/hex"
-
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 
 	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/password"
 )
 
-const secret = "xiaorui.com"
-
 // CheckUserExist: 检查制定用户名是否存在
 func CheckUserExist(username string) (err error) {
 	var count int64
@@ -28,7 +25,9 @@ func CheckUserExist(username string) (err error) {
 // InsertUser: 向数据库中添加一条新的用户数据
 func InsertUser(user *models.User) (err error) {
 	//在插入数据前， 需要对密码进行加密处理
-	user.Password = encryptPassword(user.Password)
+	if user.Password, user.Salt, err = password.Hash(user.Password); err != nil {
+		return
+	}
 	// 将数据实例插入数据表中
 	// sqlStr := `insert into user(user_id, username, password) values(?,?,?)`
 	// _, err = db.Exec(sqlStr, user.UserID, user.Username, user.Password)
@@ -37,11 +36,38 @@ func InsertUser(user *models.User) (err error) {
 	return
 }
 
-// 使用md5加密密码现在被认为是不安全的了， 因为可以通过如暴力破解的方式来破解
-func encryptPassword(oPasssword string) string {
-	h := md5.New()                                       // 创建hash.Hash对象
-	h.Write([]byte(secret))                              //  写入颜值
-	return hex.EncodeToString(h.Sum([]byte(oPasssword))) // 对于数据进行加密
+// checkPassword: 验证用户密码， 如果是使用旧算法(md5)加密的， 验证成功后使用新算法重新加密并保存
+func checkPassword(user *models.User, oPassword string) error {
+	ok, needRehash, err := password.Verify(oPassword, user.Salt, user.Password)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrorPasswordInvalid
+	}
+	if needRehash {
+		// 迁移失败不影响本次登录， 下次登录时会再次尝试
+		if err := updatePassword(user, oPassword); err != nil {
+			zap.L().Error("rehash password failed", zap.Int64("user_id", user.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// updatePassword: 使用当前算法加密新密码， 并且写回数据库
+func updatePassword(user *models.User, newPassword string) error {
+	hashed, salt, err := password.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	err = DB.Model(&models.User{}).
+		Where("user_id = ?", user.ID).
+		Updates(map[string]interface{}{"password": hashed, "salt": salt}).Error
+	if err != nil {
+		return err
+	}
+	user.Password, user.Salt = hashed, salt
+	return nil
 }
 
 func Login(user *models.User) (err error) {
@@ -58,10 +84,7 @@ func Login(user *models.User) (err error) {
 	if err != nil {
 		return
 	}
-	if encryptPassword(oPassword) != user.Password {
-		return ErrorPasswordInvalid
-	}
-	return
+	return checkPassword(user, oPassword)
 }
 
 // LoginUsingPhoneWithCode： 使用手机+验证码登陆
@@ -87,10 +110,20 @@ func LoginUsingEmail(user *models.User) error {
 	if err != nil {
 		return err
 	}
-	if encryptPassword(oPassword) != user.Password {
-		return ErrorPasswordInvalid
+	return checkPassword(user, oPassword)
+}
+
+// LoginUsingIdentifier: 根据column查询用户并校验密码， column只能是username、email或phone
+func LoginUsingIdentifier(user *models.User, column, identifier string) error {
+	oPassword := user.Password
+	err := DB.Model(&models.User{}).Where(column+" = ?", identifier).First(user).Error
+	if err == gorm.ErrRecordNotFound {
+		return ErrorUserNotExist
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	return checkPassword(user, oPassword)
 }
 
 func GetUserByID(id int64) (user *models.User, err error) {
@@ -133,28 +166,36 @@ func SaveUser(user *models.User) (*models.User, error) {
 }
 
 // CheckPasswordValid：验证用户密码是否有效
-func UpdatePassword(password, NewPassword string, userID int64) error {
+func UpdatePassword(oldPassword, NewPassword string, userID int64) error {
 	var user models.User
 	err := DB.Model(&models.User{}).Where("user_id = ?", userID).First(&user).Error
 	if err == gorm.ErrRecordNotFound {
 		return ErrorUserNotExist
 	}
-	if encryptPassword(password) != user.Password {
+	if err != nil {
+		return err
+	}
+	ok, _, err := password.Verify(oldPassword, user.Salt, user.Password)
+	if err != nil {
+		return err
+	}
+	if !ok {
 		return ErrorPasswordInvalid
 	}
-	user.Password = encryptPassword(NewPassword)
-	_, err = SaveUser(&user)
-
-	return err
+	return updatePassword(&user, NewPassword)
 }
 
-// DeletePost: 删除Post
-func DeletePost(post *models.Post, userID int64) error {
-	if post.AuthorID != userID {
-		return ErrorNotPermission
+// VerifyPassword: 校验用户的密码， 用于注销账号等敏感操作的二次确认
+func VerifyPassword(userID int64, password string) error {
+	user := new(models.User)
+	err := DB.Model(&models.User{}).Where("user_id = ?", userID).First(user).Error
+	if err == gorm.ErrRecordNotFound {
+		return ErrorUserNotExist
 	}
-
-	return DB.Delete(post).Error
+	if err != nil {
+		return err
+	}
+	return checkPassword(user, password)
 }
 
 func GetEmailList() ([]string, error) {
@@ -167,3 +208,28 @@ func GetEmailList() ([]string, error) {
 
 	return emailList, err
 }
+
+// GetUserByPhone: 根据手机号码查询用户
+func GetUserByPhone(phone string) (*models.User, error) {
+	user := new(models.User)
+	err := DB.Model(&models.User{}).Where("phone = ?", phone).First(user).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrorPhoneNotExist
+	}
+	return user, err
+}
+
+// GetUserByEmail: 根据邮箱查询用户
+func GetUserByEmail(email string) (*models.User, error) {
+	user := new(models.User)
+	err := DB.Model(&models.User{}).Where("email = ?", email).First(user).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrorEmailNotExist
+	}
+	return user, err
+}
+
+// ResetPassword: 不校验旧密码， 直接写入新密码， 调用方需要先完成身份验证
+func ResetPassword(userID int64, newPassword string) error {
+	return updatePassword(&models.User{ID: userID}, newPassword)
+}
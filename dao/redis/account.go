@@ -0,0 +1,23 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+)
+
+// AcquireAccountDeletionLock: 开始注销账号之前加锁， 返回false说明其他实例正在处理这个账号
+func AcquireAccountDeletionLock(userID int64, expire time.Duration) (bool, error) {
+	key := getRedisKey(KeyAccountDeletion) + strconv.FormatInt(userID, 10)
+	return RDB.Client.SetNX(RDB.Context, key, 1, expire).Result()
+}
+
+// ReleaseAccountDeletionLock: 注销完成之后解锁
+func ReleaseAccountDeletionLock(userID int64) error {
+	key := getRedisKey(KeyAccountDeletion) + strconv.FormatInt(userID, 10)
+	return RDB.Client.Del(RDB.Context, key).Err()
+}
+
+// DeleteUserPostSet: 删除用户发布的帖子的集合， 注销之后帖子已经转给占位用户
+func DeleteUserPostSet(userID int64) error {
+	return RDB.Client.Del(RDB.Context, getUserPostSetKey(userID)).Err()
+}
@@ -0,0 +1,122 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+)
+
+const (
+	userBlocksExpire = time.Hour
+	// 空的hash在redis中不存在， 加一个占位的field表示已经缓存过
+	userBlocksLoadedField = "loaded"
+)
+
+// 只有版本和读取数据库之前一致时才写入缓存， 否则说明这期间列表被修改过， 查询到的数据可能已经过期
+var setUserBlocksScript = redis.NewScript(`
+if (redis.call('GET', KEYS[2]) or '') ~= ARGV[1] then
+	return 0
+end
+redis.call('DEL', KEYS[1])
+redis.call('HSET', KEYS[1], unpack(ARGV, 3))
+redis.call('EXPIRE', KEYS[1], ARGV[2])
+return 1
+`)
+
+// 修改列表时增加版本， 缓存存在时直接修改其中的一个field， 不存在时等下次读取时再加载
+// ARGV[2]为空时表示取消屏蔽或静音
+var updateUserBlocksScript = redis.NewScript(`
+redis.call('INCR', KEYS[2])
+redis.call('EXPIRE', KEYS[2], ARGV[3])
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+if ARGV[2] == '' then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+else
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+end
+return 1
+`)
+
+func getUserBlocksKey(userID int64) string {
+	return getRedisKey(KeyUserBlocksPF) + strconv.FormatInt(userID, 10)
+}
+
+func getUserBlocksVersionKey(userID int64) string {
+	return getRedisKey(KeyUserBlocksVerPF) + strconv.FormatInt(userID, 10)
+}
+
+// GetUserBlocks: 获取缓存的屏蔽和静音列表， 没有缓存时ok为false
+func GetUserBlocks(userID int64) (blocks map[int64]string, ok bool, err error) {
+	fields, err := RDB.Client.HGetAll(RDB.Context, getUserBlocksKey(userID)).Result()
+	if err != nil || len(fields) == 0 {
+		return nil, false, err
+	}
+	blocks = make(map[int64]string, len(fields)-1)
+	for field, blockType := range fields {
+		if field == userBlocksLoadedField {
+			continue
+		}
+		targetID, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		blocks[targetID] = blockType
+	}
+	return blocks, true, nil
+}
+
+// GetUserBlocksVersion: 获取屏蔽和静音列表的版本， 需要在查询数据库之前调用， 没有修改过时为空
+func GetUserBlocksVersion(userID int64) (string, error) {
+	version, err := RDB.Client.Get(RDB.Context, getUserBlocksVersionKey(userID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return version, err
+}
+
+// SetUserBlocks: 缓存从数据库中查询到的屏蔽和静音列表， 版本已经变化时不写入
+func SetUserBlocks(userID int64, version string, blocks []*models.UserBlock) error {
+	keys := []string{getUserBlocksKey(userID), getUserBlocksVersionKey(userID)}
+	args := make([]interface{}, 0, 2*len(blocks)+4)
+	args = append(args, version, int64(userBlocksExpire/time.Second), userBlocksLoadedField, 1)
+	for _, b := range blocks {
+		args = append(args, b.TargetID, b.Type)
+	}
+	return setUserBlocksScript.Run(RDB.Context, RDB.Client, keys, args...).Err()
+}
+
+// SetUserBlockCache: 屏蔽或静音之后修改缓存
+func SetUserBlockCache(userID, targetID int64, blockType string) error {
+	return updateUserBlocks(userID, targetID, blockType)
+}
+
+// DelUserBlockCache: 取消屏蔽或静音之后修改缓存
+func DelUserBlockCache(userID, targetID int64) error {
+	return updateUserBlocks(userID, targetID, "")
+}
+
+func updateUserBlocks(userID, targetID int64, blockType string) error {
+	keys := []string{getUserBlocksKey(userID), getUserBlocksVersionKey(userID)}
+	return updateUserBlocksScript.Run(RDB.Context, RDB.Client, keys,
+		targetID, blockType, int64(userBlocksExpire/time.Second)).Err()
+}
+
+// DeleteUserBlocksCache: 删除多个用户的缓存， 同时增加版本， 下次读取时重新加载
+func DeleteUserBlocksCache(userIDs ...int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	pipeline := RDB.Client.TxPipeline()
+	for _, id := range userIDs {
+		versionKey := getUserBlocksVersionKey(id)
+		pipeline.Incr(RDB.Context, versionKey)
+		pipeline.Expire(RDB.Context, versionKey, userBlocksExpire)
+		pipeline.Del(RDB.Context, getUserBlocksKey(id))
+	}
+	_, err := pipeline.Exec(RDB.Context)
+	return err
+}
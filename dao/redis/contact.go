@@ -0,0 +1,34 @@
+package redis
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+)
+
+// SetContactChange: 保存联系方式的修改记录， 旧的联系方式可以在有效期内撤销修改
+func SetContactChange(token string, change *models.ContactChange, expire time.Duration) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	return RDB.Client.Set(RDB.Context, getRedisKey(KeyContactRevert)+token, data, expire).Err()
+}
+
+// TakeContactChange: 取出并删除修改记录， 每个撤销链接只能使用一次， 不存在时返回nil
+func TakeContactChange(token string) (*models.ContactChange, error) {
+	data, err := RDB.Client.GetDel(RDB.Context, getRedisKey(KeyContactRevert)+token).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	change := new(models.ContactChange)
+	if err = json.Unmarshal(data, change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
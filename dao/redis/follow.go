@@ -0,0 +1,136 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+)
+
+func getUserPostSetKey(userID int64) string {
+	return getRedisKey(KeyUserPostSetPF) + strconv.FormatInt(userID, 10)
+}
+
+func getFollowingKey(userID int64) string {
+	return getRedisKey(KeyUserFollowingPF) + strconv.FormatInt(userID, 10)
+}
+
+func getFollowerKey(userID int64) string {
+	return getRedisKey(KeyUserFollowerPF) + strconv.FormatInt(userID, 10)
+}
+
+// getFeedFollowingKeys: 关注的人的帖子列表缓存， 按时间和按分数排序各一个
+func getFeedFollowingKeys(userID int64) []string {
+	uid := strconv.FormatInt(userID, 10)
+	return []string{
+		getRedisKey(KeyFeedFollowingPF) + models.OrderTime + ":" + uid,
+		getRedisKey(KeyFeedFollowingPF) + models.OrderScore + ":" + uid,
+	}
+}
+
+// Follow: 记录关注关系， 同时清除关注者的帖子列表缓存
+func Follow(followerID, followeeID int64) error {
+	pipeline := RDB.Client.TxPipeline()
+	pipeline.SAdd(RDB.Context, getFollowingKey(followerID), followeeID)
+	pipeline.SAdd(RDB.Context, getFollowerKey(followeeID), followerID)
+	pipeline.Del(RDB.Context, getFeedFollowingKeys(followerID)...)
+	_, err := pipeline.Exec(RDB.Context)
+	return err
+}
+
+// Unfollow: 删除关注关系， 同时清除关注者的帖子列表缓存
+func Unfollow(followerID, followeeID int64) error {
+	pipeline := RDB.Client.TxPipeline()
+	pipeline.SRem(RDB.Context, getFollowingKey(followerID), followeeID)
+	pipeline.SRem(RDB.Context, getFollowerKey(followeeID), followerID)
+	pipeline.Del(RDB.Context, getFeedFollowingKeys(followerID)...)
+	_, err := pipeline.Exec(RDB.Context)
+	return err
+}
+
+// DeleteUserFollows: 删除用户所有的关注和粉丝关系
+func DeleteUserFollows(userID int64, following, followers []int64) error {
+	pipeline := RDB.Client.TxPipeline()
+	for _, id := range following {
+		pipeline.SRem(RDB.Context, getFollowerKey(id), userID)
+	}
+	for _, id := range followers {
+		pipeline.SRem(RDB.Context, getFollowingKey(id), userID)
+		pipeline.Del(RDB.Context, getFeedFollowingKeys(id)...)
+	}
+	pipeline.Del(RDB.Context, getFollowingKey(userID), getFollowerKey(userID))
+	pipeline.Del(RDB.Context, getFeedFollowingKeys(userID)...)
+	_, err := pipeline.Exec(RDB.Context)
+	return err
+}
+
+// IsFollowing: follower是否关注了followee
+func IsFollowing(followerID, followeeID int64) (bool, error) {
+	return RDB.Client.SIsMember(RDB.Context, getFollowingKey(followerID), followeeID).Result()
+}
+
+// GetFollowCounts: 获取用户的粉丝数量和关注的人的数量
+func GetFollowCounts(userID int64) (followers, following int64, err error) {
+	pipeline := RDB.Client.Pipeline()
+	followersCmd := pipeline.SCard(RDB.Context, getFollowerKey(userID))
+	followingCmd := pipeline.SCard(RDB.Context, getFollowingKey(userID))
+	if _, err = pipeline.Exec(RDB.Context); err != nil {
+		return
+	}
+	return followersCmd.Val(), followingCmd.Val(), nil
+}
+
+// GetFollowingPostIDListByOrder: 获取关注的人发布的帖子id
+// 先把关注的人的帖子集合合并， 再和按时间或分数排序的zset求交集， 和社区的帖子列表是一样的做法
+func GetFollowingPostIDListByOrder(userID int64, p *models.ParamPostList) ([]string, error) {
+	orderKey := getRedisKey(KeyPostTimeZSet)
+	key := getFeedFollowingKeys(userID)[0]
+	if p.Order == models.OrderScore {
+		orderKey = getRedisKey(KeyPostScoreZSet)
+		key = getFeedFollowingKeys(userID)[1]
+	}
+	// 缓存不存在时重新计算
+	if RDB.Client.Exists(RDB.Context, key).Val() < 1 {
+		ids, err := RDB.Client.SMembers(RDB.Context, getFollowingKey(userID)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, nil
+		}
+		postSetKeys := make([]string, 0, len(ids))
+		for _, id := range ids {
+			postSetKeys = append(postSetKeys, getRedisKey(KeyUserPostSetPF)+id)
+		}
+		authorsKey := key + ":posts"
+		pipeline := RDB.Client.TxPipeline()
+		pipeline.SUnionStore(RDB.Context, authorsKey, postSetKeys...)
+		pipeline.ZInterStore(RDB.Context, key, &redis.ZStore{
+			Aggregate: "MAX",
+			Keys:      []string{authorsKey, orderKey},
+		})
+		pipeline.Del(RDB.Context, authorsKey)
+		pipeline.Expire(RDB.Context, key, time.Second*60)
+		if _, err = pipeline.Exec(RDB.Context); err != nil {
+			return nil, err
+		}
+	}
+	start := (p.Page - 1) * p.Size
+	return RDB.Client.ZRevRange(RDB.Context, key, start, start+p.Size-1).Result()
+}
+
+// BackfillUserPosts: 把已有的帖子加入作者的帖子集合
+func BackfillUserPosts(posts []*models.Post) error {
+	pipeline := RDB.Client.Pipeline()
+	for i, post := range posts {
+		pipeline.SAdd(RDB.Context, getUserPostSetKey(post.AuthorID), post.ID)
+		if (i+1)%500 == 0 {
+			if _, err := pipeline.Exec(RDB.Context); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := pipeline.Exec(RDB.Context)
+	return err
+}
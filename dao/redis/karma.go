@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 用户karma的hash中的field
+const (
+	karmaFieldPost    = "post"
+	karmaFieldComment = "comment" // 评论还不能投票， 目前一直是0
+)
+
+func getUserKarmaKey(userID int64) string {
+	return getRedisKey(KeyUserKarmaPF) + strconv.FormatInt(userID, 10)
+}
+
+// incrPostKarma: 在投票的事务中修改作者的帖子karma， 同时标记需要同步到数据库
+func incrPostKarma(pipeline redis.Pipeliner, authorID, delta int64) {
+	pipeline.HIncrBy(RDB.Context, getUserKarmaKey(authorID), karmaFieldPost, delta)
+	pipeline.SAdd(RDB.Context, getRedisKey(KeyKarmaDirty), authorID)
+}
+
+// GetUserKarma: 获取用户的帖子karma和评论karma
+func GetUserKarma(userID int64) (postKarma, commentKarma int64, err error) {
+	vals, err := RDB.Client.HMGet(RDB.Context, getUserKarmaKey(userID), karmaFieldPost, karmaFieldComment).Result()
+	if err != nil {
+		return
+	}
+	postKarma = parseKarma(vals[0])
+	commentKarma = parseKarma(vals[1])
+	return
+}
+
+func parseKarma(val interface{}) int64 {
+	s, ok := val.(string)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+// SetPostKarma: 直接设置用户的帖子karma， 用于从投票记录回填
+func SetPostKarma(userID, karma int64) error {
+	pipeline := RDB.Client.TxPipeline()
+	pipeline.HSet(RDB.Context, getUserKarmaKey(userID), karmaFieldPost, karma)
+	pipeline.SAdd(RDB.Context, getRedisKey(KeyKarmaDirty), userID)
+	_, err := pipeline.Exec(RDB.Context)
+	return err
+}
+
+// PopKarmaDirty: 取出最多count个需要同步karma的用户id
+func PopKarmaDirty(count int64) ([]int64, error) {
+	members, err := RDB.Client.SPopN(RDB.Context, getRedisKey(KeyKarmaDirty), count).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// MarkKarmaDirty: 同步失败时重新标记， 下次再同步
+func MarkKarmaDirty(userIDs ...int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	members := make([]interface{}, 0, len(userIDs))
+	for _, id := range userIDs {
+		members = append(members, id)
+	}
+	return RDB.Client.SAdd(RDB.Context, getRedisKey(KeyKarmaDirty), members...).Err()
+}
@@ -6,11 +6,37 @@ const (
 	KeyPrefix          = "bluebell:"
 	KeyPostTimeZSet    = "post:time:"
 	KeyPostScoreZSet   = "post:score:"
-	KeyPostVotedZSetPF = "post:voted:"            // 这里更改了好像会有点麻烦
-	KeyCommunitySetPF  = "community:"             // 保存每个community下面的post的集合
-	KeyCaptcha         = "signup:captcha:"        // 保存图形验证码
-	KeyVerifyCode      = "signup:verifycode:"     // 保存短信或邮件验证码
-	KeyProfileStatus   = "signup:profile_status:" // 是否更新了个人信息
+	KeyPostVotedZSetPF = "post:voted:"             // 这里更改了好像会有点麻烦
+	KeyCommunitySetPF  = "community:"              // 保存每个community下面的post的集合
+	KeyUserUpvotedPF   = "user:upvoted:"           // 用户点赞过的帖子， score是点赞的时间
+	KeyUserPostSetPF   = "user:posts:"             // 每个用户发布的帖子id的集合
+	KeyPostRemovedPF   = "post:removed_upvotes:"   // 已经删除的帖子的点赞时间， 恢复帖子时放回用户的点赞列表
+	KeyPollVotesPF     = "poll:votes:"             // 投票帖子每个用户选择的选项， field是用户id
+	KeyPollCountsPF    = "poll:counts:"            // 投票帖子每个选项的票数， field是选项的编号
+	KeyMarkdownHTMLPF  = "markdown:html:"          // 帖子和评论渲染之后的HTML， key是渲染规则的版本和源码的sha1
+	KeyUserFollowingPF = "user:following:"         // 用户关注的人的集合
+	KeyUserFollowerPF  = "user:followers:"         // 用户的粉丝的集合
+	KeyFeedFollowingPF = "feed:following:"         // 关注的人发布的帖子， 按照时间或者分数排序的缓存
+	KeyUserBlocksPF    = "user:blocks:"            // 用户屏蔽和静音的人， field是用户id， value是类型
+	KeyUserBlocksVerPF = "user:blocks_version:"    // 屏蔽和静音列表的版本， 每次修改加1， 防止写入过期的缓存
+	KeyUserKarmaPF     = "user:karma:"             // 用户的帖子karma和评论karma
+	KeyKarmaDirty      = "karma:dirty"             // karma有变化、还没有同步到数据库的用户id
+	KeyMigration       = "migration:"              // 已经执行过的数据迁移
+	KeyCaptcha         = "signup:captcha:"         // 保存图形验证码
+	KeyVerifyCode      = "signup:verifycode:"      // 保存短信或邮件验证码
+	KeyVerifyCodeFails = "signup:code_fails:"      // 验证码输错的次数， 达到上限之后验证码失效
+	KeyProfileStatus   = "signup:profile_status:"  // 是否更新了个人信息
+	KeyTokenValidAfter = "auth:token_valid_after:" // 用户token的水位线， 早于这个时间签发的token无效
+	KeyTokenDenylist   = "auth:token_denylist:"    // 已经注销的token id
+	KeyAuthSession     = "auth:session:"           // 登录会话， 记录当前有效的refresh token id和设备信息
+	KeyUserSessions    = "auth:user_sessions:"     // 用户的所有登录会话id， score是会话的过期时间
+	KeyTOTPUsedStep    = "auth:totp_used:"         // 已经使用过的TOTP时间步， 防止验证码重放
+	KeyLoginChallenge  = "auth:login_challenge:"   // 开启两步验证的用户登录时的临时凭证
+	KeyContactRevert   = "auth:contact_revert:"    // 修改邮箱或手机号码之后， 发给旧联系方式的撤销凭证
+	KeyOIDCState       = "auth:oidc_state:"        // 第三方登录的state， 保存nonce和PKCE的code_verifier
+	KeyRateLimitWindow = "ratelimit:window:"       // 滑动窗口计数， 比如登录失败次数、发送验证码次数
+	KeyRateLimitLock   = "ratelimit:lock:"         // 暂时锁定， 比如登录失败次数过多的账号
+	KeyAccountDeletion = "lock:account_deletion:"  // 正在注销的账号， 防止多个实例同时处理同一个账号
 )
 
 // 给key加上前缀
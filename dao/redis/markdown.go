@@ -0,0 +1,37 @@
+package redis
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// GetRenderedHTML: 批量获取缓存的HTML， 没有缓存的位置为空字符串
+func GetRenderedHTML(keys []string) ([]string, error) {
+	fullKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		fullKeys = append(fullKeys, getRedisKey(KeyMarkdownHTMLPF+key))
+	}
+	values, err := RDB.Client.MGet(RDB.Context, fullKeys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	htmls := make([]string, len(values))
+	for i, v := range values {
+		if s, ok := v.(string); ok {
+			htmls[i] = s
+		}
+	}
+	return htmls, nil
+}
+
+// SetRenderedHTML: 缓存渲染之后的HTML， key到HTML的映射
+func SetRenderedHTML(htmls map[string]string, expire time.Duration) error {
+	_, err := RDB.Client.Pipelined(RDB.Context, func(pipe redis.Pipeliner) error {
+		for key, html := range htmls {
+			pipe.Set(RDB.Context, getRedisKey(KeyMarkdownHTMLPF+key), html, expire)
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,22 @@
+package redis
+
+import "time"
+
+// 数据迁移的名称， 每个迁移只需要成功执行一次
+const (
+	migrationUserUpvoted = "user_upvoted"
+	MigrationUserPosts   = "user_posts"
+	MigrationUserKarma   = "user_karma"
+	MigrationPurgePosts  = "purge_posts"
+)
+
+// IsMigrated: 数据迁移是否已经执行过
+func IsMigrated(name string) (bool, error) {
+	n, err := RDB.Client.Exists(RDB.Context, getRedisKey(KeyMigration)+name).Result()
+	return n > 0, err
+}
+
+// SetMigrated: 记录数据迁移已经执行完成
+func SetMigrated(name string) error {
+	return RDB.Client.Set(RDB.Context, getRedisKey(KeyMigration)+name, time.Now().Unix(), 0).Err()
+}
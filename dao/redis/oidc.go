@@ -0,0 +1,34 @@
+package redis
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+)
+
+// SetOIDCState: 跳转到第三方登录之前保存state对应的nonce和code_verifier
+func SetOIDCState(state string, s *models.OIDCLoginState, expire time.Duration) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return RDB.Client.Set(RDB.Context, getRedisKey(KeyOIDCState)+state, data, expire).Err()
+}
+
+// TakeOIDCState: 取出并删除state， 每个state只能使用一次， 不存在时返回nil
+func TakeOIDCState(state string) (*models.OIDCLoginState, error) {
+	data, err := RDB.Client.GetDel(RDB.Context, getRedisKey(KeyOIDCState)+state).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := new(models.OIDCLoginState)
+	if err = json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
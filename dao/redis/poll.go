@@ -0,0 +1,88 @@
+package redis
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 每个用户只能投一次票， 记录用户的选择和增加选项的票数在同一个脚本中执行
+// 返回1表示投票成功， 0表示已经投过票
+var votePollScript = redis.NewScript(`
+if redis.call('HSETNX', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+for i = 3, #ARGV do
+	redis.call('HINCRBY', KEYS[2], ARGV[i], 1)
+end
+return 1
+`)
+
+func getPollKeys(postID int64) (votesKey, countsKey string) {
+	pid := strconv.FormatInt(postID, 10)
+	return getRedisKey(KeyPollVotesPF) + pid, getRedisKey(KeyPollCountsPF) + pid
+}
+
+// VotePoll: 记录用户的投票， 已经投过票时返回false
+func VotePoll(postID, userID int64, options []int) (bool, error) {
+	votesKey, countsKey := getPollKeys(postID)
+	choices := make([]string, 0, len(options))
+	args := []interface{}{userID, ""}
+	for _, option := range options {
+		choices = append(choices, strconv.Itoa(option))
+		args = append(args, option)
+	}
+	args[1] = strings.Join(choices, ",")
+	n, err := votePollScript.Run(RDB.Context, RDB.Client, []string{votesKey, countsKey}, args...).Int()
+	return n == 1, err
+}
+
+// GetPollResults: 获取多个投票帖子每个选项的票数和投票的人数
+func GetPollResults(postIDs []int64) (counts []map[int]int64, voters []int64, err error) {
+	pipeline := RDB.Client.Pipeline()
+	countCmds := make([]*redis.MapStringStringCmd, 0, len(postIDs))
+	voterCmds := make([]*redis.IntCmd, 0, len(postIDs))
+	for _, postID := range postIDs {
+		votesKey, countsKey := getPollKeys(postID)
+		countCmds = append(countCmds, pipeline.HGetAll(RDB.Context, countsKey))
+		voterCmds = append(voterCmds, pipeline.HLen(RDB.Context, votesKey))
+	}
+	if _, err = pipeline.Exec(RDB.Context); err != nil {
+		return nil, nil, err
+	}
+	counts = make([]map[int]int64, 0, len(postIDs))
+	voters = make([]int64, 0, len(postIDs))
+	for i := range postIDs {
+		count := make(map[int]int64)
+		for option, n := range countCmds[i].Val() {
+			o, err1 := strconv.Atoi(option)
+			v, err2 := strconv.ParseInt(n, 10, 64)
+			if err1 == nil && err2 == nil {
+				count[o] = v
+			}
+		}
+		counts = append(counts, count)
+		voters = append(voters, voterCmds[i].Val())
+	}
+	return counts, voters, nil
+}
+
+// GetPollVote: 获取用户选择的选项， 没有投票时返回nil
+func GetPollVote(postID, userID int64) ([]int, error) {
+	votesKey, _ := getPollKeys(postID)
+	val, err := RDB.Client.HGet(RDB.Context, votesKey, strconv.FormatInt(userID, 10)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	options := make([]int, 0)
+	for _, s := range strings.Split(val, ",") {
+		if o, err := strconv.Atoi(s); err == nil {
+			options = append(options, o)
+		}
+	}
+	return options, nil
+}
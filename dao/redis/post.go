@@ -19,6 +19,10 @@ func CreatePost(pid, communityID, userID int64) error {
 		Score:  1,
 		Member: userID,
 	})
+	pipeline.ZAdd(RDB.Context, getUserUpvotedKey(userID), redis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: pid,
+	})
 
 	//在redis中加入一个创建的post的记录
 	pipeline.ZAdd(RDB.Context, getRedisKey(KeyPostTimeZSet), redis.Z{
@@ -31,6 +35,9 @@ func CreatePost(pid, communityID, userID int64) error {
 		Member: pid, //需要确认这里是否需要是string类型的？先暂时使用int
 	})
 
+	// 作者的帖子集合， 用于生成关注的人的帖子列表
+	pipeline.SAdd(RDB.Context, getUserPostSetKey(userID), pid)
+
 	communityKey := getRedisKey(KeyCommunitySetPF + strconv.Itoa(int(communityID)))
 	// 我们也可以在创建comment的地方采用相同的策略，创建一个postid的key， 然后使用Redis来存放有哪些commentid， 这样查询的时候就无需逐条查询mysql了
 	pipeline.SAdd(RDB.Context, communityKey, pid) // 加入member， 但是不需要score， 就是给community下面添加数据， 这些数据是使用Set来保存的
@@ -39,6 +46,40 @@ func CreatePost(pid, communityID, userID int64) error {
 	return err
 }
 
+// GetPostsMissingFromList: 返回不在按时间排序的帖子列表中的帖子id
+func GetPostsMissingFromList(pids []int64) ([]int64, error) {
+	pipeline := RDB.Client.Pipeline()
+	cmds := make([]*redis.FloatCmd, 0, len(pids))
+	for _, pid := range pids {
+		cmds = append(cmds, pipeline.ZScore(RDB.Context, getRedisKey(KeyPostTimeZSet), strconv.FormatInt(pid, 10)))
+	}
+	if _, err := pipeline.Exec(RDB.Context); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	missing := make([]int64, 0)
+	for i, cmd := range cmds {
+		if cmd.Err() == redis.Nil {
+			missing = append(missing, pids[i])
+		}
+	}
+	return missing, nil
+}
+
+// RelistPost: 把数据库中已经发布、但是没有写入redis的帖子加入各个列表， 已经存在的数据不会修改
+// 和CreatePost一样作者默认投赞成票， 时间和分数使用帖子的发布时间
+func RelistPost(pid, communityID, authorID int64, publishedTime time.Time) error {
+	member := strconv.FormatInt(pid, 10)
+	pipeline := RDB.Client.TxPipeline()
+	pipeline.ZAddNX(RDB.Context, getRedisKey(KeyPostVotedZSetPF)+member, redis.Z{Score: 1, Member: authorID})
+	pipeline.ZAddNX(RDB.Context, getUserUpvotedKey(authorID), redis.Z{Score: float64(publishedTime.Unix()), Member: pid})
+	pipeline.ZAddNX(RDB.Context, getRedisKey(KeyPostTimeZSet), redis.Z{Score: float64(publishedTime.Unix()), Member: pid})
+	pipeline.ZAddNX(RDB.Context, getRedisKey(KeyPostScoreZSet), redis.Z{Score: float64(publishedTime.Unix()), Member: pid})
+	pipeline.SAdd(RDB.Context, getUserPostSetKey(authorID), pid)
+	pipeline.SAdd(RDB.Context, getRedisKey(KeyCommunitySetPF+strconv.FormatInt(communityID, 10)), pid)
+	_, err := pipeline.Exec(RDB.Context)
+	return err
+}
+
 func getIDSFromKey(key string, page, size int64) ([]string, error) {
 	//得到按照某种方式进行排序
 	start := (page - 1) * size
@@ -102,3 +143,162 @@ func GetCommunityPostIDListByOrder(p *models.ParamPostList) ([]string, error) {
 	// 这里就是按照某种分页的依据来实现数据的获取
 	return getIDSFromKey(key, p.Page, p.Size)
 }
+
+func getPostVotedKey(postID string) string {
+	return getRedisKey(KeyPostVotedZSetPF + postID)
+}
+
+// getCommunityPostKeys: 社区的帖子集合， 以及按时间和按分数排序的缓存
+func getCommunityPostKeys(communityID int64) []string {
+	cid := strconv.FormatInt(communityID, 10)
+	return []string{
+		getRedisKey(KeyCommunitySetPF + cid),
+		getRedisKey(KeyPostTimeZSet) + cid,
+		getRedisKey(KeyPostScoreZSet) + cid,
+	}
+}
+
+// RemovePost: 把删除的帖子从所有的列表中移除
+// 投票记录在window之后过期， 在这之前恢复帖子可以还原分数和点赞列表， karma不受影响
+func RemovePost(postID, communityID, authorID int64, window time.Duration) error {
+	pid := strconv.FormatInt(postID, 10)
+	votedKey := getPostVotedKey(pid)
+	upvoters, err := RDB.Client.ZRangeByScore(RDB.Context, votedKey, &redis.ZRangeBy{Min: "1", Max: "1"}).Result()
+	if err != nil {
+		return err
+	}
+	// 记录每个用户点赞的时间
+	upvotedAt := make([]*redis.FloatCmd, 0, len(upvoters))
+	pipeline := RDB.Client.Pipeline()
+	for _, uid := range upvoters {
+		upvotedAt = append(upvotedAt, pipeline.ZScore(RDB.Context, getRedisKey(KeyUserUpvotedPF)+uid, pid))
+	}
+	followersCmd := pipeline.SMembers(RDB.Context, getFollowerKey(authorID))
+	if _, err = pipeline.Exec(RDB.Context); err != nil && err != redis.Nil {
+		return err
+	}
+	removedKey := getRedisKey(KeyPostRemovedPF) + pid
+	communityKeys := getCommunityPostKeys(communityID)
+
+	tx := RDB.Client.TxPipeline()
+	for i, uid := range upvoters {
+		if score, err := upvotedAt[i].Result(); err == nil {
+			tx.HSet(RDB.Context, removedKey, uid, int64(score))
+			tx.ZRem(RDB.Context, getRedisKey(KeyUserUpvotedPF)+uid, pid)
+		}
+	}
+	tx.Expire(RDB.Context, removedKey, window)
+	tx.Expire(RDB.Context, votedKey, window)
+	tx.ZRem(RDB.Context, getRedisKey(KeyPostTimeZSet), pid)
+	tx.ZRem(RDB.Context, getRedisKey(KeyPostScoreZSet), pid)
+	tx.SRem(RDB.Context, communityKeys[0], pid)
+	tx.SRem(RDB.Context, getUserPostSetKey(authorID), pid)
+	// 删除社区和粉丝的帖子列表缓存， 下次查询时重新计算
+	tx.Del(RDB.Context, communityKeys[1:]...)
+	for _, follower := range followersCmd.Val() {
+		tx.Del(RDB.Context, getRedisKey(KeyFeedFollowingPF)+models.OrderTime+":"+follower,
+			getRedisKey(KeyFeedFollowingPF)+models.OrderScore+":"+follower)
+	}
+	_, err = tx.Exec(RDB.Context)
+	return err
+}
+
+// RestorePost: 把恢复的帖子重新加入所有的列表， 分数根据保留的投票记录重新计算
+func RestorePost(postID, communityID, authorID int64, createTime time.Time) error {
+	pid := strconv.FormatInt(postID, 10)
+	votedKey := getPostVotedKey(pid)
+	removedKey := getRedisKey(KeyPostRemovedPF) + pid
+
+	pipeline := RDB.Client.Pipeline()
+	votesCmd := pipeline.ZRangeWithScores(RDB.Context, votedKey, 0, -1)
+	upvotedCmd := pipeline.HGetAll(RDB.Context, removedKey)
+	followersCmd := pipeline.SMembers(RDB.Context, getFollowerKey(authorID))
+	if _, err := pipeline.Exec(RDB.Context); err != nil {
+		return err
+	}
+	// 发帖时作者自动投的赞成票没有计入分数， 之后作者修改或取消投票时分数按照和1的差值变化， 所以总票数要减去1
+	sum := -1.0
+	directions := make(map[string]float64, len(votesCmd.Val()))
+	for _, z := range votesCmd.Val() {
+		sum += z.Score
+		directions[z.Member.(string)] = z.Score
+	}
+	created := float64(createTime.Unix())
+	communityKeys := getCommunityPostKeys(communityID)
+
+	tx := RDB.Client.TxPipeline()
+	tx.Persist(RDB.Context, votedKey)
+	tx.ZAdd(RDB.Context, getRedisKey(KeyPostTimeZSet), redis.Z{Score: created, Member: pid})
+	tx.ZAdd(RDB.Context, getRedisKey(KeyPostScoreZSet), redis.Z{Score: created + sum*scorePerVote, Member: pid})
+	tx.SAdd(RDB.Context, communityKeys[0], pid)
+	tx.SAdd(RDB.Context, getUserPostSetKey(authorID), pid)
+	for uid, at := range upvotedCmd.Val() {
+		score, err := strconv.ParseFloat(at, 64)
+		if err != nil || directions[uid] != 1 {
+			continue
+		}
+		tx.ZAdd(RDB.Context, getRedisKey(KeyUserUpvotedPF)+uid, redis.Z{Score: score, Member: pid})
+	}
+	tx.Del(RDB.Context, removedKey)
+	tx.Del(RDB.Context, communityKeys[1:]...)
+	for _, follower := range followersCmd.Val() {
+		tx.Del(RDB.Context, getRedisKey(KeyFeedFollowingPF)+models.OrderTime+":"+follower,
+			getRedisKey(KeyFeedFollowingPF)+models.OrderScore+":"+follower)
+	}
+	_, err := tx.Exec(RDB.Context)
+	return err
+}
+
+// GetAllPostIDs: 分批获取按时间排序的集合中所有的帖子id
+func GetAllPostIDs(start, count int64) ([]string, error) {
+	return RDB.Client.ZRange(RDB.Context, getRedisKey(KeyPostTimeZSet), start, start+count-1).Result()
+}
+
+// PurgePosts: 清理数据库中已经不存在的帖子， 以前的删除没有同步redis， 会在各个列表中留下无效的id
+func PurgePosts(pids []string) error {
+	if len(pids) == 0 {
+		return nil
+	}
+	members := make([]interface{}, 0, len(pids))
+	votedKeys := make([]string, 0, len(pids))
+	for _, pid := range pids {
+		members = append(members, pid)
+		votedKeys = append(votedKeys, getPostVotedKey(pid))
+	}
+	pipeline := RDB.Client.TxPipeline()
+	pipeline.ZRem(RDB.Context, getRedisKey(KeyPostTimeZSet), members...)
+	pipeline.ZRem(RDB.Context, getRedisKey(KeyPostScoreZSet), members...)
+	pipeline.Del(RDB.Context, votedKeys...)
+	if _, err := pipeline.Exec(RDB.Context); err != nil {
+		return err
+	}
+	// 社区集合、作者的帖子集合和用户的点赞列表没有索引， 只能遍历
+	for _, prefix := range []string{KeyCommunitySetPF, KeyUserPostSetPF, KeyUserUpvotedPF} {
+		if err := removeMembersFromKeys(getRedisKey(prefix)+"*", members); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeMembersFromKeys: 从匹配pattern的所有集合或有序集合中删除members
+func removeMembersFromKeys(pattern string, members []interface{}) error {
+	iter := RDB.Client.Scan(RDB.Context, 0, pattern, 500).Iterator()
+	for iter.Next(RDB.Context) {
+		key := iter.Val()
+		typ, err := RDB.Client.Type(RDB.Context, key).Result()
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case "set":
+			err = RDB.Client.SRem(RDB.Context, key, members...).Err()
+		case "zset":
+			err = RDB.Client.ZRem(RDB.Context, key, members...).Err()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+	滑动窗口计数: 每次请求在有序集合中记录一个成员， score是请求的时间(毫秒)
+	统计的时候先删除窗口之外的成员， 集合的大小就是窗口内的请求次数
+*/
+
+// 返回值: {是否允许, 窗口内的次数, 窗口内最早一次请求的时间}
+// ARGV[4]为0时只记录不限制
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[4])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 1
+if limit > 0 and count >= limit then
+	allowed = 0
+else
+	redis.call('ZADD', KEYS[1], now, ARGV[3])
+	redis.call('PEXPIRE', KEYS[1], window)
+	count = count + 1
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {allowed, count, oldest[2] or tostring(now)}
+`)
+
+// memberSeq: 同一毫秒内可能有多次请求， 加上序号保证成员不重复
+var memberSeq uint64
+
+func getWindowKey(key string) string {
+	return getRedisKey(KeyRateLimitWindow) + key
+}
+
+func getLockoutKey(key string) string {
+	return getRedisKey(KeyRateLimitLock) + key
+}
+
+func runSlidingWindow(key string, window time.Duration, limit int64) (allowed bool, count int64, oldest time.Time, err error) {
+	now := time.Now()
+	member := strconv.FormatInt(now.UnixNano(), 10) + "-" + strconv.FormatUint(atomic.AddUint64(&memberSeq, 1), 10)
+	res, err := slidingWindowScript.Run(RDB.Context, RDB.Client,
+		[]string{getWindowKey(key)},
+		now.UnixMilli(), window.Milliseconds(), member, limit,
+	).Slice()
+	if err != nil {
+		return false, 0, time.Time{}, err
+	}
+	allowedN, _ := res[0].(int64)
+	count, _ = res[1].(int64)
+	oldestStr, _ := res[2].(string)
+	oldestMs, _ := strconv.ParseInt(oldestStr, 10, 64)
+	return allowedN == 1, count, time.UnixMilli(oldestMs), nil
+}
+
+// SlidingWindowAllow: 窗口内的次数没有达到limit时记录一次并返回true
+// 达到limit时返回false， 以及窗口内最早的一次请求移出窗口还需要等待的时间
+func SlidingWindowAllow(key string, limit int64, window time.Duration) (bool, time.Duration, error) {
+	allowed, _, oldest, err := runSlidingWindow(key, window, limit)
+	if err != nil || allowed {
+		return allowed, 0, err
+	}
+	return false, time.Until(oldest.Add(window)), nil
+}
+
+// SlidingWindowAdd: 记录一次， 返回窗口内的总次数
+func SlidingWindowAdd(key string, window time.Duration) (int64, error) {
+	_, count, _, err := runSlidingWindow(key, window, 0)
+	return count, err
+}
+
+// SlidingWindowReset: 清空窗口内的记录
+func SlidingWindowReset(key string) error {
+	return RDB.Client.Del(RDB.Context, getWindowKey(key)).Err()
+}
+
+// SetLockout: 锁定一段时间
+func SetLockout(key string, duration time.Duration) error {
+	return RDB.Client.Set(RDB.Context, getLockoutKey(key), 1, duration).Err()
+}
+
+// GetLockout: 获取剩余的锁定时间， 没有锁定时返回0
+func GetLockout(key string) (time.Duration, error) {
+	ttl, err := RDB.Client.PTTL(RDB.Context, getLockoutKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	// key不存在时返回-2， 没有过期时间时返回-1， 这里都当作没有锁定
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// DelLockout: 解除锁定
+func DelLockout(key string) error {
+	return RDB.Client.Del(RDB.Context, getLockoutKey(key)).Err()
+}
@@ -0,0 +1,213 @@
+package redis
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+)
+
+/*
+	每次登录创建一个会话， 会话中记录当前有效的refresh token id(jti)和登录设备的信息
+	每次刷新都会换成新的jti， 旧的refresh token再次出现说明被盗用了， 此时删除整个会话
+	每个用户还有一个会话id的有序集合， 用来列出用户所有登录的设备
+*/
+
+// sessionTouchInterval: 最后活跃时间的更新间隔， 避免每个请求都写一次redis
+const sessionTouchInterval = time.Minute
+
+var (
+	ErrSessionNotFound    = errors.New("登录会话不存在或已失效")
+	ErrRefreshTokenReused = errors.New("refresh token被重复使用")
+)
+
+// 返回值: 1 轮换成功, 0 jti不匹配(重复使用， 会话已删除), -1 会话不存在
+var rotateRefreshTokenScript = redis.NewScript(`
+local uid = redis.call('HGET', KEYS[1], 'user_id')
+if (not uid) or uid ~= ARGV[1] then
+	return -1
+end
+if redis.call('HGET', KEYS[1], 'jti') ~= ARGV[2] then
+	redis.call('DEL', KEYS[1])
+	redis.call('ZREM', KEYS[2], ARGV[6])
+	return 0
+end
+redis.call('HSET', KEYS[1], 'jti', ARGV[3], 'last_seen_at', ARGV[5])
+redis.call('PEXPIREAT', KEYS[1], ARGV[4])
+redis.call('ZADD', KEYS[2], ARGV[4], ARGV[6])
+redis.call('PEXPIREAT', KEYS[2], ARGV[4])
+return 1
+`)
+
+// 会话存在时更新最后活跃时间， 返回值: 1 会话存在, 0 会话不存在
+// 不能直接HSET， 否则会把已经删除的会话重新创建出来
+var touchSessionScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+local last = tonumber(redis.call('HGET', KEYS[1], 'last_seen_at') or '0')
+if tonumber(ARGV[1]) - last >= tonumber(ARGV[2]) then
+	redis.call('HSET', KEYS[1], 'last_seen_at', ARGV[1])
+end
+return 1
+`)
+
+func getSessionKey(sessionID string) string {
+	return getRedisKey(KeyAuthSession) + sessionID
+}
+
+func getUserSessionsKey(userID int64) string {
+	return getRedisKey(KeyUserSessions) + strconv.FormatInt(userID, 10)
+}
+
+// CreateSession: 创建登录会话， 记录第一个refresh token和登录设备
+func CreateSession(sessionID string, userID int64, jti string, device *models.Device, expireAt time.Time) error {
+	key := getSessionKey(sessionID)
+	userKey := getUserSessionsKey(userID)
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	pipeline := RDB.Client.TxPipeline()
+	pipeline.HSet(RDB.Context, key,
+		"user_id", strconv.FormatInt(userID, 10),
+		"jti", jti,
+		"user_agent", device.UserAgent,
+		"ip", device.IP,
+		"created_at", now,
+		"last_seen_at", now,
+	)
+	pipeline.ExpireAt(RDB.Context, key, expireAt)
+	pipeline.ZAdd(RDB.Context, userKey, redis.Z{Score: float64(expireAt.UnixMilli()), Member: sessionID})
+	pipeline.ExpireAt(RDB.Context, userKey, expireAt)
+	_, err := pipeline.Exec(RDB.Context)
+	return err
+}
+
+// RotateRefreshToken: 原子地把会话中的jti从oldJTI换成newJTI
+func RotateRefreshToken(sessionID string, userID int64, oldJTI, newJTI string, expireAt time.Time) error {
+	res, err := rotateRefreshTokenScript.Run(RDB.Context, RDB.Client,
+		[]string{getSessionKey(sessionID), getUserSessionsKey(userID)},
+		strconv.FormatInt(userID, 10), oldJTI, newJTI, expireAt.UnixMilli(), time.Now().UnixMilli(), sessionID,
+	).Int()
+	if err != nil {
+		return err
+	}
+	switch res {
+	case 1:
+		return nil
+	case 0:
+		return ErrRefreshTokenReused
+	default:
+		return ErrSessionNotFound
+	}
+}
+
+// DeleteSession: 删除登录会话， 会话下的refresh token都不能再使用
+func DeleteSession(userID int64, sessionID string) error {
+	pipeline := RDB.Client.TxPipeline()
+	pipeline.Del(RDB.Context, getSessionKey(sessionID))
+	pipeline.ZRem(RDB.Context, getUserSessionsKey(userID), sessionID)
+	_, err := pipeline.Exec(RDB.Context)
+	return err
+}
+
+// DeleteUserSessions: 删除用户所有的登录会话
+func DeleteUserSessions(userID int64) error {
+	userKey := getUserSessionsKey(userID)
+	ids, err := RDB.Client.ZRange(RDB.Context, userKey, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(ids)+1)
+	for _, id := range ids {
+		keys = append(keys, getSessionKey(id))
+	}
+	keys = append(keys, userKey)
+	return RDB.Client.Del(RDB.Context, keys...).Err()
+}
+
+// TouchSession: 判断登录会话是否还有效， 有效时顺便更新最后活跃时间
+func TouchSession(sessionID string) (bool, error) {
+	if sessionID == "" {
+		return false, nil
+	}
+	res, err := touchSessionScript.Run(RDB.Context, RDB.Client,
+		[]string{getSessionKey(sessionID)},
+		time.Now().UnixMilli(), sessionTouchInterval.Milliseconds(),
+	).Int()
+	return res == 1, err
+}
+
+// GetSession: 获取单个登录会话的信息， 会话不存在时返回ErrSessionNotFound
+func GetSession(sessionID string) (userID int64, session *models.Session, err error) {
+	vals, err := RDB.Client.HGetAll(RDB.Context, getSessionKey(sessionID)).Result()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(vals) == 0 {
+		return 0, nil, ErrSessionNotFound
+	}
+	userID, _ = strconv.ParseInt(vals["user_id"], 10, 64)
+	return userID, parseSession(sessionID, vals), nil
+}
+
+// GetUserSessions: 获取用户所有有效的登录会话， 按照创建时间倒序排列
+func GetUserSessions(userID int64) ([]*models.Session, error) {
+	userKey := getUserSessionsKey(userID)
+	// 1. 清理已经过期的会话id
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	if err := RDB.Client.ZRemRangeByScore(RDB.Context, userKey, "-inf", "("+now).Err(); err != nil {
+		return nil, err
+	}
+	ids, err := RDB.Client.ZRange(RDB.Context, userKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// 2. 批量查询会话的信息
+	pipeline := RDB.Client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, 0, len(ids))
+	for _, id := range ids {
+		cmds = append(cmds, pipeline.HGetAll(RDB.Context, getSessionKey(id)))
+	}
+	if _, err = pipeline.Exec(RDB.Context); err != nil {
+		return nil, err
+	}
+
+	// 3. 检测到refresh token重复使用时会话会被直接删除， 这里顺便清理掉
+	sessions := make([]*models.Session, 0, len(ids))
+	stale := make([]interface{}, 0)
+	for i, cmd := range cmds {
+		vals := cmd.Val()
+		if len(vals) == 0 {
+			stale = append(stale, ids[i])
+			continue
+		}
+		sessions = append(sessions, parseSession(ids[i], vals))
+	}
+	if len(stale) > 0 {
+		if err = RDB.Client.ZRem(RDB.Context, userKey, stale...).Err(); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+func parseSession(sessionID string, vals map[string]string) *models.Session {
+	createdAt, _ := strconv.ParseInt(vals["created_at"], 10, 64)
+	lastSeenAt, _ := strconv.ParseInt(vals["last_seen_at"], 10, 64)
+	return &models.Session{
+		ID:         sessionID,
+		UserAgent:  vals["user_agent"],
+		IP:         vals["ip"],
+		CreatedAt:  time.UnixMilli(createdAt),
+		LastSeenAt: time.UnixMilli(lastSeenAt),
+	}
+}
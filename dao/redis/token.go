@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SetTokenValidAfter: 设置用户的token水位线， 在这个时间之前签发的token都视为无效
+// expire需要不小于token的最长有效期， 过期之后旧token自然也已经失效了
+func SetTokenValidAfter(userID int64, t time.Time, expire time.Duration) error {
+	key := getRedisKey(KeyTokenValidAfter) + strconv.FormatInt(userID, 10)
+	return RDB.Client.Set(RDB.Context, key, t.Unix(), expire).Err()
+}
+
+// GetTokenValidAfter: 获取用户的token水位线， 没有设置时返回0
+func GetTokenValidAfter(userID int64) (int64, error) {
+	key := getRedisKey(KeyTokenValidAfter) + strconv.FormatInt(userID, 10)
+	val, err := RDB.Client.Get(RDB.Context, key).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return val, err
+}
+
+// RevokeToken: 将token id加入黑名单， 黑名单只需要保留到token过期为止
+func RevokeToken(tokenID string, expireAt time.Time) error {
+	ttl := time.Until(expireAt)
+	if tokenID == "" || ttl <= 0 {
+		return nil
+	}
+	return RDB.Client.Set(RDB.Context, getRedisKey(KeyTokenDenylist)+tokenID, 1, ttl).Err()
+}
+
+// IsTokenRevoked: 判断token id是否在黑名单中
+func IsTokenRevoked(tokenID string) (bool, error) {
+	if tokenID == "" {
+		return false, nil
+	}
+	n, err := RDB.Client.Exists(RDB.Context, getRedisKey(KeyTokenDenylist)+tokenID).Result()
+	return n > 0, err
+}
@@ -0,0 +1,82 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+	两步验证相关:
+	1. 已经使用过的TOTP时间步， 同一个验证码不能使用两次
+	2. 登录挑战: 密码验证通过之后发给客户端的临时凭证， 需要和TOTP验证码一起换取真正的token
+*/
+
+// 挑战存在时增加失败次数， 不存在时返回-1， 不能直接HINCRBY， 否则会创建出没有过期时间的key
+var incrChallengeAttemptsScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -1
+end
+return redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+`)
+
+// 挑战存在并且没有被其他请求占用时占用这个挑战， 返回1， 否则返回0
+var claimChallengeScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+return redis.call('HSETNX', KEYS[1], 'claimed', 1)
+`)
+
+// MarkTOTPStepUsed: 记录已经使用过的时间步， 返回false说明这个时间步的验证码已经用过了
+func MarkTOTPStepUsed(userID, step int64, expire time.Duration) (bool, error) {
+	key := getRedisKey(KeyTOTPUsedStep) + strconv.FormatInt(userID, 10) + ":" + strconv.FormatInt(step, 10)
+	return RDB.Client.SetNX(RDB.Context, key, 1, expire).Result()
+}
+
+// CreateLoginChallenge: 创建登录挑战
+func CreateLoginChallenge(challenge string, userID int64, expire time.Duration) error {
+	key := getRedisKey(KeyLoginChallenge) + challenge
+	pipeline := RDB.Client.TxPipeline()
+	pipeline.HSet(RDB.Context, key, "user_id", strconv.FormatInt(userID, 10), "attempts", 0)
+	pipeline.Expire(RDB.Context, key, expire)
+	_, err := pipeline.Exec(RDB.Context)
+	return err
+}
+
+// GetLoginChallenge: 获取登录挑战对应的用户， 挑战不存在时返回0
+func GetLoginChallenge(challenge string) (int64, error) {
+	val, err := RDB.Client.HGet(RDB.Context, getRedisKey(KeyLoginChallenge)+challenge, "user_id").Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return val, err
+}
+
+// IncrLoginChallengeAttempts: 记录一次失败的验证， 返回累计失败的次数， 挑战已经不存在时返回-1
+func IncrLoginChallengeAttempts(challenge string) (int64, error) {
+	return incrChallengeAttemptsScript.Run(RDB.Context, RDB.Client,
+		[]string{getRedisKey(KeyLoginChallenge) + challenge},
+	).Int64()
+}
+
+// ClaimLoginChallenge: 校验验证码之前占用挑战， 同一个挑战同时只有一个请求可以校验， 返回false说明挑战不存在或者正在被使用
+// 恢复码和TOTP时间步只会被占用了挑战的请求消耗
+func ClaimLoginChallenge(challenge string) (bool, error) {
+	n, err := claimChallengeScript.Run(RDB.Context, RDB.Client,
+		[]string{getRedisKey(KeyLoginChallenge) + challenge},
+	).Int64()
+	return n == 1, err
+}
+
+// ReleaseLoginChallenge: 验证失败之后释放挑战， 可以继续尝试
+func ReleaseLoginChallenge(challenge string) error {
+	return RDB.Client.HDel(RDB.Context, getRedisKey(KeyLoginChallenge)+challenge, "claimed").Err()
+}
+
+// DelLoginChallenge: 删除登录挑战， 每个挑战只能成功使用一次
+func DelLoginChallenge(challenge string) (bool, error) {
+	n, err := RDB.Client.Del(RDB.Context, getRedisKey(KeyLoginChallenge)+challenge).Result()
+	return n > 0, err
+}
@@ -1,16 +1,58 @@
 package redis
 
-import "time"
+import (
+	"time"
 
-// SetVerifyCode： 将手机号码的验证码存储到redis中
+	"github.com/redis/go-redis/v9"
+)
+
+// maxVerifyCodeFailures: 验证码最多可以输错的次数， 达到之后验证码失效， 需要重新获取
+const maxVerifyCodeFailures = 5
+
+// 验证码错误时增加失败次数， 达到上限之后删除验证码和失败次数
+// KEYS: 验证码, 失败次数
+// ARGV: 用户输入的验证码, 最多失败的次数
+// 返回1表示验证码正确， 0表示验证码不存在或者错误
+var checkVerifyCodeScript = redis.NewScript(`
+local code = redis.call('GET', KEYS[1])
+if not code then
+	return 0
+end
+if code == ARGV[1] then
+	return 1
+end
+local fails = redis.call('INCR', KEYS[2])
+if fails == 1 then
+	local ttl = redis.call('PTTL', KEYS[1])
+	if ttl > 0 then
+		redis.call('PEXPIRE', KEYS[2], ttl)
+	end
+end
+if fails >= tonumber(ARGV[2]) then
+	redis.call('DEL', KEYS[1], KEYS[2])
+end
+return 0
+`)
+
+// SetVerifyCode： 将手机号码的验证码存储到redis中， 重新发送验证码时清空失败次数
 func SetVerifyCode(phone, code string) error {
 	expireTime := time.Minute * 5
 
-	return RDB.Client.Set(RDB.Context, getRedisKey(KeyVerifyCode)+phone, code, expireTime).Err()
+	pipeline := RDB.Client.TxPipeline()
+	pipeline.Set(RDB.Context, getRedisKey(KeyVerifyCode)+phone, code, expireTime)
+	pipeline.Del(RDB.Context, getRedisKey(KeyVerifyCodeFails)+phone)
+	_, err := pipeline.Exec(RDB.Context)
+	return err
 }
 
-// CheckVerifyCode： 验证验证是否正确
+// CheckVerifyCode： 验证验证是否正确， 输错的次数过多时验证码失效
 func CheckVerifyCode(key, answer string) bool {
-	val := RDB.Client.Get(RDB.Context, getRedisKey(KeyVerifyCode)+key).Val()
-	return val == answer
+	keys := []string{getRedisKey(KeyVerifyCode) + key, getRedisKey(KeyVerifyCodeFails) + key}
+	n, err := checkVerifyCodeScript.Run(RDB.Context, RDB.Client, keys, answer, maxVerifyCodeFailures).Int()
+	return err == nil && n == 1
+}
+
+// DelVerifyCode： 删除验证码， 保证验证码只能使用一次
+func DelVerifyCode(key string) error {
+	return RDB.Client.Del(RDB.Context, getRedisKey(KeyVerifyCode)+key, getRedisKey(KeyVerifyCodeFails)+key).Err()
 }
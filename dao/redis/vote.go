@@ -3,10 +3,11 @@ package redis
 import (
 	"errors"
 	"fmt"
-	"math"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
 )
 
 const (
@@ -19,50 +20,204 @@ var (
 	ErrVoteRepeated   = errors.New("不允许重复投票")
 )
 
+// 读取之前的投票和修改帖子分数、投票记录、点赞列表以及作者的karma在同一个脚本中执行
+// 同时发起的相同投票只有一个会生效， 避免重复计算分数和karma
+// KEYS: 帖子的投票记录, 帖子分数, 用户点赞过的帖子, 作者的karma, karma需要同步的用户
+// ARGV: 用户id, 投票方向, 帖子id, 当前时间, 每票的分数, 作者id, 是否修改karma
+// 返回1表示投票成功， 0表示和之前的投票相同
+var voteForPostScript = redis.NewScript(`
+local pre = tonumber(redis.call('ZSCORE', KEYS[1], ARGV[1])) or 0
+local dir = tonumber(ARGV[2])
+if pre == dir then
+	return 0
+end
+redis.call('ZINCRBY', KEYS[2], (dir - pre) * tonumber(ARGV[5]), ARGV[3])
+if dir == 0 then
+	redis.call('ZREM', KEYS[1], ARGV[1])
+else
+	redis.call('ZADD', KEYS[1], dir, ARGV[1])
+end
+if dir == 1 then
+	redis.call('ZADD', KEYS[3], ARGV[4], ARGV[3])
+else
+	redis.call('ZREM', KEYS[3], ARGV[3])
+end
+if ARGV[7] == '1' then
+	redis.call('HINCRBY', KEYS[4], '` + karmaFieldPost + `', dir - pre)
+	redis.call('SADD', KEYS[5], ARGV[6])
+end
+return 1
+`)
+
 // 影响的只有两个部分， 一个是帖子的分数， 一个是投票数据
 // 是使用分数和帖子发起的时间去获得id， 之后才去Mysql中获得详细信息， 显示的投票数量不是帖子的分数， 而是统计帖子投票为1的数量
-func VoteForPost(userID int64, postID int64, direction int8) (err error) {
+// 作者的karma也在同一个脚本中修改， 作者给自己投票和已经注销的作者不计入karma
+func VoteForPost(userID, authorID, postID int64, direction int8) (err error) {
 	//1. 判断投票限制
 	//判断发帖时间
 	postTime := RDB.Client.ZScore(RDB.Context, getRedisKey(KeyPostTimeZSet), fmt.Sprintf("%d", postID)).Val()
 	if float64(time.Now().Unix())-postTime > oneWeekInSeconds { // 已经超过一周的帖子就不能投票了
 		return ErrVoteTimeExpire
 	}
-	//2 更新帖子分数
-	//先查看之前投票的分数
-	// 前面的投票记录就是在这里才会用到的
-	// pipeline.ZAdd(RDB.Context, votedKey, redis.Z{
-	// 	Score:  1,
-	// 	Member: userID,
-	// })
-	// 下面还有就这条投票数据进行更新
-	preDirection := RDB.Client.ZScore(RDB.Context, getRedisKey(KeyPostVotedZSetPF+fmt.Sprintf("%d", postID)),
-		fmt.Sprintf("%d", userID)).Val() // 就是之前这个用户给这个post的投票记录
-	//如果投票记录相同， 则不需要发起请求了
-	if int8(preDirection) == direction {
+	//2. 更新帖子分数、投票记录、用户点赞过的帖子和作者的karma
+	countKarma := "0"
+	if userID != authorID && authorID != models.DeletedUserID {
+		countKarma = "1"
+	}
+	keys := []string{
+		getRedisKey(KeyPostVotedZSetPF + fmt.Sprintf("%d", postID)),
+		getRedisKey(KeyPostScoreZSet),
+		getUserUpvotedKey(userID),
+		getUserKarmaKey(authorID),
+		getRedisKey(KeyKarmaDirty),
+	}
+	n, err := voteForPostScript.Run(RDB.Context, RDB.Client, keys,
+		userID, direction, postID, time.Now().Unix(), scorePerVote, authorID, countKarma).Int()
+	if err != nil {
+		return err
+	}
+	//如果投票记录相同， 则不需要修改
+	if n == 0 {
 		return ErrVoteRepeated
 	}
-	diff := math.Abs(preDirection - float64(direction))
-	var dir float64
-	if float64(direction) > preDirection {
-		dir = 1
-	} else {
-		dir = -1
+	return nil
+}
+
+// GetUserVotes: 获取用户所有的投票记录， 返回帖子id到投票方向的映射
+// 投票记录是按照帖子保存的， 所以需要遍历所有帖子的投票集合
+func GetUserVotes(userID int64) (map[string]int8, error) {
+	prefix := getRedisKey(KeyPostVotedZSetPF)
+	member := fmt.Sprintf("%d", userID)
+	votes := make(map[string]int8)
+	iter := RDB.Client.Scan(RDB.Context, 0, prefix+"*", 500).Iterator()
+	keys := make([]string, 0, 500)
+	flush := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+		pipeline := RDB.Client.Pipeline()
+		cmds := make([]*redis.FloatCmd, 0, len(keys))
+		for _, key := range keys {
+			cmds = append(cmds, pipeline.ZScore(RDB.Context, key, member))
+		}
+		// 没有投票的帖子会返回redis.Nil， 这里忽略
+		if _, err := pipeline.Exec(RDB.Context); err != nil && err != redis.Nil {
+			return err
+		}
+		for i, cmd := range cmds {
+			score, err := cmd.Result()
+			if err == redis.Nil || score == 0 {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			votes[keys[i][len(prefix):]] = int8(score)
+		}
+		keys = keys[:0]
+		return nil
+	}
+	for iter.Next(RDB.Context) {
+		keys = append(keys, iter.Val())
+		if len(keys) >= 500 {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
 	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+// DeleteUserVotes: 删除用户所有的投票记录， 同时撤销这些投票对帖子分数和作者karma的影响
+// votes是GetUserVotes的结果， authors是帖子id到作者id的映射， 已经删除的帖子可以没有作者
+func DeleteUserVotes(userID int64, votes map[string]int8, authors map[string]int64) error {
+	member := fmt.Sprintf("%d", userID)
 	pipeline := RDB.Client.TxPipeline()
-	// 记录分数变化
-	pipeline.ZIncrBy(RDB.Context, getRedisKey(KeyPostScoreZSet), dir*diff*scorePerVote, fmt.Sprintf("%d", postID))
-	//3. 更新用户为该帖子投票的数据
-	if direction == 0 {
-		// 是取消投票， 那么就要删除投票记录哦
-		pipeline.ZRem(RDB.Context, getRedisKey(KeyPostVotedZSetPF+fmt.Sprintf("%d", postID)), fmt.Sprintf("%d", userID))
-	} else {
-		pipeline.ZAdd(RDB.Context, getRedisKey(KeyPostVotedZSetPF+fmt.Sprintf("%d", postID)), redis.Z{
-			Score:  float64(direction),
-			Member: fmt.Sprintf("%d", userID),
-		})
-	}
-	_, err = pipeline.Exec(RDB.Context)
+	for postID, direction := range votes {
+		pipeline.ZIncrBy(RDB.Context, getRedisKey(KeyPostScoreZSet), -float64(direction)*scorePerVote, postID)
+		pipeline.ZRem(RDB.Context, getRedisKey(KeyPostVotedZSetPF+postID), member)
+		if authorID, ok := authors[postID]; ok && authorID != userID {
+			incrPostKarma(pipeline, authorID, -int64(direction))
+		}
+	}
+	pipeline.Del(RDB.Context, getUserUpvotedKey(userID), getUserKarmaKey(userID))
+	_, err := pipeline.Exec(RDB.Context)
+	return err
+}
 
-	return
+func getUserUpvotedKey(userID int64) string {
+	return getRedisKey(KeyUserUpvotedPF) + strconv.FormatInt(userID, 10)
+}
+
+// GetUserUpvotedPostIDs: 分页获取用户点赞过的帖子id， 最近点赞的在前面
+func GetUserUpvotedPostIDs(userID, page, size int64) ([]string, error) {
+	start := (page - 1) * size
+	return RDB.Client.ZRevRange(RDB.Context, getUserUpvotedKey(userID), start, start+size-1).Result()
+}
+
+// GetPostsKarma: 统计帖子收到的赞成票减去反对票， 不包括作者自己的投票
+func GetPostsKarma(userID int64, postIDs []int64) (int64, error) {
+	if len(postIDs) == 0 {
+		return 0, nil
+	}
+	member := strconv.FormatInt(userID, 10)
+	pipeline := RDB.Client.Pipeline()
+	ups := make([]*redis.IntCmd, 0, len(postIDs))
+	downs := make([]*redis.IntCmd, 0, len(postIDs))
+	owns := make([]*redis.FloatCmd, 0, len(postIDs))
+	for _, id := range postIDs {
+		key := getRedisKey(KeyPostVotedZSetPF + strconv.FormatInt(id, 10))
+		ups = append(ups, pipeline.ZCount(RDB.Context, key, "1", "1"))
+		downs = append(downs, pipeline.ZCount(RDB.Context, key, "-1", "-1"))
+		owns = append(owns, pipeline.ZScore(RDB.Context, key, member))
+	}
+	// 作者没有投票的帖子会返回redis.Nil， 这里忽略
+	if _, err := pipeline.Exec(RDB.Context); err != nil && err != redis.Nil {
+		return 0, err
+	}
+	var karma int64
+	for i := range postIDs {
+		karma += ups[i].Val() - downs[i].Val() - int64(owns[i].Val())
+	}
+	return karma, nil
+}
+
+// BackfillUserUpvoted: 旧的投票记录只按照帖子保存， 把赞成票回填到每个用户的点赞列表中， 只会执行一次
+// 旧数据没有点赞时间， 使用帖子的发布时间代替
+func BackfillUserUpvoted() error {
+	if done, err := IsMigrated(migrationUserUpvoted); err != nil || done {
+		return err
+	}
+	prefix := getRedisKey(KeyPostVotedZSetPF)
+	iter := RDB.Client.Scan(RDB.Context, 0, prefix+"*", 500).Iterator()
+	for iter.Next(RDB.Context) {
+		key := iter.Val()
+		postID := key[len(prefix):]
+		userIDs, err := RDB.Client.ZRangeByScore(RDB.Context, key, &redis.ZRangeBy{Min: "1", Max: "1"}).Result()
+		if err != nil {
+			return err
+		}
+		if len(userIDs) == 0 {
+			continue
+		}
+		postTime := RDB.Client.ZScore(RDB.Context, getRedisKey(KeyPostTimeZSet), postID).Val()
+		pipeline := RDB.Client.Pipeline()
+		for _, uid := range userIDs {
+			pipeline.ZAddNX(RDB.Context, getRedisKey(KeyUserUpvotedPF)+uid, redis.Z{Score: postTime, Member: postID})
+		}
+		if _, err = pipeline.Exec(RDB.Context); err != nil {
+			return err
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	// 回填使用的是ZADD NX， 多个实例同时执行也没有问题， 全部完成之后再写入标记
+	return SetMigrated(migrationUserUpvoted)
 }
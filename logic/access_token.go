@@ -0,0 +1,143 @@
+package logic
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/snowflake"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	accessTokenPrefix        = "rdt_"      // 个人访问令牌的固定前缀， 用来和JWT区分
+	accessTokenPrefixLength  = 12          // 保存下来用于展示的明文长度
+	accessTokenLimit         = 50          // 每个用户最多可以创建的令牌数量
+	accessTokenTouchInterval = time.Minute // 最后使用时间的更新间隔
+)
+
+var (
+	ErrorInvalidAccessToken  = errors.New("个人访问令牌无效或已过期")
+	ErrorAccessTokenNotExist = errors.New("个人访问令牌不存在")
+	ErrorAccessTokenLimit    = errors.New("个人访问令牌数量已达上限")
+)
+
+// IsAccessToken: 判断Authorization中携带的是不是个人访问令牌
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, accessTokenPrefix)
+}
+
+// CreateAccessToken: 创建个人访问令牌， 明文只在这里返回一次
+func CreateAccessToken(userID int64, p *models.ParamCreateAccessToken) (*models.AccessTokenCreated, error) {
+	count, err := mysql.CountAccessTokens(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= accessTokenLimit {
+		return nil, ErrorAccessTokenLimit
+	}
+
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return nil, err
+	}
+	raw := accessTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	token := &models.AccessToken{
+		ID:        snowflake.GenID(),
+		UserID:    userID,
+		Name:      p.Name,
+		TokenHash: hashAccessToken(raw),
+		Prefix:    raw[:accessTokenPrefixLength],
+		Scopes:    normalizeScopes(p.Scopes),
+	}
+	if p.ExpiresIn > 0 {
+		expiresAt := time.Now().AddDate(0, 0, p.ExpiresIn)
+		token.ExpiresAt = &expiresAt
+	}
+	if err = mysql.InsertAccessToken(token); err != nil {
+		return nil, err
+	}
+	zap.L().Info("access token created",
+		zap.String("audit", "access_token"),
+		zap.Int64("user_id", userID),
+		zap.Int64("token_id", token.ID),
+		zap.Strings("scopes", token.Scopes),
+	)
+	return &models.AccessTokenCreated{Token: raw, AccessToken: token}, nil
+}
+
+// GetAccessTokens: 获取用户所有的个人访问令牌
+func GetAccessTokens(userID int64) ([]*models.AccessToken, error) {
+	return mysql.GetAccessTokens(userID)
+}
+
+// RevokeAccessToken: 撤销用户的某个个人访问令牌
+func RevokeAccessToken(userID, tokenID int64) error {
+	ok, err := mysql.DeleteAccessToken(userID, tokenID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrorAccessTokenNotExist
+	}
+	zap.L().Info("access token revoked",
+		zap.String("audit", "access_token"),
+		zap.Int64("user_id", userID),
+		zap.Int64("token_id", tokenID),
+	)
+	return nil
+}
+
+// AuthenticateAccessToken: 验证个人访问令牌， 成功时顺便记录最后使用时间
+// 申请注销或者已经匿名化的账号的令牌都不能再使用
+func AuthenticateAccessToken(raw string) (*models.AccessToken, error) {
+	token, err := mysql.GetAccessTokenByHash(hashAccessToken(raw))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if token == nil || (token.ExpiresAt != nil && now.After(*token.ExpiresAt)) {
+		return nil, ErrorInvalidAccessToken
+	}
+	user, err := mysql.GetUserByID(token.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrorInvalidAccessToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletionScheduledAt != nil || user.DeletedTime != nil {
+		return nil, ErrorInvalidAccessToken
+	}
+	// 最后使用时间只是给用户参考的， 更新失败不影响这次请求
+	if err = mysql.TouchAccessToken(token.ID, now, accessTokenTouchInterval); err != nil {
+		zap.L().Error("mysql.TouchAccessToken failed", zap.Int64("token_id", token.ID), zap.Error(err))
+	}
+	return token, nil
+}
+
+// normalizeScopes: 去掉重复的权限范围， 按照models.AccessScopes中的顺序排列
+func normalizeScopes(scopes []string) []string {
+	res := make([]string, 0, len(scopes))
+	for _, s := range models.AccessScopes {
+		for _, scope := range scopes {
+			if scope == s {
+				res = append(res, s)
+				break
+			}
+		}
+	}
+	return res
+}
+
+func hashAccessToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,232 @@
+package logic
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/file"
+	"go.uber.org/zap"
+)
+
+const (
+	accountDeletionGracePeriod = 7 * 24 * time.Hour // 申请注销之后的等待期， 期间重新登录会取消注销
+	accountDeletionInterval    = time.Hour          // 检查到期账号的间隔
+	accountDeletionBatchSize   = 100
+	accountDeletionLockExpire  = 10 * time.Minute // 注销单个账号的锁， 超时之后其他实例可以重试
+)
+
+// ExportUserData: 导出用户的个人数据， 返回zip压缩包的内容
+// 包括个人信息、帖子、评论、投票记录和登录的设备
+func ExportUserData(userID int64) ([]byte, error) {
+	// 1. 查询所有的数据
+	user, err := mysql.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := mysql.GetPostsByAuthor(userID)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := mysql.GetCommentsByAuthor(userID)
+	if err != nil {
+		return nil, err
+	}
+	voteMap, err := redis.GetUserVotes(userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := redis.GetUserSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 转换成导出的格式
+	exportPosts := make([]*models.ExportPost, 0, len(posts))
+	for _, p := range posts {
+		exportPosts = append(exportPosts, &models.ExportPost{Post: p, CreateTime: p.CreateTime, UpdatedTime: p.UpdatedTime})
+	}
+	exportComments := make([]*models.ExportComment, 0, len(comments))
+	for _, c := range comments {
+		exportComments = append(exportComments, &models.ExportComment{Comment: c, CreateTime: c.CreateTime, UpdatedTime: c.UpdatedTime})
+	}
+	votes := make([]*models.UserVote, 0, len(voteMap))
+	for postID, direction := range voteMap {
+		votes = append(votes, &models.UserVote{PostID: postID, Direction: direction})
+	}
+
+	// 3. 每一类数据写成压缩包中的一个json文件
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", user},
+		{"posts.json", exportPosts},
+		{"comments.json", exportComments},
+		{"votes.json", votes},
+		{"sessions.json", sessions},
+	}
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RequestAccountDeletion: 申请注销账号， 等待期结束之后才会真正删除
+// 申请之后所有设备都会退出登录， 返回删除的时间
+func RequestAccountDeletion(userID int64, password string) (time.Time, error) {
+	if err := mysql.VerifyPassword(userID, password); err != nil {
+		return time.Time{}, err
+	}
+	at := time.Now().Add(accountDeletionGracePeriod)
+	if err := mysql.ScheduleUserDeletion(userID, at); err != nil {
+		return time.Time{}, err
+	}
+	if err := revokeAllSessions(userID); err != nil {
+		return time.Time{}, err
+	}
+	zap.L().Info("account deletion requested",
+		zap.String("audit", "account_deletion"),
+		zap.Int64("user_id", userID),
+		zap.Time("scheduled_at", at),
+	)
+	return at, nil
+}
+
+// cancelAccountDeletion: 等待期内重新登录， 取消注销
+func cancelAccountDeletion(user *models.User) error {
+	if user.DeletionScheduledAt == nil {
+		return nil
+	}
+	if err := mysql.CancelUserDeletion(user.ID); err != nil {
+		return err
+	}
+	user.DeletionScheduledAt = nil
+	zap.L().Info("account deletion cancelled",
+		zap.String("audit", "account_deletion"),
+		zap.Int64("user_id", user.ID),
+	)
+	return nil
+}
+
+// RunAccountDeletionWorker: 定期删除等待期已经结束的账号， 需要在单独的goroutine中运行
+func RunAccountDeletionWorker() {
+	ticker := time.NewTicker(accountDeletionInterval)
+	defer ticker.Stop()
+	for {
+		processDueAccountDeletions()
+		<-ticker.C
+	}
+}
+
+// processDueAccountDeletions: 删除所有到期的账号
+// 失败的账号和其他实例正在处理的账号直接跳过， 每一批从上一批的最后一个账号之后查询， 不会重复查询同一批账号
+func processDueAccountDeletions() {
+	now := time.Now()
+	var afterTime time.Time
+	var afterID int64
+	for {
+		users, err := mysql.GetUsersDueForDeletion(now, afterTime, afterID, accountDeletionBatchSize)
+		if err != nil {
+			zap.L().Error("mysql.GetUsersDueForDeletion failed", zap.Error(err))
+			return
+		}
+		for _, user := range users {
+			if err := deleteAccountLocked(user.ID); err != nil {
+				// 失败的账号还保留着删除时间， 下次会重试
+				zap.L().Error("deleteAccount failed", zap.Int64("user_id", user.ID), zap.Error(err))
+			}
+		}
+		if len(users) < accountDeletionBatchSize {
+			return
+		}
+		last := users[len(users)-1]
+		afterTime, afterID = *last.DeletionScheduledAt, last.ID
+	}
+}
+
+// deleteAccountLocked: 加锁之后重新检查账号是否还需要注销， 多个实例同时运行时每个账号只会处理一次
+func deleteAccountLocked(userID int64) error {
+	ok, err := redis.AcquireAccountDeletionLock(userID, accountDeletionLockExpire)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// 其他实例正在处理这个账号
+		return nil
+	}
+	defer func() {
+		if err := redis.ReleaseAccountDeletionLock(userID); err != nil {
+			zap.L().Error("redis.ReleaseAccountDeletionLock failed", zap.Int64("user_id", userID), zap.Error(err))
+		}
+	}()
+	// 查询列表之后账号可能已经被其他实例注销， 或者用户重新登录取消了注销
+	user, err := mysql.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.DeletionScheduledAt == nil || user.DeletionScheduledAt.After(time.Now()) {
+		return nil
+	}
+	return deleteAccount(user)
+}
+
+// deleteAccount: 清除用户的投票记录、头像和登录会话， 最后匿名化用户信息
+// 帖子和评论会保留下来， 但是转给了占位用户， 不再能关联到用户本人
+func deleteAccount(user *models.User) error {
+	votes, err := redis.GetUserVotes(user.ID)
+	if err != nil {
+		return err
+	}
+	postIDs := make([]string, 0, len(votes))
+	for postID := range votes {
+		postIDs = append(postIDs, postID)
+	}
+	authors, err := mysql.GetPostAuthorIDs(postIDs)
+	if err != nil {
+		return err
+	}
+	if err := redis.DeleteUserVotes(user.ID, votes, authors); err != nil {
+		return err
+	}
+	if err := file.DeleteUserAvatars(user.ID, user.Avatar); err != nil {
+		return err
+	}
+	if err := redis.DeleteUserSessions(user.ID); err != nil {
+		return err
+	}
+	if err := deleteUserFollows(user.ID); err != nil {
+		return err
+	}
+	if err := deleteUserBlocks(user.ID); err != nil {
+		return err
+	}
+	if err := redis.DeleteUserPostSet(user.ID); err != nil {
+		return err
+	}
+	if err := mysql.AnonymizeUser(user.ID); err != nil {
+		return err
+	}
+	zap.L().Info("account deleted",
+		zap.String("audit", "account_deletion"),
+		zap.Int64("user_id", user.ID),
+	)
+	return nil
+}
@@ -0,0 +1,184 @@
+package logic
+
+import (
+	"errors"
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/jwt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrorTokenRevoked    = errors.New("token已经失效")
+	ErrorInvalidToken    = errors.New("无效的token")
+	ErrorSessionNotExist = errors.New("登录会话不存在")
+)
+
+// IssueTokens: 登录成功之后创建一个新的登录会话， 并且签发access token和refresh token
+// device记录的是这次登录的设备信息， 用户可以在会话列表中查看
+func IssueTokens(user *models.User, device *models.Device) (accessToken, refreshToken string, err error) {
+	// 注销等待期内重新登录， 视为取消注销
+	if err = cancelAccountDeletion(user); err != nil {
+		return "", "", err
+	}
+	sessionID := jwt.NewSessionID()
+	refreshToken, rc, err := jwt.GenRefreshToken(user.ID, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+	// 服务端记录当前有效的refresh token
+	if err = redis.CreateSession(sessionID, user.ID, rc.ID, device, rc.ExpiresAt.Time); err != nil {
+		return "", "", err
+	}
+	accessToken, err = jwt.GenAccessToken(user.ID, user.Username, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// IsTokenRevoked: 判断access token是否已经失效， 包括主动注销的、会话已删除的和早于用户token水位线签发的
+func IsTokenRevoked(mc *jwt.MyClaims) (bool, error) {
+	// 旧版本签发的token没有会话信息， 需要重新登录
+	if mc.SessionID == "" || mc.IssuedAt == nil || mc.ExpiresAt == nil {
+		return true, nil
+	}
+	revoked, err := redis.IsTokenRevoked(mc.ID)
+	if err != nil || revoked {
+		return revoked, err
+	}
+	// 修改或重置密码之后， 之前签发的token都需要失效
+	if revoked, err = isIssuedBeforeWatermark(mc.UserID, mc.IssuedAt.Unix()); err != nil || revoked {
+		return revoked, err
+	}
+	// 会话被注销或者检测到refresh token重复使用时， 这个会话下的access token也一起失效
+	// 会话有效时顺便更新会话的最后活跃时间
+	exist, err := redis.TouchSession(mc.SessionID)
+	return !exist, err
+}
+
+// tokenWatermarkExpire: token水位线需要保留到当前所有token都过期为止
+func tokenWatermarkExpire() time.Duration {
+	if jwt.RefreshTokenExpire() > jwt.AccessTokenExpire() {
+		return jwt.RefreshTokenExpire()
+	}
+	return jwt.AccessTokenExpire()
+}
+
+// isIssuedBeforeWatermark: 判断token是否早于用户的token水位线签发
+func isIssuedBeforeWatermark(userID, issuedAt int64) (bool, error) {
+	validAfter, err := redis.GetTokenValidAfter(userID)
+	if err != nil {
+		return false, err
+	}
+	return validAfter > 0 && issuedAt < validAfter, nil
+}
+
+// Logout: 注销当前的access token和它所属的登录会话
+func Logout(mc *jwt.MyClaims) error {
+	if err := redis.RevokeToken(mc.ID, mc.ExpiresAt.Time); err != nil {
+		return err
+	}
+	return redis.DeleteSession(mc.UserID, mc.SessionID)
+}
+
+// GetSessions: 获取用户所有登录的设备， currentSessionID对应的会话会被标记出来
+func GetSessions(userID int64, currentSessionID string) ([]*models.Session, error) {
+	sessions, err := redis.GetUserSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	if sessions == nil {
+		sessions = make([]*models.Session, 0)
+	}
+	for _, s := range sessions {
+		s.Current = s.ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// DeleteSession: 远程注销用户的某个登录会话， 会话下的token立即失效
+func DeleteSession(userID int64, sessionID string) error {
+	owner, _, err := redis.GetSession(sessionID)
+	if errors.Is(err, redis.ErrSessionNotFound) {
+		return ErrorSessionNotExist
+	}
+	if err != nil {
+		return err
+	}
+	// 不能注销别人的会话， 对外表现为会话不存在
+	if owner != userID {
+		return ErrorSessionNotExist
+	}
+	return redis.DeleteSession(userID, sessionID)
+}
+
+// revokeAllSessions: 注销用户所有的登录会话， 同时撤销所有的个人访问令牌， 修改或重置密码、申请注销账号之后调用
+func revokeAllSessions(userID int64) error {
+	// 在这个时间点之前签发的token都不再有效
+	if err := redis.SetTokenValidAfter(userID, time.Now(), tokenWatermarkExpire()); err != nil {
+		return err
+	}
+	if err := redis.DeleteUserSessions(userID); err != nil {
+		return err
+	}
+	return mysql.DeleteUserAccessTokens(userID)
+}
+
+// RefreshToken: 使用refresh token换取新的token， 每次使用之后refresh token都会轮换
+// 已经使用过的refresh token再次出现说明可能被盗用了， 此时注销整个会话
+func RefreshToken(refreshToken string) (string, string, error) {
+	// 1. 验证refresh token的签名和有效期
+	rc, err := jwt.ParseRefreshToken(refreshToken)
+	if err != nil {
+		zap.L().Debug("jwt.ParseRefreshToken failed", zap.Error(err))
+		return "", "", ErrorInvalidToken
+	}
+	// 2. 修改或重置密码之前签发的refresh token不能再使用
+	revoked, err := isIssuedBeforeWatermark(rc.UserID, rc.IssuedAt.Unix())
+	if err != nil {
+		return "", "", err
+	}
+	if revoked {
+		if err := redis.DeleteSession(rc.UserID, rc.SessionID); err != nil {
+			zap.L().Error("redis.DeleteSession failed", zap.Error(err))
+		}
+		return "", "", ErrorTokenRevoked
+	}
+	user, err := mysql.GetUserByID(rc.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", "", ErrorTokenRevoked
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	// 3. 生成新的refresh token， 并且在服务端替换掉旧的
+	newRefreshToken, newRC, err := jwt.GenRefreshToken(rc.UserID, rc.SessionID)
+	if err != nil {
+		return "", "", err
+	}
+	err = redis.RotateRefreshToken(rc.SessionID, rc.UserID, rc.ID, newRC.ID, newRC.ExpiresAt.Time)
+	if errors.Is(err, redis.ErrRefreshTokenReused) {
+		zap.L().Warn("refresh token reuse detected, session revoked",
+			zap.Int64("user_id", rc.UserID), zap.String("session_id", rc.SessionID))
+		return "", "", ErrorTokenRevoked
+	}
+	if errors.Is(err, redis.ErrSessionNotFound) {
+		return "", "", ErrorTokenRevoked
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	// 4. 签发新的access token
+	accessToken, err := jwt.GenAccessToken(user.ID, user.Username, rc.SessionID)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, newRefreshToken, nil
+}
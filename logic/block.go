@@ -0,0 +1,137 @@
+package logic
+
+import (
+	"errors"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+)
+
+var (
+	ErrorBlockSelf = errors.New("不能屏蔽或静音自己")
+	ErrorBlocked   = errors.New("对方已经屏蔽了你")
+)
+
+// BlockUser: 屏蔽或静音用户， 已经屏蔽时再静音会改成静音， 反之亦然
+// 屏蔽时会同时取消双方的关注关系
+func BlockUser(userID, targetID int64, blockType string) error {
+	if userID == targetID {
+		return ErrorBlockSelf
+	}
+	if _, err := getActiveUser(targetID); err != nil {
+		return err
+	}
+	if err := mysql.UpsertUserBlock(userID, targetID, blockType); err != nil {
+		return err
+	}
+	if err := redis.SetUserBlockCache(userID, targetID, blockType); err != nil {
+		return err
+	}
+	if blockType != models.BlockTypeBlock {
+		return nil
+	}
+	if err := UnfollowUser(userID, targetID); err != nil {
+		return err
+	}
+	return UnfollowUser(targetID, userID)
+}
+
+// UnblockUser: 取消屏蔽或静音， 类型不一致时不做任何修改
+func UnblockUser(userID, targetID int64, blockType string) error {
+	deleted, err := mysql.DeleteUserBlock(userID, targetID, blockType)
+	if err != nil || !deleted {
+		return err
+	}
+	return redis.DelUserBlockCache(userID, targetID)
+}
+
+// GetBlockedUsers: 分页获取屏蔽或静音的人
+func GetBlockedUsers(userID int64, blockType string, pageNum, pageSize int64) ([]*models.BlockedUser, error) {
+	return mysql.GetBlockedUsers(userID, blockType, pageNum, pageSize)
+}
+
+// getUserBlocks: 获取用户屏蔽和静音的人， 优先读取redis中的缓存
+func getUserBlocks(userID int64) (map[int64]string, error) {
+	blocks, ok, err := redis.GetUserBlocks(userID)
+	if err != nil || ok {
+		return blocks, err
+	}
+	// 先获取版本再查询数据库， 查询期间列表被修改时不会写入过期的缓存
+	version, err := redis.GetUserBlocksVersion(userID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := mysql.GetUserBlocks(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err = redis.SetUserBlocks(userID, version, list); err != nil {
+		return nil, err
+	}
+	blocks = make(map[int64]string, len(list))
+	for _, b := range list {
+		blocks[b.TargetID] = b.Type
+	}
+	return blocks, nil
+}
+
+// checkNotBlocked: actor要回复、提及或私信target之前检查是否被target屏蔽了
+func checkNotBlocked(actorID, targetID int64) error {
+	if actorID == targetID {
+		return nil
+	}
+	blocks, err := getUserBlocks(targetID)
+	if err != nil {
+		return err
+	}
+	if blocks[actorID] == models.BlockTypeBlock {
+		return ErrorBlocked
+	}
+	return nil
+}
+
+// filterBlockedPosts: 去掉viewer屏蔽或静音的人发布的帖子， 没有登录时viewerID为0
+func filterBlockedPosts(viewerID int64, data []*models.ApiPostDetail2) ([]*models.ApiPostDetail2, error) {
+	if viewerID == 0 || len(data) == 0 {
+		return data, nil
+	}
+	blocks, err := getUserBlocks(viewerID)
+	if err != nil || len(blocks) == 0 {
+		return data, err
+	}
+	filtered := data[:0]
+	for _, d := range data {
+		if _, ok := blocks[d.Post.AuthorID]; !ok {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered, nil
+}
+
+// filterBlockedComments: 去掉viewer屏蔽或静音的人发布的评论
+func filterBlockedComments(viewerID int64, comments []*models.Comment) ([]*models.Comment, error) {
+	if viewerID == 0 || len(comments) == 0 {
+		return comments, nil
+	}
+	blocks, err := getUserBlocks(viewerID)
+	if err != nil || len(blocks) == 0 {
+		return comments, err
+	}
+	filtered := comments[:0]
+	for _, c := range comments {
+		if _, ok := blocks[c.AuthorID]; !ok {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered, nil
+}
+
+// deleteUserBlocks: 注销账号时删除所有的屏蔽和静音关系
+func deleteUserBlocks(userID int64) error {
+	blockers, err := mysql.DeleteUserBlocks(userID)
+	if err != nil {
+		return err
+	}
+	return redis.DeleteUserBlocksCache(append(blockers, userID)...)
+}
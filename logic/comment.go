@@ -1,6 +1,8 @@
 package logic
 
 import (
+	"time"
+
 	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
 	"github.com/xiaorui/reddit-async/reddit-backend/models"
 	"github.com/xiaorui/reddit-async/reddit-backend/pkg/snowflake"
@@ -10,11 +12,27 @@ import (
 // CreateComment: 给定postid创建一个新的评论
 func CreateComment(postID, userID int64, p *models.ParamCreateNewComment) error {
 	// 1. 先看post是否存在
-	_, err := mysql.GetPostByID(postID) // 如果post存在则不会返回错误
+	post, err := mysql.GetPostByID(postID) // 如果post存在则不会返回错误
 	if err != nil {
 		zap.L().Error("mysql.GetPostByID failed...", zap.Error(err))
 		return err
 	}
+	// 没有发布的帖子不能评论， 锁定的帖子也不能评论
+	if !post.Listed() {
+		return mysql.ErrorPostNotExist
+	}
+	if post.Status == models.PostStatusLocked {
+		return ErrorPostLocked
+	}
+	// 被帖子的作者屏蔽之后不能回复
+	if err = checkNotBlocked(userID, post.AuthorID); err != nil {
+		return err
+	}
+	// 也不能提及屏蔽了自己的人
+	links, err := checkContent(userID, p.Content)
+	if err != nil {
+		return err
+	}
 
 	// 生成commentid
 	commentID := snowflake.GenID()
@@ -27,28 +45,52 @@ func CreateComment(postID, userID int64, p *models.ParamCreateNewComment) error
 		Content:  p.Content,
 	}
 
-	// 2. 保存内容
-	return mysql.CreateComment(comment)
+	// 2. 保存内容， 同时提取内容中的外链
+	if err = mysql.CreateComment(comment); err != nil {
+		return err
+	}
+	saveOutboundLinks(models.LinkSourceComment, commentID, postID, post.CommunityID, userID, links)
+	return nil
 }
 
-// DeleteComment: 删除Comment
+// DeleteComment: 删除Comment， 作者和社区的版主可以删除
 func DeleteComment(userid, commentID int64) error {
 	//1. 查询Comment是否存在
 	comment, err := mysql.GetCommentByID(commentID)
 	if err != nil {
 		return err
 	}
+	if comment.AuthorID != userid {
+		if err = checkManageComment(userid, comment); err != nil {
+			return err
+		}
+	}
 
-	// 2. 如果Comment存在则删除
-	return mysql.DeleteComment(userid, comment)
+	// 2. 如果Comment存在则标记为删除， 讨论串中显示为占位符
+	return mysql.SoftDeleteComment(commentID, userid, time.Now())
 }
 
-// GetComment: 返回给定post的评论
-func GetComment(postID, pageNum, pageSize int64) (comms []*models.Comment, err error) {
-	//1. 验证post是否存在
-	if _, err = mysql.GetPostByID(postID); err != nil {
+// GetComment: 返回给定post的评论， 会去掉viewer屏蔽或静音的人发布的评论
+func GetComment(viewerID, postID, pageNum, pageSize int64) (comms []*models.Comment, err error) {
+	//1. 验证post是否存在， 已经删除的帖子的评论仍然可以查看
+	post, err := mysql.GetPostByIDUnscoped(postID)
+	if err != nil {
+		return
+	}
+	if err = checkViewPost(viewerID, post); err != nil {
+		return
+	}
+	// 2. 查询返回结果， 已经删除的评论保留位置， 显示为占位符
+	if comms, err = mysql.GetComments(postID, pageNum, pageSize); err != nil {
+		return
+	}
+	for _, comment := range comms {
+		if comment.DeletedAt.Valid {
+			deletedComment(comment)
+		}
+	}
+	if comms, err = filterBlockedComments(viewerID, comms); err != nil {
 		return
 	}
-	// 2. 查询返回结果
-	return mysql.GetComments(postID, pageNum, pageSize)
+	return comms, renderComments(comms)
 }
@@ -1,9 +1,14 @@
 package logic
 
 import (
+	"errors"
+	"sort"
+	"strconv"
+
 	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
 	"github.com/xiaorui/reddit-async/reddit-backend/models"
 	"github.com/xiaorui/reddit-async/reddit-backend/pkg/snowflake"
+	"gorm.io/gorm"
 )
 
 func GetCommunityList() ([]*models.Community, error) {
@@ -16,8 +21,8 @@ func GetCommunityDetail(communityID int64) (*models.Community, error) {
 	return mysql.GetCommunityDetailByID(communityID)
 }
 
-// CreateNewCommunity: 创建新的社区
-func CreateNewCommunity(p *models.ParamCommunity) error {
+// CreateNewCommunity: 创建新的社区， 创建者自动成为社区的owner
+func CreateNewCommunity(p *models.ParamCommunity, userID int64) error {
 	// 1. 查询该社区是否存在
 	if err := mysql.CheckCommunityExist(p.Name); err != nil {
 		return err
@@ -33,7 +38,7 @@ func CreateNewCommunity(p *models.ParamCommunity) error {
 		Introduction: p.Introduction,
 	}
 
-	return mysql.InsertCommunity(comm)
+	return mysql.InsertCommunityWithOwner(comm, userID)
 }
 
 // UpdateCommunity： 更新社区信息
@@ -56,6 +61,70 @@ func UpdateCommunity(cid string, p *models.ParamCommunity) (com *models.Communit
 	return mysql.SaveCommunity(com)
 }
 
+// SetCommunityRequirements: 设置在社区中发帖需要的最低karma和注册天数， 以及新用户的帖子是否需要审核
+func SetCommunityRequirements(cid string, p *models.ParamCommunityRequirements) (*models.Community, error) {
+	com, err := mysql.GetCommunityByID(cid)
+	if err != nil {
+		return nil, err
+	}
+	com.MinKarma = p.MinKarma
+	com.MinAccountAgeDays = p.MinAccountAgeDays
+	com.ReviewAccountAgeDays = p.ReviewAccountAgeDays
+	return mysql.SaveCommunity(com)
+}
+
 func DeleteCommunity(cid string) error {
 	return mysql.DeleteCommunity(cid)
 }
+
+// GetCommunityMembers: 获取社区的owner和版主等成员， 按照角色从高到低排列
+func GetCommunityMembers(cid int64) ([]*models.CommunityMember, error) {
+	members, err := mysql.GetCommunityMembers(cid)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return models.CommunityRoleLevel(members[i].Role) > models.CommunityRoleLevel(members[j].Role)
+	})
+	return members, nil
+}
+
+// SetCommunityMemberRole: 任免社区的版主， 社区的owner不能被修改
+func SetCommunityMemberRole(cid, userID int64, role string) error {
+	if role != models.CommunityRoleModerator && role != models.CommunityRoleMember {
+		return ErrorInvalidRole
+	}
+	if err := checkMemberChangeable(cid, userID); err != nil {
+		return err
+	}
+	return mysql.SetCommunityRole(cid, userID, role)
+}
+
+// RemoveCommunityMember: 移除用户在社区中的角色， 社区的owner不能被移除
+func RemoveCommunityMember(cid, userID int64) error {
+	if err := checkMemberChangeable(cid, userID); err != nil {
+		return err
+	}
+	return mysql.DeleteCommunityMember(cid, userID)
+}
+
+// checkMemberChangeable: 社区和用户必须存在， 并且用户不是社区的owner
+func checkMemberChangeable(cid, userID int64) error {
+	if _, err := mysql.GetCommunityByID(strconv.FormatInt(cid, 10)); err != nil {
+		return err
+	}
+	if _, err := mysql.GetUserByID(userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return mysql.ErrorUserNotExist
+		}
+		return err
+	}
+	role, err := mysql.GetCommunityRole(cid, userID)
+	if err != nil {
+		return err
+	}
+	if role == models.CommunityRoleOwner {
+		return ErrorNotPerm
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package logic
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/mail"
+	"github.com/xiaorui/reddit-async/reddit-backend/settings"
+	"go.uber.org/zap"
+)
+
+// contactRevertExpire: 撤销链接的有效期
+const contactRevertExpire = 7 * 24 * time.Hour
+
+var ErrorInvalidRevertToken = errors.New("撤销链接无效或已过期")
+
+// changeContact: 修改邮箱或手机号码， 新的联系方式已经通过验证码验证过了
+// 修改成功之后通知旧的联系方式， 并附上撤销修改的链接
+func changeContact(user *models.User, contactType, value string) (*models.User, error) {
+	oldValue, oldVerifiedAt := user.Email, user.EmailVerifiedAt
+	if contactType == models.ContactPhone {
+		oldValue, oldVerifiedAt = user.Phone, user.PhoneVerifiedAt
+	}
+
+	// 1. 写入新的联系方式， 验证码只能使用一次
+	now := time.Now()
+	if err := mysql.UpdateContact(user.ID, contactType, value, &now); err != nil {
+		return nil, err
+	}
+	if err := redis.DelVerifyCode(value); err != nil {
+		zap.L().Error("redis.DelVerifyCode failed", zap.Error(err))
+	}
+	if contactType == models.ContactPhone {
+		user.Phone, user.PhoneVerifiedAt = value, &now
+	} else {
+		user.Email, user.EmailVerifiedAt = value, &now
+	}
+
+	// 2. 之前没有设置过的不需要通知
+	if oldValue == "" || oldValue == value {
+		return user, nil
+	}
+	token, err := newContactRevertToken()
+	if err != nil {
+		return nil, err
+	}
+	change := &models.ContactChange{
+		UserID:        user.ID,
+		Type:          contactType,
+		OldValue:      oldValue,
+		NewValue:      value,
+		OldVerifiedAt: oldVerifiedAt,
+	}
+	if err := redis.SetContactChange(token, change, contactRevertExpire); err != nil {
+		return nil, err
+	}
+	sendContactChangedNotice(user, change, token)
+	return user, nil
+}
+
+// markContactVerified: 通过验证码登录或者找回密码时， 顺便记录联系方式已经验证过了
+func markContactVerified(userID int64, contactType string, verifiedAt *time.Time) {
+	if verifiedAt != nil {
+		return
+	}
+	if err := mysql.MarkContactVerified(userID, contactType); err != nil {
+		zap.L().Error("mysql.MarkContactVerified failed", zap.Error(err))
+	}
+}
+
+// RevertContactChange: 通过发给旧联系方式的链接撤销修改
+// 撤销之后所有设备都需要重新登录， 防止修改联系方式的人继续使用账号
+func RevertContactChange(token string) error {
+	// 1. 取出修改记录， 链接只能使用一次
+	change, err := redis.TakeContactChange(token)
+	if err != nil {
+		return err
+	}
+	if change == nil {
+		return ErrorInvalidRevertToken
+	}
+	user, err := mysql.GetUserByID(change.UserID)
+	if err != nil {
+		return err
+	}
+
+	// 2. 之后又修改过的不能再撤销
+	current := user.Email
+	if change.Type == models.ContactPhone {
+		current = user.Phone
+	}
+	if current != change.NewValue {
+		return ErrorInvalidRevertToken
+	}
+
+	// 3. 旧的联系方式可能已经被别人使用了
+	var exist bool
+	if change.Type == models.ContactPhone {
+		exist, err = mysql.IsPhoneExist(change.OldValue)
+	} else {
+		exist, err = mysql.IsEmailExist(change.OldValue)
+	}
+	if err != nil {
+		return err
+	}
+	if exist {
+		if change.Type == models.ContactPhone {
+			return mysql.ErrorPhoneExist
+		}
+		return mysql.ErrorEmailExist
+	}
+
+	// 4. 恢复旧的联系方式
+	if err = mysql.UpdateContact(user.ID, change.Type, change.OldValue, change.OldVerifiedAt); err != nil {
+		return err
+	}
+	// 修改联系方式的人可能已经创建了个人访问令牌， 一起撤销
+	if err = revokeAllSessions(user.ID); err != nil {
+		return err
+	}
+	zap.L().Warn("contact change reverted",
+		zap.String("audit", "contact_revert"),
+		zap.Int64("user_id", user.ID),
+		zap.String("type", change.Type),
+	)
+	return nil
+}
+
+func newContactRevertToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// sendContactChangedNotice: 通知旧的联系方式， 发送失败不影响修改结果
+// 短信模板只支持发送验证码， 所以修改手机号码时发送到账号的邮箱
+func sendContactChangedNotice(user *models.User, change *models.ContactChange, token string) {
+	to := user.Email
+	name := "邮箱"
+	if change.Type == models.ContactEmail {
+		to = change.OldValue
+	} else {
+		name = "手机号码"
+	}
+	if to == "" {
+		zap.L().Warn("no email to notify contact change", zap.Int64("user_id", user.ID), zap.String("type", change.Type))
+		return
+	}
+	link := strings.TrimRight(settings.Conf.AuthConfig.PublicURL, "/") +
+		"/api/v1/auth/contact/revert?token=" + url.QueryEscape(token)
+	ok := mail.NewMailer().Send(
+		mail.Email{
+			From: mail.From{
+				Address: settings.Conf.EmailConfig.FromConfig.Address,
+				Name:    settings.Conf.EmailConfig.FromConfig.Name,
+			},
+			To:      []string{to},
+			Subject: fmt.Sprintf("您的%s已修改", name),
+			HTML: []byte(fmt.Sprintf(`
+				<p>亲爱的 %s：</p>
+				<p>您账号的%s已于 %s 由 %s 修改为 %s。</p>
+				<p>如果这不是您本人的操作， 请在 %d 天内点击下面的链接撤销修改， 撤销之后所有设备都需要重新登录：</p>
+				<p><a href="%s">%s</a></p>
+			`, user.Username, name, time.Now().Format("2006-01-02 15:04:05"),
+				maskContact(change.OldValue), maskContact(change.NewValue),
+				int(contactRevertExpire.Hours()/24), link, link)),
+		},
+	)
+	if !ok {
+		zap.L().Error("send contact changed notice failed", zap.Int64("user_id", user.ID))
+	}
+}
+
+// maskContact: 通知中只显示联系方式的一部分
+func maskContact(value string) string {
+	if at := strings.Index(value, "@"); at > 0 {
+		if at <= 2 {
+			return value[:1] + "***" + value[at:]
+		}
+		return value[:2] + "***" + value[at:]
+	}
+	if len(value) > 7 {
+		return value[:3] + "****" + value[len(value)-4:]
+	}
+	return value
+}
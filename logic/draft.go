@@ -0,0 +1,211 @@
+package logic
+
+import (
+	"errors"
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+const (
+	postSchedulerInterval  = 30 * time.Second
+	postSchedulerBatchSize = 100
+	maxPublishAhead        = 365 * 24 * time.Hour // 最多可以提前多久设置定时发布
+
+	postRelistInterval = time.Hour          // 检查已经发布但是不在redis列表中的帖子的间隔
+	postRelistWindow   = 7 * 24 * time.Hour // 只检查这段时间内发布的帖子， 更早的帖子已经不能投票
+	postRelistGrace    = time.Minute        // 刚发布的帖子可能还在写入redis， 跳过
+)
+
+var (
+	ErrorNotDraft           = errors.New("该帖子不是草稿")
+	ErrorInvalidPublishTime = errors.New("发布时间需要在未来一年之内")
+)
+
+// GetDrafts: 分页获取用户的草稿， 包括定时发布的草稿
+func GetDrafts(userID, pageNum, pageSize int64) ([]*models.Post, error) {
+	drafts, err := mysql.GetDraftsByAuthor(userID, pageNum, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	if err = loadPostPayloads(userID, drafts); err != nil {
+		return nil, err
+	}
+	return drafts, renderPosts(drafts)
+}
+
+// SchedulePost: 设置草稿定时发布的时间， 为空时取消定时发布
+func SchedulePost(userID, postID int64, p *models.ParamSchedulePost) error {
+	post, err := getDraft(userID, postID)
+	if err != nil {
+		return err
+	}
+	if p.PublishAt != nil {
+		if err = checkPublishAt(*p.PublishAt); err != nil {
+			return err
+		}
+	}
+	return mysql.SchedulePost(post.ID, p.PublishAt)
+}
+
+// PublishPost: 作者立即发布草稿
+func PublishPost(userID, postID int64) error {
+	post, err := getDraft(userID, postID)
+	if err != nil {
+		return err
+	}
+	return publishDraft(post)
+}
+
+// RunPostScheduler: 定期发布已经到时间的草稿， 需要在单独的goroutine中运行
+// 待发布的草稿保存在数据库中， 重启之后会继续发布， 多个实例同时运行时通过数据库的条件更新保证只发布一次
+// 写入数据库之后、写入redis之前进程退出的帖子， 启动时和之后每隔一段时间重新加入redis的列表
+func RunPostScheduler() {
+	ticker := time.NewTicker(postSchedulerInterval)
+	defer ticker.Stop()
+	relistTicker := time.NewTicker(postRelistInterval)
+	defer relistTicker.Stop()
+	relistMissingPosts()
+	for {
+		publishDuePosts()
+		select {
+		case <-ticker.C:
+		case <-relistTicker.C:
+			relistMissingPosts()
+		}
+	}
+}
+
+// relistMissingPosts: 找出最近发布、但是不在redis列表中的帖子， 重新加入列表
+func relistMissingPosts() {
+	now := time.Now()
+	since, until := now.Add(-postRelistWindow), now.Add(-postRelistGrace)
+	var afterTime time.Time
+	var afterID int64
+	for {
+		posts, err := mysql.GetRecentlyPublishedPosts(since, until, afterTime, afterID, postSchedulerBatchSize)
+		if err != nil {
+			zap.L().Error("mysql.GetRecentlyPublishedPosts failed", zap.Error(err))
+			return
+		}
+		if len(posts) == 0 {
+			return
+		}
+		pids := make([]int64, 0, len(posts))
+		byID := make(map[int64]*models.Post, len(posts))
+		for _, post := range posts {
+			pids = append(pids, post.ID)
+			byID[post.ID] = post
+		}
+		missing, err := redis.GetPostsMissingFromList(pids)
+		if err != nil {
+			zap.L().Error("redis.GetPostsMissingFromList failed", zap.Error(err))
+			return
+		}
+		for _, pid := range missing {
+			post := byID[pid]
+			if err = redis.RelistPost(post.ID, post.CommunityID, post.AuthorID, post.ListedTime()); err != nil {
+				zap.L().Error("redis.RelistPost failed", zap.Int64("post_id", post.ID), zap.Error(err))
+				continue
+			}
+			zap.L().Warn("relisted post missing from redis", zap.Int64("post_id", post.ID))
+		}
+		if len(posts) < postSchedulerBatchSize {
+			return
+		}
+		last := posts[len(posts)-1]
+		afterTime, afterID = *last.PublishedTime, last.ID
+	}
+}
+
+// publishDuePosts: 发布所有到时间的草稿， 按照发布时间分页， 每个草稿在一次调度中只处理一次
+// 发布失败或者正在被其他实例发布的草稿直接跳过， 不影响后面的草稿， 下一次调度时再重试
+func publishDuePosts() {
+	now := time.Now()
+	var afterTime time.Time
+	var afterID int64
+	for {
+		posts, err := mysql.GetDuePosts(now, afterTime, afterID, postSchedulerBatchSize)
+		if err != nil {
+			zap.L().Error("mysql.GetDuePosts failed", zap.Error(err))
+			return
+		}
+		for _, post := range posts {
+			if err = publishDraft(post); err != nil {
+				zap.L().Error("publish scheduled post failed", zap.Int64("post_id", post.ID), zap.Error(err))
+			}
+		}
+		if len(posts) < postSchedulerBatchSize {
+			return
+		}
+		last := posts[len(posts)-1]
+		afterTime, afterID = *last.PublishAt, last.ID
+	}
+}
+
+// publishDraft: 发布草稿， 社区开启审核时可能需要先审核
+// 先通过数据库的条件更新把草稿改为已发布， 只有修改成功的调用方才会写入redis， 多个实例同时发布时只会写入一次
+// 写入redis失败时把帖子改回草稿， 定时发布的草稿会在下一次调度时重新发布
+// 写入redis之前进程退出时， 帖子由relistMissingPosts重新加入列表
+func publishDraft(post *models.Post) error {
+	community, err := mysql.GetCommunityDetailByID(post.CommunityID)
+	if err != nil {
+		return err
+	}
+	status, err := initialPostStatus(post.AuthorID, community)
+	if err != nil {
+		return err
+	}
+	if status == models.PostStatusPending {
+		_, err = mysql.PublishDraft(post.ID, status, nil)
+		return err
+	}
+
+	now := time.Now()
+	ok, err := mysql.PublishDraft(post.ID, status, &now)
+	if err != nil || !ok {
+		// 已经被其他实例发布， 或者草稿已经被删除
+		return err
+	}
+	if err = redis.CreatePost(post.ID, post.CommunityID, post.AuthorID); err != nil {
+		if rerr := mysql.RevertPublishDraft(post.ID, post.PublishAt); rerr != nil {
+			zap.L().Error("mysql.RevertPublishDraft failed", zap.Int64("post_id", post.ID), zap.Error(rerr))
+		}
+		return err
+	}
+	zap.L().Info("post published", zap.Int64("post_id", post.ID), zap.Int64("author_id", post.AuthorID))
+	// 写入redis之前帖子被删除了， 删除时帖子还不在列表中， 这里再移除一次
+	current, err := mysql.GetPostByIDUnscoped(post.ID)
+	if err != nil {
+		return err
+	}
+	if current.DeletedAt.Valid || !current.Listed() {
+		return redis.RemovePost(post.ID, post.CommunityID, post.AuthorID, 0)
+	}
+	return nil
+}
+
+// getDraft: 获取用户自己的草稿
+func getDraft(userID, postID int64) (*models.Post, error) {
+	post, err := getPost(postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.AuthorID != userID {
+		return nil, ErrorNotPerm
+	}
+	if post.Status != models.PostStatusDraft {
+		return nil, ErrorNotDraft
+	}
+	return post, nil
+}
+
+func checkPublishAt(t time.Time) error {
+	if d := time.Until(t); d <= 0 || d > maxPublishAhead {
+		return ErrorInvalidPublishTime
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package logic
+
+import (
+	"errors"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+var ErrorFollowSelf = errors.New("不能关注自己")
+
+// FollowUser: 关注用户， 重复关注不会报错
+func FollowUser(followerID, followeeID int64) error {
+	if followerID == followeeID {
+		return ErrorFollowSelf
+	}
+	if _, err := getActiveUser(followeeID); err != nil {
+		return err
+	}
+	if err := checkNotBlocked(followerID, followeeID); err != nil {
+		return err
+	}
+	if err := mysql.InsertFollow(followerID, followeeID); err != nil {
+		return err
+	}
+	return redis.Follow(followerID, followeeID)
+}
+
+// UnfollowUser: 取消关注
+func UnfollowUser(followerID, followeeID int64) error {
+	if err := mysql.DeleteFollow(followerID, followeeID); err != nil {
+		return err
+	}
+	return redis.Unfollow(followerID, followeeID)
+}
+
+// GetFollowers: 分页获取用户的粉丝
+func GetFollowers(userID, pageNum, pageSize int64) ([]*models.FollowUser, error) {
+	if _, err := getActiveUser(userID); err != nil {
+		return nil, err
+	}
+	return mysql.GetFollowers(userID, pageNum, pageSize)
+}
+
+// GetFollowing: 分页获取用户关注的人
+func GetFollowing(userID, pageNum, pageSize int64) ([]*models.FollowUser, error) {
+	if _, err := getActiveUser(userID); err != nil {
+		return nil, err
+	}
+	return mysql.GetFollowing(userID, pageNum, pageSize)
+}
+
+// GetFollowingFeed: 获取关注的人最近发布的帖子， 静音的人发布的帖子会被去掉
+func GetFollowingFeed(userID int64, p *models.ParamPostList) ([]*models.ApiPostDetail2, error) {
+	pidList, err := redis.GetFollowingPostIDListByOrder(userID, p)
+	if err != nil {
+		return nil, err
+	}
+	if len(pidList) == 0 {
+		return []*models.ApiPostDetail2{}, nil
+	}
+	posts, err := mysql.GetPostListByIDs(pidList)
+	if err != nil {
+		return nil, err
+	}
+	data, err := getPostDetails(posts)
+	if err != nil {
+		return nil, err
+	}
+	return filterBlockedPosts(userID, data)
+}
+
+// BackfillUserPosts: 把已有的帖子加入redis中作者的帖子集合， 只会执行一次
+func BackfillUserPosts() error {
+	done, err := redis.IsMigrated(redis.MigrationUserPosts)
+	if err != nil || done {
+		return err
+	}
+	posts, err := mysql.GetAllPostAuthors()
+	if err != nil {
+		return err
+	}
+	if err = redis.BackfillUserPosts(posts); err != nil {
+		return err
+	}
+	zap.L().Info("user post sets backfilled", zap.Int("posts", len(posts)))
+	return redis.SetMigrated(redis.MigrationUserPosts)
+}
+
+// deleteUserFollows: 注销账号时删除所有的关注和粉丝关系
+func deleteUserFollows(userID int64) error {
+	following, followers, err := mysql.DeleteUserFollows(userID)
+	if err != nil {
+		return err
+	}
+	return redis.DeleteUserFollows(userID, following, followers)
+}
@@ -0,0 +1,112 @@
+package logic
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/snowflake"
+	"github.com/xiaorui/reddit-async/reddit-backend/settings"
+	"go.uber.org/zap"
+)
+
+const invitationCodeBytes = 10 // base32编码之后是16个字符
+
+var (
+	ErrorRegistrationClosed = errors.New("暂不开放注册")
+	ErrorInvitationRequired = errors.New("需要邀请码才能注册")
+	ErrorInvitationNotExist = errors.New("邀请码不存在")
+)
+
+// RegistrationMode: 当前的注册方式， 没有配置时为开放注册
+func RegistrationMode() string {
+	cfg := settings.Conf.RegistrationConfig
+	if cfg == nil || cfg.Mode == "" {
+		return settings.RegistrationOpen
+	}
+	return cfg.Mode
+}
+
+// checkRegistration: 注册之前检查注册方式， 邀请注册时先检查邀请码， 避免验证码等操作做完之后才失败
+func checkRegistration(inviteCode string) error {
+	switch RegistrationMode() {
+	case settings.RegistrationOpen:
+		return nil
+	case settings.RegistrationInvite:
+		if inviteCode == "" {
+			return ErrorInvitationRequired
+		}
+		return mysql.CheckInvitation(inviteCode, time.Now())
+	default:
+		// 配置错误时按照关闭注册处理
+		return ErrorRegistrationClosed
+	}
+}
+
+// insertNewUser: 保存注册的用户， 邀请注册时同时消耗邀请码的次数
+func insertNewUser(user *models.User, inviteCode string) error {
+	if RegistrationMode() != settings.RegistrationInvite {
+		return mysql.InsertUser(user)
+	}
+	if err := mysql.InsertUserWithInvitation(user, inviteCode, time.Now()); err != nil {
+		return err
+	}
+	zap.L().Info("invitation redeemed",
+		zap.String("audit", "invitation"),
+		zap.Int64("user_id", user.ID),
+		zap.Int64("inviter_id", user.InviterID),
+	)
+	return nil
+}
+
+// CreateInvitation: 管理员生成邀请码
+func CreateInvitation(inviterID int64, p *models.ParamCreateInvitation) (*models.Invitation, error) {
+	buf := make([]byte, invitationCodeBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	invitation := &models.Invitation{
+		ID:        snowflake.GenID(),
+		Code:      base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf),
+		InviterID: inviterID,
+		MaxUses:   p.MaxUses,
+	}
+	if p.ExpiresIn > 0 {
+		expiresAt := time.Now().AddDate(0, 0, p.ExpiresIn)
+		invitation.ExpiresAt = &expiresAt
+	}
+	if err := mysql.InsertInvitation(invitation); err != nil {
+		return nil, err
+	}
+	zap.L().Info("invitation created",
+		zap.String("audit", "invitation"),
+		zap.Int64("user_id", inviterID),
+		zap.Int64("invitation_id", invitation.ID),
+	)
+	return invitation, nil
+}
+
+// GetInvitations: 分页获取所有的邀请码
+func GetInvitations(pageNum, pageSize int64) ([]*models.Invitation, error) {
+	return mysql.GetInvitations(pageNum, pageSize)
+}
+
+// RevokeInvitation: 撤销邀请码， 已经注册的用户不受影响
+func RevokeInvitation(userID, invitationID int64) error {
+	revoked, err := mysql.RevokeInvitation(invitationID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrorInvitationNotExist
+	}
+	zap.L().Info("invitation revoked",
+		zap.String("audit", "invitation"),
+		zap.Int64("user_id", userID),
+		zap.Int64("invitation_id", invitationID),
+	)
+	return nil
+}
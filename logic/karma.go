@@ -0,0 +1,111 @@
+package logic
+
+import (
+	"errors"
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+const (
+	karmaReconcileInterval  = 5 * time.Minute
+	karmaReconcileBatchSize = 500
+)
+
+var (
+	ErrorKarmaTooLow   = errors.New("karma不足， 不能在该社区发帖")
+	ErrorAccountTooNew = errors.New("注册时间太短， 不能在该社区发帖")
+)
+
+// RunKarmaReconcileWorker: 定期把redis中有变化的karma同步到数据库， 需要在单独的goroutine中运行
+func RunKarmaReconcileWorker() {
+	ticker := time.NewTicker(karmaReconcileInterval)
+	defer ticker.Stop()
+	for {
+		reconcileKarma()
+		<-ticker.C
+	}
+}
+
+// reconcileKarma: 同步所有标记过的用户的karma， 失败的用户重新标记， 下次再同步
+func reconcileKarma() {
+	for {
+		userIDs, err := redis.PopKarmaDirty(karmaReconcileBatchSize)
+		if err != nil {
+			zap.L().Error("redis.PopKarmaDirty failed", zap.Error(err))
+			return
+		}
+		for i, userID := range userIDs {
+			postKarma, commentKarma, err := redis.GetUserKarma(userID)
+			if err == nil {
+				err = mysql.UpdateUserKarma(userID, postKarma, commentKarma)
+			}
+			if err != nil {
+				zap.L().Error("reconcile karma failed", zap.Int64("user_id", userID), zap.Error(err))
+				if err = redis.MarkKarmaDirty(userIDs[i:]...); err != nil {
+					zap.L().Error("redis.MarkKarmaDirty failed", zap.Error(err))
+				}
+				return
+			}
+		}
+		if len(userIDs) < karmaReconcileBatchSize {
+			return
+		}
+	}
+}
+
+// BackfillUserKarma: 根据已有的投票记录计算每个作者的帖子karma， 只会执行一次
+func BackfillUserKarma() error {
+	done, err := redis.IsMigrated(redis.MigrationUserKarma)
+	if err != nil || done {
+		return err
+	}
+	posts, err := mysql.GetAllPostAuthors()
+	if err != nil {
+		return err
+	}
+	postIDs := make(map[int64][]int64)
+	for _, p := range posts {
+		postIDs[p.AuthorID] = append(postIDs[p.AuthorID], p.ID)
+	}
+	for authorID, ids := range postIDs {
+		karma, err := redis.GetPostsKarma(authorID, ids)
+		if err != nil {
+			return err
+		}
+		if err = redis.SetPostKarma(authorID, karma); err != nil {
+			return err
+		}
+	}
+	zap.L().Info("user karma backfilled", zap.Int("authors", len(postIDs)))
+	return redis.SetMigrated(redis.MigrationUserKarma)
+}
+
+// checkPostRequirements: 检查用户是否满足社区的发帖门槛
+func checkPostRequirements(userID int64, community *models.Community) error {
+	if community.MinKarma <= 0 && community.MinAccountAgeDays <= 0 {
+		return nil
+	}
+	if community.MinAccountAgeDays > 0 {
+		user, err := mysql.GetUserByID(userID)
+		if err != nil {
+			return err
+		}
+		if time.Since(user.CreateTime) < time.Duration(community.MinAccountAgeDays)*24*time.Hour {
+			return ErrorAccountTooNew
+		}
+	}
+	if community.MinKarma > 0 {
+		postKarma, commentKarma, err := redis.GetUserKarma(userID)
+		if err != nil {
+			return err
+		}
+		if postKarma+commentKarma < community.MinKarma {
+			return ErrorKarmaTooLow
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package logic
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/markdown"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/snowflake"
+	"go.uber.org/zap"
+)
+
+const renderedHTMLExpire = 7 * 24 * time.Hour
+
+// renderMarkdown: 把markdown源码渲染为HTML， 相同的源码只渲染一次， 结果缓存在redis中
+// 缓存的key由渲染规则的版本和源码的sha1组成， 帖子每次编辑都是一个新的版本， 不需要删除旧的缓存
+func renderMarkdown(sources []string) ([]string, error) {
+	htmls := make([]string, len(sources))
+	// 空的源码不需要渲染， 也不查询缓存， 全部为空时不访问redis
+	indexes := make([]int, 0, len(sources))
+	keys := make([]string, 0, len(sources))
+	for i, source := range sources {
+		if source == "" {
+			continue
+		}
+		sum := sha1.Sum([]byte(source))
+		indexes = append(indexes, i)
+		keys = append(keys, strconv.Itoa(markdown.Version)+":"+hex.EncodeToString(sum[:]))
+	}
+	if len(keys) == 0 {
+		return htmls, nil
+	}
+	cached, err := redis.GetRenderedHTML(keys)
+	if err != nil {
+		// 缓存不可用时直接渲染
+		zap.L().Error("redis.GetRenderedHTML failed", zap.Error(err))
+		cached = make([]string, len(keys))
+	}
+	missing := make(map[string]string)
+	for j, i := range indexes {
+		if cached[j] != "" {
+			htmls[i] = cached[j]
+			continue
+		}
+		if html, ok := missing[keys[j]]; ok {
+			htmls[i] = html
+			continue
+		}
+		if htmls[i], err = markdown.Render(sources[i]); err != nil {
+			return nil, err
+		}
+		missing[keys[j]] = htmls[i]
+	}
+	if len(missing) > 0 {
+		if err = redis.SetRenderedHTML(missing, renderedHTMLExpire); err != nil {
+			zap.L().Error("redis.SetRenderedHTML failed", zap.Error(err))
+		}
+	}
+	return htmls, nil
+}
+
+// renderPosts: 补充帖子内容渲染之后的HTML
+func renderPosts(posts []*models.Post) error {
+	sources := make([]string, 0, len(posts))
+	for _, post := range posts {
+		sources = append(sources, post.Content)
+	}
+	htmls, err := renderMarkdown(sources)
+	if err != nil {
+		return err
+	}
+	for i, post := range posts {
+		post.ContentHTML = htmls[i]
+	}
+	return nil
+}
+
+// renderComments: 补充评论内容渲染之后的HTML
+func renderComments(comments []*models.Comment) error {
+	sources := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		sources = append(sources, comment.Content)
+	}
+	htmls, err := renderMarkdown(sources)
+	if err != nil {
+		return err
+	}
+	for i, comment := range comments {
+		comment.ContentHTML = htmls[i]
+	}
+	return nil
+}
+
+// checkContent: 保存帖子或评论之前提取内容中的外链和提及的用户， 返回外链
+// 提及的用户屏蔽了作者时返回ErrorBlocked
+func checkContent(authorID int64, content string) ([]string, error) {
+	urls, mentions := markdown.Extract(content)
+	userIDs, err := mysql.GetUserIDsByUsernames(mentions)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range userIDs {
+		if err = checkNotBlocked(authorID, id); err != nil {
+			return nil, err
+		}
+	}
+	return urls, nil
+}
+
+// savePostLinks: 保存帖子内容中的外链
+func savePostLinks(post *models.Post, urls []string) {
+	saveOutboundLinks(models.LinkSourcePost, post.ID, post.ID, post.CommunityID, post.AuthorID, urls)
+}
+
+// saveOutboundLinks: 保存内容中的外链， 替换以前保存的链接
+// 内容已经保存成功， 外链只是方便之后审核， 失败时只记录日志， 不影响发帖和评论
+func saveOutboundLinks(sourceType string, sourceID, postID, communityID, authorID int64, urls []string) {
+	links := make([]*models.OutboundLink, 0, len(urls))
+	for _, u := range urls {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			continue
+		}
+		links = append(links, &models.OutboundLink{
+			ID:          snowflake.GenID(),
+			SourceType:  sourceType,
+			SourceID:    sourceID,
+			PostID:      postID,
+			CommunityID: communityID,
+			AuthorID:    authorID,
+			URL:         u,
+			Host:        parsed.Hostname(),
+		})
+	}
+	if err := mysql.ReplaceOutboundLinks(sourceType, sourceID, links); err != nil {
+		zap.L().Error("mysql.ReplaceOutboundLinks failed",
+			zap.String("source_type", sourceType), zap.Int64("source_id", sourceID), zap.Error(err))
+	}
+}
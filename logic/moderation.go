@@ -0,0 +1,173 @@
+package logic
+
+import (
+	"errors"
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrorPostLocked        = errors.New("帖子已锁定， 不能评论")
+	ErrorInvalidPostStatus = errors.New("帖子当前的状态不能执行该操作")
+)
+
+// initialPostStatus: 新帖子的状态， 社区开启审核时注册时间太短的用户的帖子需要审核
+func initialPostStatus(userID int64, community *models.Community) (int32, error) {
+	if community.ReviewAccountAgeDays <= 0 {
+		return models.PostStatusPublished, nil
+	}
+	user, err := mysql.GetUserByID(userID)
+	if err != nil {
+		return 0, err
+	}
+	if time.Since(user.CreateTime) < time.Duration(community.ReviewAccountAgeDays)*24*time.Hour {
+		return models.PostStatusPending, nil
+	}
+	return models.PostStatusPublished, nil
+}
+
+// GetModerationQueue: 分页获取社区中待审核的帖子， 先发布的在前面
+func GetModerationQueue(communityID, pageNum, pageSize int64) ([]*models.ApiPostDetail2, error) {
+	posts, err := mysql.GetCommunityPostsByStatus(communityID, models.PostStatusPending, pageNum, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return getPostDetails(posts)
+}
+
+// ApprovePost: 审核通过待审核的帖子， 或者恢复被移除的帖子， 通过之后才会加入redis的列表
+func ApprovePost(userID, postID int64) error {
+	post, err := getModeratedPost(userID, postID)
+	if err != nil {
+		return err
+	}
+	switch post.Status {
+	case models.PostStatusPending:
+		now := time.Now()
+		if err = mysql.UpdatePostStatus(postID, models.PostStatusPublished, "", &now); err != nil {
+			return err
+		}
+		err = redis.CreatePost(post.ID, post.CommunityID, post.AuthorID)
+	case models.PostStatusRemoved, models.PostStatusSpam:
+		// 被移除的帖子不占用链接， 恢复时重新检查链接是否已经被其他帖子使用
+		hash := linkHash(post, models.PostStatusPublished)
+		// 从来没有发布过的帖子按照第一次发布处理
+		if post.PublishedTime == nil {
+			now := time.Now()
+			if err = mysql.RepublishPost(postID, &now, hash); err != nil {
+				return linkError(err)
+			}
+			err = redis.CreatePost(post.ID, post.CommunityID, post.AuthorID)
+			break
+		}
+		if err = mysql.RepublishPost(postID, nil, hash); err != nil {
+			return linkError(err)
+		}
+		err = redis.RestorePost(post.ID, post.CommunityID, post.AuthorID, post.ListedTime())
+	default:
+		return ErrorInvalidPostStatus
+	}
+	if err != nil {
+		return err
+	}
+	logModeration(userID, post, "approve", "")
+	return nil
+}
+
+// RejectPost: 拒绝待审核的帖子， 或者移除已经发布的帖子， 原因会显示给作者
+func RejectPost(userID, postID int64, p *models.ParamRejectPost) error {
+	post, err := getModeratedPost(userID, postID)
+	if err != nil {
+		return err
+	}
+	if post.Status == models.PostStatusDraft {
+		return ErrorInvalidPostStatus
+	}
+	status := models.PostStatusRemoved
+	if p.Spam {
+		status = models.PostStatusSpam
+	}
+	if post.Listed() {
+		// 已经发布的帖子需要从列表中移除， 以前的帖子没有发布时间， 使用创建时间， 恢复时才能放回原来的位置
+		var publishedTime *time.Time
+		if post.PublishedTime == nil {
+			publishedTime = &post.CreateTime
+		}
+		if err = mysql.UpdatePostStatus(postID, status, p.Reason, publishedTime); err != nil {
+			return err
+		}
+		if err = redis.RemovePost(post.ID, post.CommunityID, post.AuthorID, restoreWindow); err != nil {
+			return err
+		}
+	} else if err = mysql.UpdatePostStatus(postID, status, p.Reason, nil); err != nil {
+		return err
+	}
+	logModeration(userID, post, "reject", p.Reason)
+	return nil
+}
+
+// LockPost: 锁定或解锁已经发布的帖子， 锁定之后不能评论， 仍然出现在列表中
+func LockPost(userID, postID int64, locked bool) error {
+	post, err := getModeratedPost(userID, postID)
+	if err != nil {
+		return err
+	}
+	from, to, action := models.PostStatusPublished, models.PostStatusLocked, "lock"
+	if !locked {
+		from, to, action = to, from, "unlock"
+	}
+	if post.Status != from {
+		return ErrorInvalidPostStatus
+	}
+	if err = mysql.UpdatePostStatus(postID, to, "", nil); err != nil {
+		return err
+	}
+	logModeration(userID, post, action, "")
+	return nil
+}
+
+// getModeratedPost: 获取帖子， 同时检查用户是否是帖子所在社区的版主
+func getModeratedPost(userID, postID int64) (*models.Post, error) {
+	post, err := getPost(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err = CheckCommunityPermission(userID, post.CommunityID, PermManagePosts); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// checkViewPost: 没有发布的帖子只有作者和社区的版主可以查看， 草稿只有作者可以查看， 其他人当作不存在
+func checkViewPost(viewerID int64, post *models.Post) error {
+	isAuthor := viewerID != 0 && viewerID == post.AuthorID
+	if post.Status == models.PostStatusDraft && !isAuthor {
+		return mysql.ErrorPostNotExist
+	}
+	if post.Listed() || post.DeletedAt.Valid || isAuthor {
+		return nil
+	}
+	if viewerID == 0 {
+		return mysql.ErrorPostNotExist
+	}
+	err := CheckCommunityPermission(viewerID, post.CommunityID, PermManagePosts)
+	if errors.Is(err, ErrorNotPerm) {
+		return mysql.ErrorPostNotExist
+	}
+	return err
+}
+
+func logModeration(userID int64, post *models.Post, action, reason string) {
+	zap.L().Info("post moderated",
+		zap.String("audit", "moderation"),
+		zap.String("action", action),
+		zap.Int64("moderator_id", userID),
+		zap.Int64("post_id", post.ID),
+		zap.Int64("community_id", post.CommunityID),
+		zap.String("reason", reason),
+	)
+}
@@ -0,0 +1,238 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/oidc"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/snowflake"
+	"github.com/xiaorui/reddit-async/reddit-backend/settings"
+	"go.uber.org/zap"
+)
+
+// OIDCStateExpire: 跳转到第三方登录之后需要在这个时间内回调， 保存state的cookie也使用这个有效期
+const OIDCStateExpire = 10 * time.Minute
+
+const (
+	oidcRequestTimeout   = 10 * time.Second
+	oidcUsernameMinLen   = 3 // 和注册时用户名的要求保持一致
+	oidcUsernameMaxLen   = 20
+	oidcUsernameAttempts = 5
+)
+
+var (
+	ErrorOIDCProviderNotExist = errors.New("不支持该第三方登录")
+	ErrorInvalidOIDCState     = errors.New("第三方登录的state无效或已过期")
+	ErrorOIDCEmailConflict    = errors.New("该邮箱已经注册但没有验证， 请先登录之后再绑定")
+	ErrorOIDCEmailRequired    = errors.New("第三方账号没有验证过的邮箱， 不能创建新用户")
+)
+
+var (
+	oidcMu        sync.Mutex
+	oidcProviders = map[string]*oidc.Provider{}
+)
+
+// getOIDCProvider: 根据名称获取配置的第三方登录提供方， 端点和公钥会缓存在Provider中
+func getOIDCProvider(name string) (*oidc.Provider, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+	if p, ok := oidcProviders[name]; ok {
+		return p, nil
+	}
+	cfg := settings.Conf.OIDCConfig
+	if cfg == nil {
+		return nil, ErrorOIDCProviderNotExist
+	}
+	for _, pc := range cfg.Providers {
+		if pc.Name == name {
+			p := oidc.NewProvider(pc)
+			oidcProviders[name] = p
+			return p, nil
+		}
+	}
+	return nil, ErrorOIDCProviderNotExist
+}
+
+// StartOIDCLogin: 生成跳转到第三方登录页面的地址， 同时保存state、nonce和code_verifier
+// 返回的state需要保存在发起登录的浏览器中， 回调时检查是否一致， 防止登录CSRF
+func StartOIDCLogin(providerName string) (authURL, state string, err error) {
+	provider, err := getOIDCProvider(providerName)
+	if err != nil {
+		return "", "", err
+	}
+	if state, err = oidc.NewRandomString(); err != nil {
+		return "", "", err
+	}
+	s := &models.OIDCLoginState{Provider: providerName}
+	if s.Nonce, err = oidc.NewRandomString(); err != nil {
+		return "", "", err
+	}
+	if s.CodeVerifier, err = oidc.NewRandomString(); err != nil {
+		return "", "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oidcRequestTimeout)
+	defer cancel()
+	authURL, err = provider.AuthCodeURL(ctx, state, s.Nonce, s.CodeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+	if err = redis.SetOIDCState(state, s, OIDCStateExpire); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// LoginUsingOIDC: 第三方登录回调
+// 1. 已经绑定过的第三方账号直接登录
+// 2. 第三方返回的邮箱已经验证， 并且和某个用户验证过的邮箱相同时， 自动绑定到这个用户
+// 3. 否则创建新的用户
+func LoginUsingOIDC(providerName string, p *models.ParamOIDCCallback) (*models.User, error) {
+	// 1. 校验state， 每个state只能使用一次
+	s, err := redis.TakeOIDCState(p.State)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil || s.Provider != providerName {
+		return nil, ErrorInvalidOIDCState
+	}
+	provider, err := getOIDCProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 使用code换取id_token并验证
+	ctx, cancel := context.WithTimeout(context.Background(), oidcRequestTimeout)
+	defer cancel()
+	idToken, err := provider.Exchange(ctx, p.Code, s.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := provider.VerifyIDToken(ctx, idToken, s.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 已经绑定过
+	identity, err := mysql.GetUserIdentity(providerName, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		return mysql.GetUserByID(identity.UserID)
+	}
+
+	// 4. 通过验证过的邮箱绑定已有的用户
+	identity = &models.UserIdentity{Provider: providerName, Subject: claims.Subject, Email: claims.Email}
+	if claims.Email != "" && claims.EmailVerified {
+		user, err := mysql.GetUserByEmail(claims.Email)
+		if err != nil && !errors.Is(err, mysql.ErrorEmailNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			// 本地的邮箱没有验证过时不能确定是同一个人
+			if user.EmailVerifiedAt == nil {
+				return nil, ErrorOIDCEmailConflict
+			}
+			identity.UserID = user.ID
+			if err = mysql.InsertUserIdentity(identity); err != nil {
+				return nil, err
+			}
+			zap.L().Info("oidc identity linked",
+				zap.String("audit", "oidc"),
+				zap.Int64("user_id", user.ID),
+				zap.String("provider", providerName),
+			)
+			return user, nil
+		}
+	}
+
+	// 5. 第一次登录， 创建新用户， 只有开放注册时才可以
+	if RegistrationMode() != settings.RegistrationOpen {
+		return nil, ErrorRegistrationClosed
+	}
+	return provisionOIDCUser(claims, identity)
+}
+
+// provisionOIDCUser: 使用第三方账号的信息创建新用户
+// 密码是随机生成的， 用户之后需要通过邮箱验证码重置密码， 所以第三方账号必须有验证过的邮箱
+// 否则用户不知道密码， 也没有可以接收验证码的联系方式， 既不能设置密码也不能注销账号
+func provisionOIDCUser(claims *oidc.Claims, identity *models.UserIdentity) (*models.User, error) {
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrorOIDCEmailRequired
+	}
+	randomPassword, err := oidc.NewRandomString()
+	if err != nil {
+		return nil, err
+	}
+	username, err := oidcUsername(claims)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	user := &models.User{
+		ID:              snowflake.GenID(),
+		Username:        username,
+		Password:        randomPassword,
+		Email:           claims.Email,
+		EmailVerifiedAt: &now,
+	}
+	if err = mysql.InsertUserWithIdentity(user, identity); err != nil {
+		return nil, err
+	}
+	zap.L().Info("oidc user provisioned",
+		zap.String("audit", "oidc"),
+		zap.Int64("user_id", user.ID),
+		zap.String("provider", identity.Provider),
+	)
+	return user, nil
+}
+
+// oidcUsername: 根据第三方账号的信息生成用户名， 只保留字母和数字， 重复时加上随机数字
+func oidcUsername(claims *oidc.Claims) (string, error) {
+	base := ""
+	candidates := []string{claims.PreferredUsername, claims.Name, strings.SplitN(claims.Email, "@", 2)[0]}
+	for _, c := range candidates {
+		if base = sanitizeUsername(c); len(base) >= oidcUsernameMinLen {
+			break
+		}
+	}
+	if len(base) < oidcUsernameMinLen {
+		base = "user"
+	}
+	if len(base) > oidcUsernameMaxLen-5 {
+		base = base[:oidcUsernameMaxLen-5]
+	}
+
+	name := base
+	for i := 0; i < oidcUsernameAttempts; i++ {
+		err := mysql.CheckUserExist(name)
+		if err == nil {
+			return name, nil
+		}
+		if !errors.Is(err, mysql.ErrorUserExist) {
+			return "", err
+		}
+		name = fmt.Sprintf("%s%04d", base, rand.Intn(10000))
+	}
+	return "", mysql.ErrorUserExist
+}
+
+func sanitizeUsername(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
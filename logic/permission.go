@@ -0,0 +1,68 @@
+package logic
+
+import (
+	"errors"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"gorm.io/gorm"
+)
+
+var ErrorInvalidRole = errors.New("无效的角色")
+
+// Permission: 需要鉴权的操作
+type Permission int
+
+const (
+	PermUpdateCommunity        Permission = iota + 1 // 修改社区信息
+	PermDeleteCommunity                              // 删除社区
+	PermManageCommunityMembers                       // 任免社区的版主
+	PermViewPostRevisions                            // 查看帖子的编辑记录
+	PermManagePosts                                  // 删除和恢复其他人的帖子和评论
+)
+
+// communityPermissionRole: 每个操作需要的最低社区角色， 站点管理员拥有所有权限
+var communityPermissionRole = map[Permission]string{
+	PermUpdateCommunity:        models.CommunityRoleModerator,
+	PermDeleteCommunity:        models.CommunityRoleOwner,
+	PermManageCommunityMembers: models.CommunityRoleOwner,
+	PermViewPostRevisions:      models.CommunityRoleModerator,
+	PermManagePosts:            models.CommunityRoleModerator,
+}
+
+// IsAdmin: 判断用户是否是站点管理员
+func IsAdmin(userID int64) (bool, error) {
+	user, err := mysql.GetUserByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return user.Role == models.RoleAdmin, nil
+}
+
+// CheckCommunityPermission: 检查用户是否可以对社区执行某个操作， 没有权限时返回ErrorNotPerm
+func CheckCommunityPermission(userID, communityID int64, perm Permission) error {
+	required, ok := communityPermissionRole[perm]
+	if !ok {
+		return ErrorNotPerm
+	}
+	// 1. 站点管理员可以管理所有社区
+	admin, err := IsAdmin(userID)
+	if err != nil {
+		return err
+	}
+	if admin {
+		return nil
+	}
+	// 2. 其他用户按照在社区中的角色判断
+	role, err := mysql.GetCommunityRole(communityID, userID)
+	if err != nil {
+		return err
+	}
+	if models.CommunityRoleLevel(role) < models.CommunityRoleLevel(required) {
+		return ErrorNotPerm
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package logic
 
 import (
 	"errors"
+	"time"
 
 	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
 	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
@@ -15,12 +16,62 @@ var (
 )
 
 func CreatePost(p *models.Post) (err error) {
+	// 检查社区的发帖门槛
+	community, err := mysql.GetCommunityDetailByID(p.CommunityID)
+	if err != nil {
+		return
+	}
+	if err = checkPostRequirements(p.AuthorID, community); err != nil {
+		return
+	}
+
 	//1. 生成post id
 	p.ID = snowflake.GenID()
+	p.RemovalReason = ""
+	p.EditedTime = nil
+	// 检查链接、图片和投票等不同类型的内容
+	if err = preparePostKind(p); err != nil {
+		return
+	}
+	// 提及的用户屏蔽了作者时不能发布
+	links, err := checkContent(p.AuthorID, p.Content)
+	if err != nil {
+		return
+	}
+	// 保存为草稿， 或者定时发布
+	if p.Draft || p.PublishAt != nil {
+		if p.PublishAt != nil {
+			if err = checkPublishAt(*p.PublishAt); err != nil {
+				return
+			}
+		}
+		p.Status = models.PostStatusDraft
+		if err = mysql.CreatePost(p); err != nil {
+			return linkError(err)
+		}
+		savePostLinks(p, links)
+		return renderPosts([]*models.Post{p})
+	}
+	// 状态由社区的审核设置决定， 不使用请求中的值
+	if p.Status, err = initialPostStatus(p.AuthorID, community); err != nil {
+		return
+	}
+	if p.Status == models.PostStatusPublished {
+		now := time.Now()
+		p.PublishedTime = &now
+	}
 
 	//2. 将数据保存到数据库, 这里还需要再redis中加入post的记录， 当前post创建的时间
 	err = mysql.CreatePost(p)
 	if err != nil {
+		return linkError(err)
+	}
+	savePostLinks(p, links)
+	if err = renderPosts([]*models.Post{p}); err != nil {
+		return
+	}
+	// 待审核的帖子审核通过之后才加入redis
+	if p.Status == models.PostStatusPending {
 		return
 	}
 
@@ -31,21 +82,44 @@ func CreatePost(p *models.Post) (err error) {
 	return
 }
 
-func GetPostByID(pid int64) (data *models.ApiPostDetail, err error) {
+// GetPostByID: 获取帖子详情， 没有发布的帖子只有作者和版主可以查看， 没有登录时viewerID为0
+func GetPostByID(viewerID, pid int64) (data *models.ApiPostDetail, err error) {
 	//就是从mysql中去获取数据
 	//不只需要post的信息， 还需要community的信息，还需要author的信息
 	//1. 先获取post， 才能获取author， 才能获取community
-	post, err := mysql.GetPostByID(pid)
+	post, err := mysql.GetPostByIDUnscoped(pid)
 	if err != nil {
 		zap.L().Error("GetPostByID mysql.GetPostByID failed.", zap.Error(err))
 		return nil, err
 	}
+	if err = checkViewPost(viewerID, post); err != nil {
+		return nil, err
+	}
+	// 已经删除的帖子显示为占位符， 评论仍然可以查看
+	if post.DeletedAt.Valid {
+		community, err := mysql.GetCommunityDetailByID(post.CommunityID)
+		if err != nil {
+			return nil, err
+		}
+		placeholder := deletedPost(post)
+		if err = renderPosts([]*models.Post{placeholder}); err != nil {
+			return nil, err
+		}
+		return &models.ApiPostDetail{AuthorName: models.DeletedPlaceholder, Post: placeholder, Community: community}, nil
+	}
 	//2. 获取authorname
 	user, err := mysql.GetUserByID(post.AuthorID)
 	if err != nil {
 		zap.L().Error("GetPostByID mysql.GetUserByID failed.", zap.Error(err))
 		return nil, err
 	}
+	// 图片和投票
+	if err = loadPostPayloads(viewerID, []*models.Post{post}); err != nil {
+		return nil, err
+	}
+	if err = renderPosts([]*models.Post{post}); err != nil {
+		return nil, err
+	}
 
 	//3. 获取community信息
 	community, err := mysql.GetCommunityDetailByID(post.CommunityID)
@@ -68,6 +142,9 @@ func GetPostList(pageNum, pageSize int64) (data []*models.ApiPostDetail, err err
 	if err != nil {
 		return nil, err
 	}
+	if err = renderPosts(posts); err != nil {
+		return nil, err
+	}
 
 	data = make([]*models.ApiPostDetail, 0, len(posts))
 	for _, post := range posts {
@@ -108,46 +185,12 @@ func GetPostList2(p *models.ParamPostList) (data []*models.ApiPostDetail2, err e
 		return
 	}
 
-	//从redis中去获取这些pids的票数
-	votes, err := redis.GetVotesByPostIDS(pidList)
-	if err != nil {
-		return nil, err
-	}
-
-	//2. 根据列表从数据库中得到post的详细信息
+	//2. 根据列表从数据库中得到post的详细信息， 已经删除的帖子查不到， 票数按照查到的帖子获取， 避免错位
 	posts, err := mysql.GetPostListByIDs(pidList)
 	if err != nil {
 		return nil, err
 	}
-
-	//就是说希望在这里传入用户对于每个帖子的投票情况， 应该在结构体中加入一个结构信息， 即投票
-	//3. 根据获取到的post的详细信息去mysql中查询community和user的详细信息
-	data = make([]*models.ApiPostDetail2, 0, len(posts))
-	for idx, post := range posts {
-		//2. 获取authorname
-		user, err := mysql.GetUserByID(post.AuthorID)
-		if err != nil {
-			zap.L().Error("GetPostByID mysql.GetUserByID failed.", zap.Error(err))
-			continue
-		}
-
-		//3. 获取community信息
-		community, err := mysql.GetCommunityDetailByID(post.CommunityID)
-		if err != nil {
-			zap.L().Error("GetPostByID mysql.GetCommunityDetailByID failed.", zap.Error(err))
-			continue
-		}
-
-		postDetail := &models.ApiPostDetail2{
-			user.Username,
-			votes[idx],
-			post,
-			community,
-		}
-		data = append(data, postDetail)
-	}
-
-	return
+	return getPostDetails(posts)
 }
 
 // 这个函数的主要目的就是加上communityid， 也就是说获取pid的这里的方式需要有community的参与
@@ -164,48 +207,16 @@ func GetCommunityPostList(p *models.ParamPostList) (data []*models.ApiPostDetail
 		return
 	}
 
-	//从redis中去获取这些pids的票数
-	votes, err := redis.GetVotesByPostIDS(pidList)
-	if err != nil {
-		return nil, err
-	}
-	//2. 根据列表从数据库中得到post的详细信息
+	//2. 根据列表从数据库中得到post的详细信息， 已经删除的帖子查不到， 票数按照查到的帖子获取， 避免错位
 	posts, err := mysql.GetPostListByIDs(pidList)
 	if err != nil {
 		return nil, err
 	}
-
-	//就是说希望在这里传入用户对于每个帖子的投票情况， 应该在结构体中加入一个结构信息， 即投票
-	//3. 根据获取到的post的详细信息去mysql中查询community和user的详细信息
-	data = make([]*models.ApiPostDetail2, 0, len(posts))
-	for idx, post := range posts {
-		//2. 获取authorname
-		user, err := mysql.GetUserByID(post.AuthorID)
-		if err != nil {
-			zap.L().Error("GetPostByID mysql.GetUserByID failed.", zap.Error(err))
-			continue
-		}
-
-		//3. 获取community信息
-		community, err := mysql.GetCommunityDetailByID(post.CommunityID)
-		if err != nil {
-			zap.L().Error("GetPostByID mysql.GetCommunityDetailByID failed.", zap.Error(err))
-			continue
-		}
-
-		postDetail := &models.ApiPostDetail2{
-			user.Username,
-			votes[idx],
-			post,
-			community,
-		}
-		data = append(data, postDetail)
-	}
-
-	return
+	return getPostDetails(posts)
 }
 
-func GetPostList0(p *models.ParamPostList) (data []*models.ApiPostDetail2, err error) {
+// GetPostList0: 获取帖子列表， 会去掉viewer屏蔽或静音的人发布的帖子， 没有登录时viewerID为0
+func GetPostList0(viewerID int64, p *models.ParamPostList) (data []*models.ApiPostDetail2, err error) {
 	if p.CommunityID == 0 {
 		data, err = GetPostList2(p)
 	} else {
@@ -215,21 +226,27 @@ func GetPostList0(p *models.ParamPostList) (data []*models.ApiPostDetail2, err e
 		zap.L().Error("GetPostList0 failed.", zap.Error(err))
 		return nil, err
 	}
-	return
+	return filterBlockedPosts(viewerID, data)
 }
 
-// DeletePost: 删除post
+// DeletePost: 删除帖子， 作者和社区的版主可以删除
+// 帖子只是标记为删除， 同时从redis的列表中移除， 在恢复期限内可以恢复
 func DeletePost(postID, userID int64) error {
-	// 1. 查询post
-	post, err := mysql.GetPostByID(postID)
+	post, err := getPost(postID)
 	if err != nil {
 		return err
 	}
-
-	// 2. 执行删除操作
-	if err := mysql.DeletePost(post, userID); err != nil {
+	if post.AuthorID != userID {
+		if err = CheckCommunityPermission(userID, post.CommunityID, PermManagePosts); err != nil {
+			return err
+		}
+	}
+	if err = mysql.SoftDeletePost(postID, userID, time.Now()); err != nil {
 		return err
 	}
-
-	return nil
+	// 没有发布的帖子不在redis的列表中
+	if !post.Listed() {
+		return nil
+	}
+	return redis.RemovePost(post.ID, post.CommunityID, post.AuthorID, restoreWindow)
 }
@@ -0,0 +1,291 @@
+package logic
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/file"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/snowflake"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/urlnorm"
+	"go.uber.org/zap"
+)
+
+const (
+	maxPostImages    = 20
+	minPollOptions   = 2
+	maxPollOptions   = 10
+	maxPollOptionLen = 100
+	maxPollDuration  = 90 * 24 * time.Hour
+
+	unattachedImageExpire     = 24 * time.Hour // 上传之后超过这个时间还没有关联到帖子的图片会被删除
+	postImageCleanupInterval  = time.Hour
+	postImageCleanupBatchSize = 100
+)
+
+var (
+	ErrorInvalidPostKind   = errors.New("不支持的帖子类型")
+	ErrorDuplicateLink     = errors.New("该链接已经在社区中发布过")
+	ErrorInvalidImages     = errors.New("图片帖子需要1~20张图片")
+	ErrorInvalidPoll       = errors.New("投票需要2~10个不重复的选项， 截止时间需要在未来90天之内")
+	ErrorInvalidPollOption = errors.New("无效的投票选项")
+	ErrorPollClosed        = errors.New("投票已经截止")
+	ErrorPollVoted         = errors.New("已经投过票了")
+)
+
+// CreatePostImage: 保存上传的图片， 创建图片帖子时使用返回的图片id
+func CreatePostImage(userID int64, path string, width, height int) (*models.PostImage, error) {
+	img := &models.PostImage{
+		ID:         snowflake.GenID(),
+		UploaderID: userID,
+		Path:       path,
+		Width:      width,
+		Height:     height,
+	}
+	if err := mysql.InsertPostImage(img); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// RunPostImageCleanup: 定期删除上传之后没有使用的图片， 需要在单独的goroutine中运行
+func RunPostImageCleanup() {
+	ticker := time.NewTicker(postImageCleanupInterval)
+	defer ticker.Stop()
+	for {
+		cleanupUnattachedImages()
+		<-ticker.C
+	}
+}
+
+// cleanupUnattachedImages: 先删除数据库中的记录再删除文件， 删除记录时图片已经被帖子使用的不会删除文件
+func cleanupUnattachedImages() {
+	for {
+		images, err := mysql.GetUnattachedPostImages(time.Now().Add(-unattachedImageExpire), postImageCleanupBatchSize)
+		if err != nil {
+			zap.L().Error("mysql.GetUnattachedPostImages failed", zap.Error(err))
+			return
+		}
+		for _, img := range images {
+			deleted, err := mysql.DeleteUnattachedPostImage(img.ID)
+			if err != nil {
+				zap.L().Error("mysql.DeleteUnattachedPostImage failed", zap.Int64("image_id", img.ID), zap.Error(err))
+				return
+			}
+			if !deleted {
+				continue
+			}
+			if err := file.DeletePostImage(img.Path); err != nil {
+				zap.L().Error("file.DeletePostImage failed", zap.String("path", img.Path), zap.Error(err))
+			}
+		}
+		if len(images) < postImageCleanupBatchSize {
+			return
+		}
+	}
+}
+
+// preparePostKind: 检查不同类型的帖子的内容， 不使用请求中和类型无关的字段
+func preparePostKind(p *models.Post) error {
+	rawURL, poll := p.URL, p.Poll
+	p.URL, p.URLHash, p.Images, p.Poll = "", nil, nil, nil
+	switch p.Kind {
+	case "", models.PostKindText:
+		p.Kind = models.PostKindText
+		return nil
+	case models.PostKindLink:
+		return prepareLinkPost(p, rawURL)
+	case models.PostKindImage:
+		return prepareImagePost(p)
+	case models.PostKindPoll:
+		return preparePollPost(p, poll)
+	}
+	return ErrorInvalidPostKind
+}
+
+// prepareLinkPost: 规范化链接地址， 同一个社区中不能重复发布相同的链接， 保存帖子时检查
+func prepareLinkPost(p *models.Post, rawURL string) error {
+	u, err := urlnorm.Normalize(rawURL)
+	if err != nil {
+		return err
+	}
+	p.URL = u
+	p.URLHash = urlHash(u)
+	return nil
+}
+
+func urlHash(u string) *string {
+	sum := sha1.Sum([]byte(u))
+	hash := hex.EncodeToString(sum[:])
+	return &hash
+}
+
+// linkHash: 帖子恢复成status之后占用链接时保存的url_hash， 不是链接帖子和被移除的帖子返回nil
+// 重复的链接由数据库的唯一索引检查， 保存时返回mysql.ErrorLinkExist
+func linkHash(p *models.Post, status int32) *string {
+	if p.Kind != models.PostKindLink || status == models.PostStatusRemoved || status == models.PostStatusSpam {
+		return nil
+	}
+	return urlHash(p.URL)
+}
+
+// linkError: 把数据库中链接重复的错误转换成ErrorDuplicateLink
+func linkError(err error) error {
+	if errors.Is(err, mysql.ErrorLinkExist) {
+		return ErrorDuplicateLink
+	}
+	return err
+}
+
+func prepareImagePost(p *models.Post) error {
+	if len(p.ImageIDs) == 0 || len(p.ImageIDs) > maxPostImages {
+		return ErrorInvalidImages
+	}
+	seen := make(map[int64]bool, len(p.ImageIDs))
+	for _, s := range p.ImageIDs {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || seen[id] {
+			return ErrorInvalidImages
+		}
+		seen[id] = true
+		p.Images = append(p.Images, &models.PostImage{ID: id})
+	}
+	p.ImageIDs = nil
+	return nil
+}
+
+func preparePollPost(p *models.Post, poll *models.Poll) error {
+	if poll == nil || len(poll.Options) < minPollOptions || len(poll.Options) > maxPollOptions {
+		return ErrorInvalidPoll
+	}
+	// 定时发布的帖子， 截止时间需要在发布时间之后
+	start := time.Now()
+	if p.PublishAt != nil {
+		start = *p.PublishAt
+	}
+	if d := poll.CloseTime.Sub(start); d <= 0 || d > maxPollDuration {
+		return ErrorInvalidPoll
+	}
+	options := make([]*models.PollOption, 0, len(poll.Options))
+	seen := make(map[string]bool, len(poll.Options))
+	for _, option := range poll.Options {
+		text := strings.TrimSpace(option.Text)
+		if text == "" || utf8.RuneCountInString(text) > maxPollOptionLen || seen[text] {
+			return ErrorInvalidPoll
+		}
+		seen[text] = true
+		options = append(options, &models.PollOption{Text: text})
+	}
+	p.Poll = &models.Poll{Multiple: poll.Multiple, CloseTime: poll.CloseTime, Options: options}
+	return nil
+}
+
+// VotePoll: 投票帖子投票， 每个用户只能投一次， 截止之后不能再投票
+func VotePoll(userID, postID int64, p *models.ParamVotePoll) error {
+	post, err := getPost(postID)
+	if err != nil {
+		return err
+	}
+	if !post.Listed() {
+		return mysql.ErrorPostNotExist
+	}
+	if post.Kind != models.PostKindPoll {
+		return ErrorInvalidPostKind
+	}
+	if err = checkNotBlocked(userID, post.AuthorID); err != nil {
+		return err
+	}
+	polls, err := mysql.GetPolls([]int64{postID})
+	if err != nil {
+		return err
+	}
+	if len(polls) == 0 {
+		return ErrorInvalidPostKind
+	}
+	poll := polls[0]
+	if !time.Now().Before(poll.CloseTime) {
+		return ErrorPollClosed
+	}
+	if len(p.Options) == 0 || (!poll.Multiple && len(p.Options) > 1) {
+		return ErrorInvalidPollOption
+	}
+	seen := make(map[int]bool, len(p.Options))
+	for _, option := range p.Options {
+		if option < 0 || option >= len(poll.Options) || seen[option] {
+			return ErrorInvalidPollOption
+		}
+		seen[option] = true
+	}
+	ok, err := redis.VotePoll(postID, userID, p.Options)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrorPollVoted
+	}
+	return nil
+}
+
+// loadPostPayloads: 补充图片帖子的图片和投票帖子的选项、票数， viewerID不为0时同时返回用户选择的选项
+func loadPostPayloads(viewerID int64, posts []*models.Post) error {
+	var imagePosts, pollPosts []int64
+	byID := make(map[int64]*models.Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+		switch post.Kind {
+		case models.PostKindImage:
+			imagePosts = append(imagePosts, post.ID)
+		case models.PostKindPoll:
+			pollPosts = append(pollPosts, post.ID)
+		}
+	}
+
+	if len(imagePosts) > 0 {
+		images, err := mysql.GetPostImages(imagePosts)
+		if err != nil {
+			return err
+		}
+		for _, img := range images {
+			post := byID[img.PostID]
+			post.Images = append(post.Images, img)
+		}
+	}
+
+	if len(pollPosts) == 0 {
+		return nil
+	}
+	polls, err := mysql.GetPolls(pollPosts)
+	if err != nil {
+		return err
+	}
+	ids := make([]int64, 0, len(polls))
+	for _, poll := range polls {
+		ids = append(ids, poll.PostID)
+	}
+	counts, voters, err := redis.GetPollResults(ids)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for i, poll := range polls {
+		for _, option := range poll.Options {
+			option.Votes = counts[i][option.Position]
+		}
+		poll.Voters = voters[i]
+		poll.Closed = !now.Before(poll.CloseTime)
+		if viewerID != 0 {
+			if poll.Voted, err = redis.GetPollVote(poll.PostID, viewerID); err != nil {
+				return err
+			}
+		}
+		byID[poll.PostID].Poll = poll
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package logic
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrorVotesHidden = errors.New("该用户隐藏了点赞记录")
+
+// GetPublicProfileByID: 根据用户id获取公开的个人信息
+func GetPublicProfileByID(userID int64) (*models.PublicProfile, error) {
+	user, err := getActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return toPublicProfile(user)
+}
+
+// GetPublicProfileByName: 根据用户名获取公开的个人信息
+func GetPublicProfileByName(username string) (*models.PublicProfile, error) {
+	user, err := mysql.GetUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedTime != nil {
+		return nil, mysql.ErrorUserNotExist
+	}
+	return toPublicProfile(user)
+}
+
+// GetUserPosts: 分页获取用户发布的帖子， 作者本人可以看到待审核和被移除的帖子以及移除的原因
+func GetUserPosts(viewerID, userID, pageNum, pageSize int64) ([]*models.ApiPostDetail2, error) {
+	if _, err := getActiveUser(userID); err != nil {
+		return nil, err
+	}
+	posts, err := mysql.GetPostsByAuthorPage(userID, viewerID != userID, pageNum, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return getPostDetails(posts)
+}
+
+// GetUserComments: 分页获取用户发布的评论
+func GetUserComments(userID, pageNum, pageSize int64) ([]*models.Comment, error) {
+	if _, err := getActiveUser(userID); err != nil {
+		return nil, err
+	}
+	comments, err := mysql.GetCommentsByAuthorPage(userID, pageNum, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return comments, renderComments(comments)
+}
+
+// GetUserUpvotedPosts: 分页获取用户点赞过的帖子， 用户隐藏了点赞记录时只有自己可以查看
+func GetUserUpvotedPosts(viewerID, userID, pageNum, pageSize int64) ([]*models.ApiPostDetail2, error) {
+	user, err := getActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.HideVotes && viewerID != userID {
+		return nil, ErrorVotesHidden
+	}
+	pids, err := redis.GetUserUpvotedPostIDs(userID, pageNum, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(pids) == 0 {
+		return []*models.ApiPostDetail2{}, nil
+	}
+	// 已经删除的帖子在数据库中查不到， 直接跳过
+	posts, err := mysql.GetPostListByIDs(pids)
+	if err != nil {
+		return nil, err
+	}
+	return getPostDetails(posts)
+}
+
+// UpdatePrivacy: 修改隐私设置
+func UpdatePrivacy(userID int64, p *models.ParamPrivacy) error {
+	return mysql.UpdatePrivacy(userID, p.HideVotes)
+}
+
+// getActiveUser: 获取没有注销的用户， 已经匿名化的用户当作不存在
+func getActiveUser(userID int64) (*models.User, error) {
+	user, err := mysql.GetUserByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, mysql.ErrorUserNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedTime != nil {
+		return nil, mysql.ErrorUserNotExist
+	}
+	return user, nil
+}
+
+func toPublicProfile(user *models.User) (*models.PublicProfile, error) {
+	postKarma, commentKarma, err := redis.GetUserKarma(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	followers, following, err := redis.GetFollowCounts(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PublicProfile{
+		ID:           user.ID,
+		Username:     user.Username,
+		Avatar:       user.Avatar,
+		City:         user.City,
+		Introduction: user.Introduction,
+		JoinedAt:     user.CreateTime,
+		Karma:        postKarma + commentKarma,
+		PostKarma:    postKarma,
+		CommentKarma: commentKarma,
+		Followers:    followers,
+		Following:    following,
+		HideVotes:    user.HideVotes,
+	}, nil
+}
+
+// getPostDetails: 补充帖子的作者名称、社区信息、点赞数、图片和投票以及渲染之后的内容， 同一个作者和社区只查询一次
+func getPostDetails(posts []*models.Post) ([]*models.ApiPostDetail2, error) {
+	data := make([]*models.ApiPostDetail2, 0, len(posts))
+	if len(posts) == 0 {
+		return data, nil
+	}
+	pids := make([]string, 0, len(posts))
+	for _, post := range posts {
+		pids = append(pids, strconv.FormatInt(post.ID, 10))
+	}
+	votes, err := redis.GetVotesByPostIDS(pids)
+	if err != nil {
+		return nil, err
+	}
+	if err = loadPostPayloads(0, posts); err != nil {
+		return nil, err
+	}
+	if err = renderPosts(posts); err != nil {
+		return nil, err
+	}
+
+	authors := make(map[int64]string)
+	communities := make(map[int64]*models.Community)
+	for idx, post := range posts {
+		name, ok := authors[post.AuthorID]
+		if !ok {
+			user, err := mysql.GetUserByID(post.AuthorID)
+			if err != nil {
+				zap.L().Error("getPostDetails mysql.GetUserByID failed.", zap.Error(err))
+				continue
+			}
+			name = user.Username
+			authors[post.AuthorID] = name
+		}
+		community, ok := communities[post.CommunityID]
+		if !ok {
+			community, err = mysql.GetCommunityDetailByID(post.CommunityID)
+			if err != nil {
+				zap.L().Error("getPostDetails mysql.GetCommunityDetailByID failed.", zap.Error(err))
+				continue
+			}
+			communities[post.CommunityID] = community
+		}
+		data = append(data, &models.ApiPostDetail2{
+			AuthorName: name,
+			VoteNum:    votes[idx],
+			Post:       post,
+			Community:  community,
+		})
+	}
+	return data, nil
+}
@@ -0,0 +1,245 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+/*
+	防止暴力破解和验证码轰炸:
+	1. 登录: 按账号和IP统计失败次数， 超过免费次数之后每次失败都需要等待更长的时间， 达到上限之后暂时锁定
+	2. 发送验证码: 按接收目标(手机号/邮箱)和IP统计发送次数
+	3. 两步验证: 按用户统计验证码错误的次数， 重新登录换一个挑战也不会重新计数
+*/
+
+const (
+	loginFailureWindow      = 15 * time.Minute // 统计登录失败次数的窗口
+	loginFreeAttempts       = 3                // 不需要等待的失败次数
+	loginMaxDelay           = time.Minute      // 两次尝试之间的最长等待时间
+	loginAccountMaxFailures = 10               // 账号在窗口内失败这么多次之后锁定
+	loginIPMaxFailures      = 50               // IP在窗口内失败这么多次之后锁定
+	loginLockoutDuration    = 15 * time.Minute
+	twoFactorMaxFailures    = 10 // 用户在窗口内两步验证码错误这么多次之后锁定
+
+	codeTargetInterval    = time.Minute // 同一个手机号/邮箱两次发送的最小间隔
+	codeTargetHourlyLimit = 5           // 同一个手机号/邮箱每小时最多发送的次数
+	codeIPHourlyLimit     = 20          // 同一个IP每小时最多发送的次数
+)
+
+// RateLimitError: 请求过于频繁， RetryAfter之后才能重试
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("请求过于频繁， 请%d秒后重试", e.Seconds())
+}
+
+// Seconds: 需要等待的秒数， 向上取整
+func (e *RateLimitError) Seconds() int64 {
+	return int64(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+func loginAccountKey(account string) string {
+	return "login:account:" + strings.ToLower(strings.TrimSpace(account))
+}
+
+func loginIPKey(ip string) string {
+	return "login:ip:" + ip
+}
+
+func loginDelayKey(account string) string {
+	return "login:delay:" + strings.ToLower(strings.TrimSpace(account))
+}
+
+// loginUserAccount: 已经存在的账号按照用户id统计失败次数， 使用用户名、邮箱和手机号码登录时共享同一个计数
+// 用户名只能是字母和数字， 不会和这个格式冲突
+func loginUserAccount(userID int64) string {
+	return "uid:" + strconv.FormatInt(userID, 10)
+}
+
+// passwordLogin: 使用密码登录的公共流程， login查询用户并校验密码， 查询到用户时会填充user
+// 不存在的账号按照输入的标识统计失败次数， 存在的账号按照用户id统计， 换用其他标识登录不能增加尝试次数
+func passwordLogin(identifier, ip string, user *models.User, login func(user *models.User) error) error {
+	if err := checkLoginAllowed(identifier, ip); err != nil {
+		return err
+	}
+	err := login(user)
+	if err != nil && !isLoginFailure(err) {
+		return err
+	}
+	if user.ID == 0 {
+		// 账号不存在
+		recordLoginFailure(identifier, ip)
+		return err
+	}
+	account := loginUserAccount(user.ID)
+	// 账号被锁定时不管密码是否正确都不允许登录， 也不会泄露密码是否正确
+	if lerr := checkLoginAllowed(account, ip); lerr != nil {
+		return lerr
+	}
+	if err != nil {
+		recordLoginFailure(account, ip)
+		return err
+	}
+	resetLoginFailures(account)
+	return nil
+}
+
+// checkLoginAllowed: 登录之前检查账号和IP是否被锁定， 或者还在等待时间内
+func checkLoginAllowed(account, ip string) error {
+	for _, key := range []string{loginAccountKey(account), loginIPKey(ip), loginDelayKey(account)} {
+		ttl, err := redis.GetLockout(key)
+		if err != nil {
+			return err
+		}
+		if ttl > 0 {
+			return &RateLimitError{RetryAfter: ttl}
+		}
+	}
+	return nil
+}
+
+// recordLoginFailure: 记录一次登录失败， 根据失败次数设置等待时间或者锁定
+func recordLoginFailure(account, ip string) {
+	// 1. 账号维度
+	failures, err := redis.SlidingWindowAdd(loginAccountKey(account), loginFailureWindow)
+	if err != nil {
+		zap.L().Error("redis.SlidingWindowAdd failed", zap.Error(err))
+		return
+	}
+	if failures >= loginAccountMaxFailures {
+		lockout(loginAccountKey(account), "account", account, ip, failures)
+	} else if failures > loginFreeAttempts {
+		// 每多失败一次， 等待时间翻倍
+		delay := time.Second << uint(failures-loginFreeAttempts-1)
+		if delay > loginMaxDelay {
+			delay = loginMaxDelay
+		}
+		if err := redis.SetLockout(loginDelayKey(account), delay); err != nil {
+			zap.L().Error("redis.SetLockout failed", zap.Error(err))
+		}
+	}
+
+	// 2. IP维度， 防止同一个IP尝试大量不同的账号
+	failures, err = redis.SlidingWindowAdd(loginIPKey(ip), loginFailureWindow)
+	if err != nil {
+		zap.L().Error("redis.SlidingWindowAdd failed", zap.Error(err))
+		return
+	}
+	if failures >= loginIPMaxFailures {
+		lockout(loginIPKey(ip), "ip", account, ip, failures)
+	}
+}
+
+// isLoginFailure: 账号不存在和密码错误都算作一次失败
+func isLoginFailure(err error) bool {
+	return errors.Is(err, mysql.ErrorPasswordInvalid) ||
+		errors.Is(err, mysql.ErrorUserNotExist) ||
+		errors.Is(err, mysql.ErrorEmailNotExist)
+}
+
+// resetLoginFailures: 登录成功之后清空账号的失败记录， IP的记录保留
+func resetLoginFailures(account string) {
+	if err := redis.SlidingWindowReset(loginAccountKey(account)); err != nil {
+		zap.L().Error("redis.SlidingWindowReset failed", zap.Error(err))
+	}
+	if err := redis.DelLockout(loginDelayKey(account)); err != nil {
+		zap.L().Error("redis.DelLockout failed", zap.Error(err))
+	}
+}
+
+// lockout: 锁定账号或者IP， 并且记录审计日志
+func lockout(key, scope, account, ip string, failures int64) {
+	if err := redis.SetLockout(key, loginLockoutDuration); err != nil {
+		zap.L().Error("redis.SetLockout failed", zap.Error(err))
+		return
+	}
+	// 锁定之后重新计数， 解锁之后可以再尝试
+	if err := redis.SlidingWindowReset(key); err != nil {
+		zap.L().Error("redis.SlidingWindowReset failed", zap.Error(err))
+	}
+	zap.L().Warn("login locked out",
+		zap.String("audit", "lockout"),
+		zap.String("scope", scope),
+		zap.String("account", account),
+		zap.String("ip", ip),
+		zap.Int64("failures", failures),
+		zap.Duration("duration", loginLockoutDuration),
+	)
+}
+
+func twoFactorKey(userID int64) string {
+	return "login:2fa:" + strconv.FormatInt(userID, 10)
+}
+
+// checkTwoFactorAllowed: 校验两步验证码之前检查用户是否被锁定
+func checkTwoFactorAllowed(userID int64) error {
+	ttl, err := redis.GetLockout(twoFactorKey(userID))
+	if err != nil {
+		return err
+	}
+	if ttl > 0 {
+		return &RateLimitError{RetryAfter: ttl}
+	}
+	return nil
+}
+
+// recordTwoFactorFailure: 记录一次两步验证码错误， 所有登录挑战共享同一个计数
+func recordTwoFactorFailure(userID int64, ip string) {
+	key := twoFactorKey(userID)
+	failures, err := redis.SlidingWindowAdd(key, loginFailureWindow)
+	if err != nil {
+		zap.L().Error("redis.SlidingWindowAdd failed", zap.Error(err))
+		return
+	}
+	if failures >= twoFactorMaxFailures {
+		lockout(key, "2fa", loginUserAccount(userID), ip, failures)
+	}
+}
+
+// resetTwoFactorFailures: 两步验证通过之后清空失败记录
+func resetTwoFactorFailures(userID int64) {
+	if err := redis.SlidingWindowReset(twoFactorKey(userID)); err != nil {
+		zap.L().Error("redis.SlidingWindowReset failed", zap.Error(err))
+	}
+}
+
+// checkSendCodeAllowed: 发送验证码之前检查发送频率
+func checkSendCodeAllowed(target, ip string) error {
+	limits := []struct {
+		key    string
+		limit  int64
+		window time.Duration
+	}{
+		{"code:ip:" + ip, codeIPHourlyLimit, time.Hour},
+		{"code:target:interval:" + target, 1, codeTargetInterval},
+		{"code:target:hourly:" + target, codeTargetHourlyLimit, time.Hour},
+	}
+	for _, l := range limits {
+		allowed, retryAfter, err := redis.SlidingWindowAllow(l.key, l.limit, l.window)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			zap.L().Warn("send code rate limited",
+				zap.String("audit", "rate_limit"),
+				zap.String("target", target),
+				zap.String("ip", ip),
+				zap.String("key", l.key),
+				zap.Duration("retry_after", retryAfter),
+			)
+			return &RateLimitError{RetryAfter: retryAfter}
+		}
+	}
+	return nil
+}
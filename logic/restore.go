@@ -0,0 +1,127 @@
+package logic
+
+import (
+	"errors"
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
+)
+
+// restoreWindow: 删除之后可以恢复的期限， 超过之后redis中保留的投票记录也会过期
+const restoreWindow = 30 * 24 * time.Hour
+
+var (
+	ErrorNotDeleted     = errors.New("没有被删除")
+	ErrorRestoreExpired = errors.New("已经超过恢复期限")
+)
+
+// RestorePost: 恢复删除的帖子， 重新加入redis中的列表
+// 作者只能恢复自己删除的帖子， 版主删除的帖子只有版主可以恢复
+func RestorePost(userID, postID int64) error {
+	post, err := mysql.GetPostByIDUnscoped(postID)
+	if err != nil {
+		return err
+	}
+	if err = checkRestore(userID, post.AuthorID, post.DeletedBy, post.CommunityID, post.DeletedAt.Valid, post.DeletedAt.Time); err != nil {
+		return err
+	}
+	if err = mysql.RestorePost(postID, linkHash(post, post.Status)); err != nil {
+		return linkError(err)
+	}
+	if !post.Listed() {
+		return nil
+	}
+	return redis.RestorePost(post.ID, post.CommunityID, post.AuthorID, post.ListedTime())
+}
+
+// RestoreComment: 恢复删除的评论， 规则和恢复帖子相同
+func RestoreComment(userID, commentID int64) error {
+	comment, err := mysql.GetCommentByIDUnscoped(commentID)
+	if err != nil {
+		return err
+	}
+	post, err := mysql.GetPostByIDUnscoped(comment.PostID)
+	if err != nil {
+		return err
+	}
+	if err = checkRestore(userID, comment.AuthorID, comment.DeletedBy, post.CommunityID, comment.DeletedAt.Valid, comment.DeletedAt.Time); err != nil {
+		return err
+	}
+	return mysql.RestoreComment(commentID)
+}
+
+func checkRestore(userID, authorID, deletedBy, communityID int64, deleted bool, deletedAt time.Time) error {
+	if !deleted {
+		return ErrorNotDeleted
+	}
+	if time.Since(deletedAt) > restoreWindow {
+		return ErrorRestoreExpired
+	}
+	if userID == authorID && deletedBy == authorID {
+		return nil
+	}
+	return CheckCommunityPermission(userID, communityID, PermManagePosts)
+}
+
+// checkManageComment: 检查用户是否可以管理评论所在社区的帖子和评论
+func checkManageComment(userID int64, comment *models.Comment) error {
+	post, err := mysql.GetPostByIDUnscoped(comment.PostID)
+	if err != nil {
+		return err
+	}
+	return CheckCommunityPermission(userID, post.CommunityID, PermManagePosts)
+}
+
+// deletedPost: 返回显示为占位符的帖子， 不会修改原来的数据
+func deletedPost(post *models.Post) *models.Post {
+	p := *post
+	p.AuthorID = 0
+	p.Title = models.DeletedPlaceholder
+	p.Content = models.DeletedPlaceholder
+	p.EditedTime = nil
+	p.RemovalReason = ""
+	p.URL = ""
+	p.Images = nil
+	p.Poll = nil
+	p.Deleted = true
+	return &p
+}
+
+// deletedComment: 把已经删除的评论替换为占位符
+func deletedComment(comment *models.Comment) {
+	comment.AuthorID = 0
+	comment.Content = models.DeletedPlaceholder
+	comment.Deleted = true
+}
+
+// PurgeRemovedPosts: 以前删除帖子时没有同步redis， 清理各个列表中已经不存在的帖子id， 只会执行一次
+func PurgeRemovedPosts() error {
+	if done, err := redis.IsMigrated(redis.MigrationPurgePosts); err != nil || done {
+		return err
+	}
+	const batch = 500
+	missing := make([]string, 0)
+	for start := int64(0); ; start += batch {
+		pids, err := redis.GetAllPostIDs(start, batch)
+		if err != nil {
+			return err
+		}
+		ids, err := mysql.GetMissingPostIDs(pids)
+		if err != nil {
+			return err
+		}
+		missing = append(missing, ids...)
+		if len(pids) < batch {
+			break
+		}
+	}
+	// 遍历结束之后再删除， 避免删除时影响分页
+	if err := redis.PurgePosts(missing); err != nil {
+		return err
+	}
+	zap.L().Info("purge removed posts finished", zap.Int("count", len(missing)))
+	return redis.SetMigrated(redis.MigrationPurgePosts)
+}
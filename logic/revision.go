@@ -0,0 +1,112 @@
+package logic
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/diff"
+	"gorm.io/gorm"
+)
+
+var ErrorRevisionNotExist = errors.New("该版本不存在")
+
+// UpdatePost: 作者编辑帖子的标题和内容， 每次编辑都会保存一个新的版本
+func UpdatePost(userID, postID int64, p *models.ParamUpdatePost) (*models.Post, error) {
+	post, err := getPost(postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.AuthorID != userID {
+		return nil, ErrorNotPerm
+	}
+	// 没有修改时不保存新的版本
+	if post.Title == p.Title && post.Content == p.Content {
+		return post, renderPosts([]*models.Post{post})
+	}
+	links, err := checkContent(userID, p.Content)
+	if err != nil {
+		return nil, err
+	}
+	// 草稿还没有发布， 不需要编辑记录
+	if post.Status == models.PostStatusDraft {
+		if err = mysql.UpdateDraft(post, p.Title, p.Content); err != nil {
+			return nil, err
+		}
+	} else {
+		// 记录编辑时的赞成票数量， 方便版主判断是不是获得投票之后才修改的内容
+		votes, err := redis.GetVotesByPostIDS([]string{strconv.FormatInt(postID, 10)})
+		if err != nil {
+			return nil, err
+		}
+		if post, err = mysql.UpdatePostWithRevision(postID, userID, p.Title, p.Content, votes[0], time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	// 只保留最新版本中的外链
+	savePostLinks(post, links)
+	return post, renderPosts([]*models.Post{post})
+}
+
+// GetPostRevisions: 获取帖子所有的版本， 只有作者和社区的版主可以查看
+func GetPostRevisions(userID, postID int64) ([]*models.PostRevision, error) {
+	post, err := getPost(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkViewRevisions(userID, post); err != nil {
+		return nil, err
+	}
+	return mysql.GetPostRevisions(postID)
+}
+
+// GetPostRevisionDiff: 比较某个版本和上一个版本， 版本1和空白内容比较
+func GetPostRevisionDiff(userID, postID int64, version int) (*models.PostRevisionDiff, error) {
+	post, err := getPost(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkViewRevisions(userID, post); err != nil {
+		return nil, err
+	}
+	to, err := mysql.GetPostRevision(postID, version)
+	if err != nil {
+		return nil, err
+	}
+	if to == nil {
+		return nil, ErrorRevisionNotExist
+	}
+	data := &models.PostRevisionDiff{To: to}
+	var fromTitle, fromContent string
+	if version > 1 {
+		if data.From, err = mysql.GetPostRevision(postID, version-1); err != nil {
+			return nil, err
+		}
+		if data.From != nil {
+			fromTitle, fromContent = data.From.Title, data.From.Content
+		}
+	}
+	data.Title = diff.Lines(fromTitle, to.Title)
+	data.Content = diff.Lines(fromContent, to.Content)
+	return data, nil
+}
+
+// checkViewRevisions: 作者本人、社区的版主和站点管理员可以查看编辑记录
+func checkViewRevisions(userID int64, post *models.Post) error {
+	if post.AuthorID == userID {
+		return nil
+	}
+	return CheckCommunityPermission(userID, post.CommunityID, PermViewPostRevisions)
+}
+
+// getPost: 获取帖子， 不存在时返回mysql.ErrorPostNotExist
+func getPost(postID int64) (*models.Post, error) {
+	post, err := mysql.GetPostByID(postID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, mysql.ErrorPostNotExist
+	}
+	return post, err
+}
@@ -0,0 +1,241 @@
+package logic
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/totp"
+	"github.com/xiaorui/reddit-async/reddit-backend/settings"
+	"go.uber.org/zap"
+)
+
+const (
+	recoveryCodeCount       = 10              // 每次生成的恢复码数量
+	loginChallengeExpire    = 5 * time.Minute // 登录挑战的有效期
+	loginChallengeAttempts  = 5               // 登录挑战允许失败的次数
+	totpUsedStepExpire      = 2 * time.Minute // 时间步的记录要覆盖验证时允许的时间偏差
+	recoveryCodeGroupLength = 5
+)
+
+var (
+	ErrorTOTPAlreadyEnabled = errors.New("已经开启了两步验证")
+	ErrorTOTPNotEnabled     = errors.New("没有开启两步验证")
+	ErrorTOTPNotEnrolled    = errors.New("请先获取两步验证密钥")
+	ErrorInvalidTOTPCode    = errors.New("两步验证码错误")
+	ErrorInvalidChallenge   = errors.New("登录挑战不存在或已过期")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EnrollTOTP: 生成新的两步验证密钥， 需要调用ConfirmTOTP确认之后才会生效
+func EnrollTOTP(userID int64) (*models.TOTPEnrollment, error) {
+	user, err := mysql.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrorTOTPAlreadyEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err = mysql.SetTOTPSecret(userID, secret); err != nil {
+		return nil, err
+	}
+	// 验证器App中显示的账号名
+	account := user.Username
+	if user.Email != "" {
+		account = user.Email
+	} else if user.Phone != "" {
+		account = user.Phone
+	}
+	return &models.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.ProvisioningURI(settings.Conf.Name, account, secret),
+	}, nil
+}
+
+// ConfirmTOTP: 使用验证器App生成的验证码确认开启两步验证， 返回一次性的恢复码
+func ConfirmTOTP(userID int64, code string) ([]string, error) {
+	user, err := mysql.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrorTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrorTOTPNotEnrolled
+	}
+	if err = verifyTOTPCode(user, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err = mysql.EnableTOTP(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP: 关闭两步验证， 需要提供TOTP验证码或者恢复码
+func DisableTOTP(userID int64, code string) error {
+	user, err := mysql.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrorTOTPNotEnabled
+	}
+	if err = verifySecondFactor(user, code); err != nil {
+		return err
+	}
+	return mysql.DisableTOTP(userID)
+}
+
+// CreateLoginChallenge: 开启了两步验证的用户通过第一步验证之后， 生成一个登录挑战
+func CreateLoginChallenge(userID int64) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	challenge := base64.RawURLEncoding.EncodeToString(b)
+	if err := redis.CreateLoginChallenge(challenge, userID, loginChallengeExpire); err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+// LoginWithTwoFactor: 使用登录挑战和TOTP验证码(或恢复码)完成登录， 签发真正的token
+func LoginWithTwoFactor(p *models.ParamLoginTwoFactor, device *models.Device) (user *models.User, accessToken, refreshToken string, err error) {
+	// 1. 查询登录挑战对应的用户
+	userID, err := redis.GetLoginChallenge(p.ChallengeToken)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if userID == 0 {
+		return nil, "", "", ErrorInvalidChallenge
+	}
+	user, err = mysql.GetUserByID(userID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if err = checkTwoFactorAllowed(userID); err != nil {
+		return nil, "", "", err
+	}
+
+	// 2. 先占用挑战再校验， 并发的请求中只有占用成功的那个会消耗恢复码或者TOTP时间步
+	claimed, err := redis.ClaimLoginChallenge(p.ChallengeToken)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if !claimed {
+		return nil, "", "", ErrorInvalidChallenge
+	}
+
+	// 3. 校验验证码， 失败次数过多时挑战作废， 需要重新登录； 同一个用户的所有挑战累计失败次数过多时锁定
+	if err = verifySecondFactor(user, p.Code); err != nil {
+		// 先记录失败次数再释放挑战， 并发的请求不能超过允许失败的次数
+		if errors.Is(err, ErrorInvalidTOTPCode) {
+			recordTwoFactorFailure(userID, device.IP)
+			attempts, incrErr := redis.IncrLoginChallengeAttempts(p.ChallengeToken)
+			if incrErr != nil {
+				zap.L().Error("redis.IncrLoginChallengeAttempts failed", zap.Error(incrErr))
+			}
+			if attempts >= loginChallengeAttempts {
+				if _, delErr := redis.DelLoginChallenge(p.ChallengeToken); delErr != nil {
+					zap.L().Error("redis.DelLoginChallenge failed", zap.Error(delErr))
+				}
+			}
+		}
+		if relErr := redis.ReleaseLoginChallenge(p.ChallengeToken); relErr != nil {
+			zap.L().Error("redis.ReleaseLoginChallenge failed", zap.Error(relErr))
+		}
+		return nil, "", "", err
+	}
+
+	// 4. 挑战只能成功使用一次， 占用之后其他请求已经不能使用， 这里直接删除
+	if _, err = redis.DelLoginChallenge(p.ChallengeToken); err != nil {
+		zap.L().Error("redis.DelLoginChallenge failed", zap.Error(err))
+	}
+	resetTwoFactorFailures(userID)
+	accessToken, refreshToken, err = IssueTokens(user, device)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return user, accessToken, refreshToken, nil
+}
+
+// verifySecondFactor: 校验TOTP验证码， 不是TOTP验证码的格式时当作恢复码校验
+func verifySecondFactor(user *models.User, code string) error {
+	if !user.TOTPEnabled {
+		return ErrorTOTPNotEnabled
+	}
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		return verifyTOTPCode(user, code)
+	}
+	ok, err := mysql.UseRecoveryCode(user.ID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrorInvalidTOTPCode
+	}
+	zap.L().Info("recovery code used", zap.Int64("user_id", user.ID))
+	return nil
+}
+
+// verifyTOTPCode: 校验TOTP验证码， 同一个时间步的验证码只能使用一次
+func verifyTOTPCode(user *models.User, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return ErrorInvalidTOTPCode
+	}
+	fresh, err := redis.MarkTOTPStepUsed(user.ID, step, totpUsedStepExpire)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrorInvalidTOTPCode
+	}
+	return nil
+}
+
+// generateRecoveryCodes: 生成恢复码， 返回给用户的明文和保存到数据库的hash
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	codes = make([]string, 0, recoveryCodeCount)
+	hashes = make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 6) // 6个字节正好编码成10个base32字符
+		if _, err = rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		code := raw[:recoveryCodeGroupLength] + "-" + raw[recoveryCodeGroupLength:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode: 恢复码本身是高熵的随机数， 直接sha256即可
+// 输入时忽略大小写、空格和分隔符
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -3,6 +3,7 @@ package logic
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
@@ -10,7 +11,6 @@ import (
 	"github.com/xiaorui/reddit-async/reddit-backend/models"
 	"github.com/xiaorui/reddit-async/reddit-backend/pkg/async"
 	"github.com/xiaorui/reddit-async/reddit-backend/pkg/helpers"
-	"github.com/xiaorui/reddit-async/reddit-backend/pkg/jwt"
 	"github.com/xiaorui/reddit-async/reddit-backend/pkg/mail"
 	"github.com/xiaorui/reddit-async/reddit-backend/pkg/rabbitmq"
 	"github.com/xiaorui/reddit-async/reddit-backend/pkg/sms"
@@ -21,6 +21,10 @@ import (
 
 // 存放业务逻辑的代码
 func SignUp(p *models.ParamSignUp) (err error) {
+	// 检查注册方式和邀请码
+	if err = checkRegistration(p.InviteCode); err != nil {
+		return err
+	}
 	//1. 判断用户是否存在
 	if err = mysql.CheckUserExist(p.Username); err != nil {
 		return err
@@ -39,25 +43,30 @@ func SignUp(p *models.ParamSignUp) (err error) {
 
 	//4. 保存进数据库
 
-	err = mysql.InsertUser(user)
+	err = insertNewUser(user, p.InviteCode)
 
 	//这里还可以有很多其他的数据操作， 比如对于redis进行操作
 
 	return
 }
 
-func Login(p *models.ParamLogin) (user *models.User, err error) {
+func Login(p *models.ParamLogin, device *models.Device) (user *models.User, err error) {
 	user = &models.User{
 		Username: p.Username,
 		Password: p.Password,
 	}
-	if err = mysql.Login(user); err != nil {
+	// 账号或IP失败次数过多时暂时不允许登录
+	if err = passwordLogin(p.Username, device.IP, user, mysql.Login); err != nil {
 		return nil, err
 	}
 	//如果登录成功
+	//开启了两步验证的用户先不签发token， 由调用方返回登录挑战
+	if user.TOTPEnabled {
+		return user, nil
+	}
 	//生成JWT
 	//return jwt.GenToken(user.UserID, user.Username)
-	accessToken, _, err := jwt.GenToken(user.ID, user.Username)
+	accessToken, _, err := IssueTokens(user, device)
 	if err != nil {
 		return nil, err
 	}
@@ -74,18 +83,21 @@ func LoginUsingPhoneWithCode(p *models.ParamLoginUsingPhoneWithCode) (*models.Us
 	if err := mysql.LoginUsingPhoneWithCode(user); err != nil {
 		return nil, err
 	}
+	// 能收到验证码说明手机号码是有效的
+	markContactVerified(user.ID, models.ContactPhone, user.PhoneVerifiedAt)
 
 	// 如果登录成功：即确实有这个用户存在
 	return user, nil
 }
 
 // LoginUsingEmail: 使用邮箱+密码的方式进行登陆
-func LoginUsingEmail(p *models.ParamLoginUsingEmail) (*models.User, error) {
+func LoginUsingEmail(p *models.ParamLoginUsingEmail, device *models.Device) (*models.User, error) {
 	user := &models.User{
 		Email:    p.Email,
 		Password: p.Password,
 	}
-	if err := mysql.LoginUsingEmail(user); err != nil {
+	// 账号或IP失败次数过多时暂时不允许登录
+	if err := passwordLogin(p.Email, device.IP, user, mysql.LoginUsingEmail); err != nil {
 		zap.L().Error(" mysql.LoginUsingEmail failed", zap.Error(err))
 		return nil, err
 	}
@@ -93,6 +105,32 @@ func LoginUsingEmail(p *models.ParamLoginUsingEmail) (*models.User, error) {
 	return user, nil
 }
 
+// LoginUsingPassword: 使用用户名、邮箱或手机号码+密码的方式进行登陆
+func LoginUsingPassword(p *models.ParamLoginUsingPassword, device *models.Device) (*models.User, error) {
+	// 账号或IP失败次数过多时暂时不允许登录
+	user := &models.User{Password: p.Password}
+	column := identifierColumn(p.Identifier)
+	err := passwordLogin(p.Identifier, device.IP, user, func(user *models.User) error {
+		return mysql.LoginUsingIdentifier(user, column, p.Identifier)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// identifierColumn: 根据登录标识的格式判断是哪种账号
+// 包含@的是邮箱， 11位数字的是手机号码， 其他的都当作用户名
+func identifierColumn(identifier string) string {
+	if strings.Contains(identifier, "@") {
+		return "email"
+	}
+	if len(identifier) == 11 && strings.Trim(identifier, "0123456789") == "" {
+		return "phone"
+	}
+	return "username"
+}
+
 // IsPhoneExist：返回输入手机号码是否存在数据表中
 func IsPhoneExist(phone string) (bool, error) {
 	exist, err := mysql.IsPhoneExist(phone)
@@ -108,7 +146,11 @@ func IsEmailExist(phone string) (bool, error) {
 }
 
 // SendPhoneCode: 发送短信验证码
-func SendPhoneCode(phone string) error {
+func SendPhoneCode(phone, ip string) error {
+	// 0. 限制同一个手机号和同一个IP的发送频率
+	if err := checkSendCodeAllowed(phone, ip); err != nil {
+		return err
+	}
 	// 1. 生成验证码
 	code := helpers.GenerateRandomCode()
 
@@ -125,7 +167,11 @@ func SendPhoneCode(phone string) error {
 }
 
 // SendEmailCode: 发送邮箱验证码
-func SendEmailCode(email string) error {
+func SendEmailCode(email, ip string) error {
+	// 0. 限制同一个邮箱和同一个IP的发送频率
+	if err := checkSendCodeAllowed(strings.ToLower(email), ip); err != nil {
+		return err
+	}
 	// 1. 生成验证码
 	code := helpers.GenerateRandomCode()
 
@@ -154,6 +200,10 @@ func SendEmailCode(email string) error {
 
 // SignupUsingPhone：处理手机注册登陆逻辑
 func SignupUsingPhone(p *models.ParamSignupUsingPhone) (err error) {
+	// 检查注册方式和邀请码
+	if err = checkRegistration(p.InviteCode); err != nil {
+		return err
+	}
 	// 1. 判断用户是否存在
 	if err = mysql.CheckUserExist(p.Name); err != nil {
 		return err
@@ -167,21 +217,28 @@ func SignupUsingPhone(p *models.ParamSignupUsingPhone) (err error) {
 	userID := snowflake.GenID()
 
 	// 3. 构造用户实例
+	// 注册时已经验证过短信验证码
+	now := time.Now()
 	user := &models.User{
-		ID:       userID,
-		Username: p.Name,
-		Password: p.Password,
-		Phone:    p.Phone,
+		ID:              userID,
+		Username:        p.Name,
+		Password:        p.Password,
+		Phone:           p.Phone,
+		PhoneVerifiedAt: &now,
 	}
 
 	// 4. 保存到数据库
-	err = mysql.InsertUser(user)
+	err = insertNewUser(user, p.InviteCode)
 
 	return
 }
 
 // SignUpUsingEmail: 进行使用邮箱进行注册的业务
 func SignUpUsingEmail(p *models.ParamSignUpUsingEmail) (err error) {
+	// 检查注册方式和邀请码
+	if err = checkRegistration(p.InviteCode); err != nil {
+		return err
+	}
 	// 1. 验证用户名是否存在
 	if err = mysql.CheckUserExist(p.Name); err != nil {
 		return err
@@ -194,11 +251,14 @@ func SignUpUsingEmail(p *models.ParamSignUpUsingEmail) (err error) {
 	userID := snowflake.GenID()
 
 	// 4. 构造用户实例
+	// 注册时已经验证过邮箱验证码
+	verifiedAt := time.Now()
 	_user := models.User{
-		ID:       userID,
-		Username: p.Name,
-		Email:    p.Email,
-		Password: p.Password,
+		ID:              userID,
+		Username:        p.Name,
+		Email:           p.Email,
+		Password:        p.Password,
+		EmailVerifiedAt: &verifiedAt,
 	}
 
 	// TODO: 这里用户注册成功之后， 发送一份邮件给用户邮箱， 说明欢迎加入论坛
@@ -277,7 +337,7 @@ func SignUpUsingEmail(p *models.ParamSignUpUsingEmail) (err error) {
 		return err
 	}
 	// 5. 保存到数据库中
-	if err = mysql.InsertUser(&_user); err != nil {
+	if err = insertNewUser(&_user, p.InviteCode); err != nil {
 		return err
 	}
 	return nil
@@ -321,15 +381,19 @@ func UpdateEmail(p *models.ParamUpdateEmail, userID int64) (user *models.User, e
 		return nil, err
 	}
 
-	// 2. 查看当下邮箱是否存在
-	if _, err = mysql.IsEmailExist(p.Email); err != nil {
-		return nil, err
+	// 2. 查看新的邮箱是否已经被使用
+	if p.Email != user.Email {
+		exist, err := mysql.IsEmailExist(p.Email)
+		if err != nil {
+			return nil, err
+		}
+		if exist {
+			return nil, mysql.ErrorEmailExist
+		}
 	}
 
-	// 3. 设置用户信
-	user.Email = p.Email
-	// 4. 写回数据库
-	return mysql.SaveUser(user)
+	// 3. 新邮箱已经通过验证码验证， 写入之后通知旧邮箱
+	return changeContact(user, models.ContactEmail, p.Email)
 }
 
 func UpdatePhone(p *models.ParamUpdatePhone, userID int64) (user *models.User, err error) {
@@ -337,23 +401,112 @@ func UpdatePhone(p *models.ParamUpdatePhone, userID int64) (user *models.User, e
 	if user, err = mysql.GetUserByID(userID); err != nil {
 		return nil, err
 	}
-	// 2. 查看当前号码是否存在
-	if _, err = mysql.IsPhoneExist(p.Phone); err != nil {
-		return nil, err
+	// 2. 查看新的号码是否已经被使用
+	if p.Phone != user.Phone {
+		exist, err := mysql.IsPhoneExist(p.Phone)
+		if err != nil {
+			return nil, err
+		}
+		if exist {
+			return nil, mysql.ErrorPhoneExist
+		}
 	}
 
-	// 3. 设置新的号码
-	user.Phone = p.Phone
-
-	// 4. 进行写回
-	return mysql.SaveUser(user)
+	// 3. 新号码已经通过验证码验证， 写入之后发送通知
+	return changeContact(user, models.ContactPhone, p.Phone)
 }
 
 // UpdatePassword： 更改当前用户的密码
 func UpdatePassword(p *models.ParamUpdatePassword, userID int64) (err error) {
-	return mysql.UpdatePassword(p.Password, p.NewPassword, userID)
+	if err = mysql.UpdatePassword(p.Password, p.NewPassword, userID); err != nil {
+		return err
+	}
+	// 修改密码之后， 之前签发的token都不再有效， 需要重新登录
+	if err = revokeAllSessions(userID); err != nil {
+		return err
+	}
+	if user, err := mysql.GetUserByID(userID); err == nil {
+		sendPasswordChangedNotice(user)
+	}
+	return nil
 }
 
 func GetEmailList() (dataList []string, err error) {
 	return mysql.GetEmailList()
 }
+
+// ResetPasswordUsingPhone: 使用手机验证码重置密码
+func ResetPasswordUsingPhone(p *models.ParamResetPasswordUsingPhone) error {
+	// 1. 查询手机号码对应的用户
+	user, err := mysql.GetUserByPhone(p.Phone)
+	if err != nil {
+		return err
+	}
+	// 2. 重置密码
+	if err = resetPassword(user, p.Password); err != nil {
+		return err
+	}
+	// 3. 验证码只能使用一次
+	if err = redis.DelVerifyCode(p.Phone); err != nil {
+		zap.L().Error("redis.DelVerifyCode failed", zap.Error(err))
+	}
+	markContactVerified(user.ID, models.ContactPhone, user.PhoneVerifiedAt)
+	return nil
+}
+
+// ResetPasswordUsingEmail: 使用邮箱验证码重置密码
+func ResetPasswordUsingEmail(p *models.ParamResetPasswordUsingEmail) error {
+	// 1. 查询邮箱对应的用户
+	user, err := mysql.GetUserByEmail(p.Email)
+	if err != nil {
+		return err
+	}
+	// 2. 重置密码
+	if err = resetPassword(user, p.Password); err != nil {
+		return err
+	}
+	// 3. 验证码只能使用一次
+	if err = redis.DelVerifyCode(p.Email); err != nil {
+		zap.L().Error("redis.DelVerifyCode failed", zap.Error(err))
+	}
+	markContactVerified(user.ID, models.ContactEmail, user.EmailVerifiedAt)
+	return nil
+}
+
+// resetPassword: 写入新密码， 使之前签发的token失效， 并且通知用户
+func resetPassword(user *models.User, password string) error {
+	if err := mysql.ResetPassword(user.ID, password); err != nil {
+		return err
+	}
+	// 之前签发的token都不再有效， 所有设备都需要重新登录
+	if err := revokeAllSessions(user.ID); err != nil {
+		return err
+	}
+	sendPasswordChangedNotice(user)
+	return nil
+}
+
+// sendPasswordChangedNotice: 向用户邮箱发送密码已修改的通知， 发送失败不影响修改结果
+func sendPasswordChangedNotice(user *models.User) {
+	if user.Email == "" {
+		return
+	}
+	ok := mail.NewMailer().Send(
+		mail.Email{
+			From: mail.From{
+				Address: settings.Conf.EmailConfig.FromConfig.Address,
+				Name:    settings.Conf.EmailConfig.FromConfig.Name,
+			},
+			To:      []string{user.Email},
+			Subject: "您的密码已修改",
+			HTML: []byte(fmt.Sprintf(`
+				<p>亲爱的 %s：</p>
+				<p>您的账号密码已于 %s 修改， 所有设备上的登录状态都已失效， 请使用新密码重新登录。</p>
+				<p>如果这不是您本人的操作， 请立即通过找回密码功能重置密码并联系我们的支持团队。</p>
+			`, user.Username, time.Now().Format("2006-01-02 15:04:05"))),
+		},
+	)
+	if !ok {
+		zap.L().Error("send password changed notice failed", zap.Int64("user_id", user.ID))
+	}
+}
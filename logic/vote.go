@@ -1,6 +1,7 @@
 package logic
 
 import (
+	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
 	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
 	"github.com/xiaorui/reddit-async/reddit-backend/models"
 	"go.uber.org/zap"
@@ -9,5 +10,14 @@ import (
 func VoteForPost(userID int64, p *models.ParamVoteData) error {
 	zap.L().Debug("VoteForPost", zap.Int64("userID", userID), zap.Int64("postID", p.PostID),
 		zap.Int8("direction", p.Direction))
-	return redis.VoteForPost(userID, p.PostID, p.Direction)
+	// 投票会同时修改作者的karma
+	post, err := mysql.GetPostByID(p.PostID)
+	if err != nil {
+		return err
+	}
+	// 没有发布的帖子不能投票
+	if !post.Listed() {
+		return mysql.ErrorPostNotExist
+	}
+	return redis.VoteForPost(userID, post.AuthorID, p.PostID, p.Direction)
 }
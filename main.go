@@ -12,8 +12,10 @@ import (
 	"github.com/xiaorui/reddit-async/reddit-backend/dao/mysql"
 	"github.com/xiaorui/reddit-async/reddit-backend/dao/redis"
 	"github.com/xiaorui/reddit-async/reddit-backend/logger"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
 	"github.com/xiaorui/reddit-async/reddit-backend/pkg/async"
 	"github.com/xiaorui/reddit-async/reddit-backend/pkg/console"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/jwt"
 	"github.com/xiaorui/reddit-async/reddit-backend/pkg/rabbitmq"
 	"github.com/xiaorui/reddit-async/reddit-backend/pkg/snowflake"
 	"github.com/xiaorui/reddit-async/reddit-backend/settings"
@@ -61,12 +63,42 @@ func main() {
 				return
 			}
 
+			// 旧的投票记录回填到用户的点赞列表， 只会执行一次
+			if err := redis.BackfillUserUpvoted(); err != nil {
+				fmt.Printf("redis.BackfillUserUpvoted err:%v", err)
+				return
+			}
+
 			//3. 初始化mysql
 			if err := mysql.Init(settings.Conf.MySQLConfig); err != nil {
 				fmt.Printf("init mysql failed, err:%v", err)
 				return
 			}
 
+			// 已有的帖子回填到作者的帖子集合， 只会执行一次
+			if err := logic.BackfillUserPosts(); err != nil {
+				fmt.Printf("logic.BackfillUserPosts err:%v", err)
+				return
+			}
+
+			// 根据已有的投票记录计算每个作者的karma， 只会执行一次
+			if err := logic.BackfillUserKarma(); err != nil {
+				fmt.Printf("logic.BackfillUserKarma err:%v", err)
+				return
+			}
+
+			// 清理以前删除帖子时留在redis列表中的id， 只会执行一次
+			if err := logic.PurgeRemovedPosts(); err != nil {
+				fmt.Printf("logic.PurgeRemovedPosts err:%v", err)
+				return
+			}
+
+			// 加载jwt签名密钥
+			if err := jwt.Init(settings.Conf.AuthConfig); err != nil {
+				fmt.Printf("jwt.Init err:%v", err)
+				return
+			}
+
 			//初始化雪花算法， 用于创建用户id
 			if err := snowflake.Init(settings.Conf.StartTime, settings.Conf.MachineID); err != nil {
 				fmt.Printf("snowflake.Init err:%v", err)
@@ -75,6 +107,14 @@ func main() {
 
 			// 初始化消费者
 			go rabbitmq.Consumer()
+			// 定期删除注销等待期已经结束的账号
+			go logic.RunAccountDeletionWorker()
+			// 定期把redis中的karma同步到数据库
+			go logic.RunKarmaReconcileWorker()
+			// 定期发布到时间的草稿
+			go logic.RunPostScheduler()
+			// 定期删除上传之后没有使用的图片
+			go logic.RunPostImageCleanup()
 			// TODO: 发起一个定时任务， 每周会生成当下的所有热点信息， 将热点信息投递给所有的已经订阅周报的邮箱， 默认订阅周报
 			if err := async.SendWeekReport(); err != nil {
 				fmt.Println("async.SendWeekReport error...")
@@ -1,11 +1,14 @@
 package middlewares
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/xiaorui/reddit-async/reddit-backend/controller"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
 	"github.com/xiaorui/reddit-async/reddit-backend/pkg/jwt"
+	"go.uber.org/zap"
 )
 
 // JWTAuthMiddleware 基于JWT的认证中间件
@@ -27,6 +30,24 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 			c.Abort()
 			return
 		}
+		// 脚本和机器人使用个人访问令牌， 权限范围由RequireScope检查
+		if logic.IsAccessToken(parts[1]) {
+			token, err := logic.AuthenticateAccessToken(parts[1])
+			if err != nil {
+				if errors.Is(err, logic.ErrorInvalidAccessToken) {
+					controller.ResponseError(c, controller.CodeInvalidToken)
+				} else {
+					zap.L().Error("logic.AuthenticateAccessToken failed", zap.Error(err))
+					controller.ResponseError(c, controller.CodeServerBusy)
+				}
+				c.Abort()
+				return
+			}
+			c.Set(controller.CtxUserIDKey, token.UserID)
+			c.Set(controller.CtxAccessTokenKey, token)
+			c.Next()
+			return
+		}
 		// parts[1]是获取到的tokenString，我们使用之前定义好的解析JWT的函数来解析它
 		mc, err := jwt.ParseToken(parts[1])
 		if err != nil {
@@ -34,9 +55,22 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 			c.Abort()
 			return
 		}
+		// 已经注销， 或者在修改密码之前签发的token都不能再使用
+		revoked, err := logic.IsTokenRevoked(mc)
+		if err != nil {
+			controller.ResponseError(c, controller.CodeServerBusy)
+			c.Abort()
+			return
+		}
+		if revoked {
+			controller.ResponseError(c, controller.CodeInvalidToken)
+			c.Abort()
+			return
+		}
 		// 将当前请求的username信息保存到请求的上下文c上
 		//c.Set("username", mc.Username)
 		c.Set(controller.CtxUserIDKey, mc.UserID) // 在多个模块中可能会用到的常量通常会写成全局常量
+		c.Set(controller.CtxClaimsKey, mc)        // 注销的时候需要用到token id和过期时间
 		//这里在ctx中set值， 后续就可以get值了。也就是说你认证了之后， 你就可以在ctx中获得userid了
 		c.Next() // 后续的处理函数可以用过c.Get("username")来获取当前请求的用户信息
 	}
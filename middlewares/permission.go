@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/controller"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
+	"go.uber.org/zap"
+)
+
+// CommunityPermission 社区操作的鉴权中间件， 需要放在JWTAuthMiddleware之后
+// 社区id从路径参数 :id 中获取
+func CommunityPermission(perm logic.Permission) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userID, ok := c.Get(controller.CtxUserIDKey)
+		if !ok {
+			controller.ResponseError(c, controller.CodeNeedLogin)
+			c.Abort()
+			return
+		}
+		communityID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			controller.ResponseError(c, controller.CodeInvalidParam)
+			c.Abort()
+			return
+		}
+		if err := logic.CheckCommunityPermission(userID.(int64), communityID, perm); err != nil {
+			if errors.Is(err, logic.ErrorNotPerm) {
+				controller.ResponseError(c, controller.CodeNotPerm)
+			} else {
+				zap.L().Error("logic.CheckCommunityPermission failed", zap.Error(err))
+				controller.ResponseError(c, controller.CodeServerBusy)
+			}
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// AdminOnly 只有站点管理员可以访问， 需要放在JWTAuthMiddleware之后
+func AdminOnly() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		userID, ok := c.Get(controller.CtxUserIDKey)
+		if !ok {
+			controller.ResponseError(c, controller.CodeNeedLogin)
+			c.Abort()
+			return
+		}
+		admin, err := logic.IsAdmin(userID.(int64))
+		if err != nil {
+			zap.L().Error("logic.IsAdmin failed", zap.Error(err))
+			controller.ResponseError(c, controller.CodeServerBusy)
+			c.Abort()
+			return
+		}
+		if !admin {
+			controller.ResponseError(c, controller.CodeNotPerm)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
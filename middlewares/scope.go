@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/xiaorui/reddit-async/reddit-backend/controller"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+)
+
+// RequireScope 个人访问令牌的权限范围检查， 需要放在JWTAuthMiddleware之后
+// 使用JWT登录的请求拥有所有权限， 直接放行
+func RequireScope(scope string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		v, ok := c.Get(controller.CtxAccessTokenKey)
+		if !ok {
+			c.Next()
+			return
+		}
+		if token, ok := v.(*models.AccessToken); !ok || !token.HasScope(scope) {
+			controller.ResponseError(c, controller.CodeInsufficientScope)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// SessionOnly 只允许使用JWT登录的请求访问， 例如修改密码、管理令牌等账号相关的操作
+func SessionOnly() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(controller.CtxAccessTokenKey); ok {
+			controller.ResponseError(c, controller.CodeInsufficientScope)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
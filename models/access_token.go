@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// 个人访问令牌的权限范围
+const (
+	ScopeRead     = "read"     // 读取帖子、评论和社区
+	ScopePost     = "post"     // 发布和删除帖子、评论
+	ScopeVote     = "vote"     // 给帖子投票
+	ScopeModerate = "moderate" // 管理社区， 还需要有对应的社区角色
+)
+
+// AccessScopes: 所有可以申请的权限范围
+var AccessScopes = []string{ScopeRead, ScopePost, ScopeVote, ScopeModerate}
+
+// AccessToken: 个人访问令牌， 给脚本和机器人使用， 不需要验证码登录和刷新
+// 数据库中只保存令牌的sha256， 明文只在创建时返回一次
+type AccessToken struct {
+	ID         int64      `json:"token_id,string" gorm:"primaryKey;column:token_id"`
+	UserID     int64      `json:"-" gorm:"column:user_id;index"`
+	Name       string     `json:"name" gorm:"column:name;size:64"`
+	TokenHash  string     `json:"-" gorm:"column:token_hash;size:64;uniqueIndex"`
+	Prefix     string     `json:"prefix" gorm:"column:prefix;size:16"` // 令牌明文的开头， 方便用户区分不同的令牌
+	Scopes     []string   `json:"scopes" gorm:"column:scopes;serializer:json"`
+	ExpiresAt  *time.Time `json:"expires_at" gorm:"column:expires_at"` // 为空表示永不过期
+	LastUsedAt *time.Time `json:"last_used_at" gorm:"column:last_used_at"`
+	CreateTime time.Time  `json:"create_time" gorm:"column:create_time;autoCreateTime"`
+}
+
+// HasScope: 令牌是否拥有某个权限范围
+func (t *AccessToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessTokenCreated: 创建令牌之后的返回值， token只会出现这一次
+type AccessTokenCreated struct {
+	Token       string       `json:"token"`
+	AccessToken *AccessToken `json:"access_token"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	BlockTypeBlock = "block" // 屏蔽： 看不到对方的内容， 对方也不能回复、提及或私信自己
+	BlockTypeMute  = "mute"  // 静音： 只是看不到对方的内容
+)
+
+// UserBlock: 用户屏蔽或静音了target
+type UserBlock struct {
+	UserID     int64     `gorm:"primaryKey;autoIncrement:false;column:user_id"`
+	TargetID   int64     `gorm:"primaryKey;autoIncrement:false;column:target_id;index"`
+	Type       string    `gorm:"column:type;size:8;not null"`
+	CreateTime time.Time `gorm:"column:create_time;autoCreateTime"`
+}
+
+// BlockedUser: 屏蔽列表和静音列表中的用户
+type BlockedUser struct {
+	ID        int64     `json:"user_id,string" gorm:"column:user_id"`
+	Username  string    `json:"username" gorm:"column:username"`
+	Avatar    string    `json:"avatar" gorm:"column:avatar"`
+	BlockedAt time.Time `json:"blocked_at" gorm:"column:blocked_at"`
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Comment struct {
 	ID          int64     `json:"comment_id" gorm:"column:comment_id"`
@@ -11,4 +15,10 @@ type Comment struct {
 	UpdatedTime time.Time `json:"-" gorm:"column:updated_time;autoUpdateTime"`
 	User        User      `json:"-" gorm:"foreignKey:AuthorID"`
 	Post        Post      `json:"-" gorm:"foreignKey:PostID"`
+	// 软删除， 和帖子一样
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"column:deleted_at;index"`
+	DeletedBy int64          `json:"-" gorm:"column:deleted_by;default:0"`
+	Deleted   bool           `json:"deleted,omitempty" gorm:"-"`
+	// 和帖子一样， content是markdown源码
+	ContentHTML string `json:"content_html" gorm:"-"`
 }
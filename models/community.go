@@ -19,4 +19,9 @@ type Community struct {
 	Introduction string    `json:"introduction,omitempty" gorm:"column:introduction"`
 	CreateTime   time.Time `json:"-" gorm:"column:create_time;autoCreateTime"`
 	UpdatedTime  time.Time `json:"-" gorm:"column:updated_time;autoUpdateTime"`
+	// 发帖的门槛， 为0时不限制
+	MinKarma          int64 `json:"min_karma" gorm:"column:min_karma;default:0"`
+	MinAccountAgeDays int64 `json:"min_account_age_days" gorm:"column:min_account_age_days;default:0"`
+	// 注册时间少于这个天数的用户发布的帖子需要版主审核， 为0时不需要审核
+	ReviewAccountAgeDays int64 `json:"review_account_age_days" gorm:"column:review_account_age_days;default:0"`
 }
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+const (
+	ContactEmail = "email"
+	ContactPhone = "phone"
+)
+
+// ContactChange: 修改邮箱或手机号码的记录， 用于通过旧的联系方式撤销修改
+type ContactChange struct {
+	UserID        int64      `json:"user_id"`
+	Type          string     `json:"type"` // email 或 phone
+	OldValue      string     `json:"old_value"`
+	NewValue      string     `json:"new_value"`
+	OldVerifiedAt *time.Time `json:"old_verified_at"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// UserVote: 用户对某个帖子的投票， direction为1赞成， -1反对
+type UserVote struct {
+	PostID    string `json:"post_id"`
+	Direction int8   `json:"direction"`
+}
+
+// ExportPost: 导出数据中的帖子， 带上创建和修改时间
+type ExportPost struct {
+	*Post
+	CreateTime  time.Time `json:"create_time"`
+	UpdatedTime time.Time `json:"updated_time"`
+}
+
+// ExportComment: 导出数据中的评论， 带上创建和修改时间
+type ExportComment struct {
+	*Comment
+	CreateTime  time.Time `json:"create_time"`
+	UpdatedTime time.Time `json:"updated_time"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Follow: 关注关系， follower关注了followee
+type Follow struct {
+	FollowerID int64     `gorm:"primaryKey;autoIncrement:false;column:follower_id"`
+	FolloweeID int64     `gorm:"primaryKey;autoIncrement:false;column:followee_id;index"`
+	CreateTime time.Time `gorm:"column:create_time;autoCreateTime"`
+}
+
+// FollowUser: 关注列表和粉丝列表中的用户
+type FollowUser struct {
+	ID         int64     `json:"user_id,string" gorm:"column:user_id"`
+	Username   string    `json:"username" gorm:"column:username"`
+	Avatar     string    `json:"avatar" gorm:"column:avatar"`
+	FollowedAt time.Time `json:"followed_at" gorm:"column:followed_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// UserIdentity: 用户绑定的第三方账号， 同一个提供方的同一个subject只能绑定一个用户
+type UserIdentity struct {
+	ID         int64     `json:"-" gorm:"primaryKey;autoIncrement;column:id"`
+	UserID     int64     `json:"-" gorm:"column:user_id;index"`
+	Provider   string    `json:"provider" gorm:"column:provider;size:32;uniqueIndex:idx_provider_subject"`
+	Subject    string    `json:"-" gorm:"column:subject;size:255;uniqueIndex:idx_provider_subject"` // 提供方的用户id， 即id_token中的sub
+	Email      string    `json:"email" gorm:"column:email"`                                         // 绑定时提供方返回的邮箱， 只用于展示
+	CreateTime time.Time `json:"create_time" gorm:"column:create_time;autoCreateTime"`
+}
+
+// OIDCLoginState: 跳转到第三方登录之前保存的状态， 回调时使用state取出
+type OIDCLoginState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Invitation: 邀请码， 仅邀请注册时需要， 由管理员生成
+type Invitation struct {
+	ID         int64      `json:"invitation_id,string" gorm:"primaryKey;column:invitation_id"`
+	Code       string     `json:"code" gorm:"column:code;size:32;uniqueIndex"`
+	InviterID  int64      `json:"inviter_id,string" gorm:"column:inviter_id;index"` // 生成邀请码的管理员， 注册的用户会记录这个id
+	MaxUses    int        `json:"max_uses" gorm:"column:max_uses"`
+	Uses       int        `json:"uses" gorm:"column:uses;default:0"`
+	ExpiresAt  *time.Time `json:"expires_at" gorm:"column:expires_at"` // 为空表示永不过期
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+	CreateTime time.Time  `json:"create_time" gorm:"column:create_time;autoCreateTime"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// 外链所在内容的类型
+const (
+	LinkSourcePost    = "post"
+	LinkSourceComment = "comment"
+)
+
+// OutboundLink: 帖子和评论的内容中指向站外的链接， 方便版主按照域名查找和处理
+// 内容修改之后会重新提取， 只保留最新版本中的链接
+type OutboundLink struct {
+	ID          int64     `json:"link_id,string" gorm:"primaryKey;column:link_id"`
+	SourceType  string    `json:"source_type" gorm:"column:source_type;size:16;index:idx_link_source"`
+	SourceID    int64     `json:"source_id,string" gorm:"column:source_id;index:idx_link_source"`
+	PostID      int64     `json:"post_id,string" gorm:"column:post_id"`
+	CommunityID int64     `json:"community_id" gorm:"column:community_id;index"`
+	AuthorID    int64     `json:"author_id,string" gorm:"column:author_id"`
+	URL         string    `json:"url" gorm:"column:url;size:2048;not null"` // 规范化之后的地址
+	Host        string    `json:"host" gorm:"column:host;size:255;index"`
+	CreateTime  time.Time `json:"create_time" gorm:"column:create_time;autoCreateTime"`
+}
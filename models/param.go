@@ -1,6 +1,9 @@
 package models
 
-import "mime/multipart"
+import (
+	"mime/multipart"
+	"time"
+)
 
 const (
 	OrderTime  = "time"
@@ -11,6 +14,7 @@ type ParamSignUp struct {
 	Username   string `json:"username" binding:"required"`
 	Password   string `json:"password" binding:"required"`
 	RePassword string `json:"re_password" binding:"required,eqfield=Password"`
+	InviteCode string `json:"invite_code"` // 仅邀请注册时需要
 }
 
 type ParamLogin struct {
@@ -18,6 +22,21 @@ type ParamLogin struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type ParamRefreshToken struct {
+	RefreshToken string `json:"refresh_token" valid:"refresh_token"`
+}
+
+// ParamLoginTwoFactor: 开启两步验证之后， 使用登录挑战和验证码完成登录
+type ParamLoginTwoFactor struct {
+	ChallengeToken string `json:"challenge_token" valid:"challenge_token"`
+	Code           string `json:"code" valid:"code"` // TOTP验证码或者恢复码
+}
+
+// ParamTOTPCode: 确认或者关闭两步验证
+type ParamTOTPCode struct {
+	Code string `json:"code" valid:"code"`
+}
+
 type ParamVoteData struct {
 	PostID    int64 `json:"post_id,string" binding:"required"`
 	Direction int8  `json:"direction,string" binding:"oneof=0 1 -1"` // required会把一些零值给看做没有值， 比如0对于int
@@ -56,6 +75,7 @@ type ParamSignupUsingPhone struct {
 	Name            string `json:"name" valid:"name"`
 	Password        string `json:"password" valid:"password"`
 	PasswordConfirm string `json:"password_confirm" valid:"password_confirm"`
+	InviteCode      string `json:"invite_code,omitempty" valid:"invite_code"` // 仅邀请注册时需要
 }
 
 type ParamSignUpUsingEmail struct {
@@ -64,6 +84,7 @@ type ParamSignUpUsingEmail struct {
 	Name            string `json:"name" valid:"name"`
 	Password        string `json:"password" valid:"password"`
 	PasswordConfirm string `json:"password_confirm" valid:"password_confirm"`
+	InviteCode      string `json:"invite_code,omitempty" valid:"invite_code"` // 仅邀请注册时需要
 }
 
 type ParamLoginUsingPhoneWithCode struct {
@@ -76,6 +97,17 @@ type ParamLoginUsingEmail struct {
 	Password string `json:"password" valid:"password"`
 }
 
+// ParamDeleteAccount: 注销账号之前需要再次输入密码
+type ParamDeleteAccount struct {
+	Password string `json:"password" valid:"password"`
+}
+
+// ParamLoginUsingPassword: 使用用户名、邮箱或手机号码+密码登录
+type ParamLoginUsingPassword struct {
+	Identifier string `json:"identifier" valid:"identifier"` // 用户名、邮箱或者手机号码
+	Password   string `json:"password" valid:"password"`
+}
+
 type ParamUpdateProfile struct {
 	Name         string `json:"name" valid:"name"`
 	City         string `json:"city" valid:"city"`
@@ -92,6 +124,24 @@ type ParamUpdatePhone struct {
 	Code  string `json:"code,omitempty" valid:"code"`
 }
 
+type ParamResetPasswordUsingPhone struct {
+	CaptchaID       string `json:"captcha_id,omitempty" valid:"captcha_id"`
+	CaptchaAnswer   string `json:"captcha_answer,omitempty" valid:"captcha_answer"`
+	Phone           string `json:"phone,omitempty" valid:"phone"`
+	Code            string `json:"code,omitempty" valid:"verify_code"`
+	Password        string `json:"password" valid:"password"`
+	PasswordConfirm string `json:"password_confirm" valid:"password_confirm"`
+}
+
+type ParamResetPasswordUsingEmail struct {
+	CaptchaID       string `json:"captcha_id,omitempty" valid:"captcha_id"`
+	CaptchaAnswer   string `json:"captcha_answer,omitempty" valid:"captcha_answer"`
+	Email           string `json:"email,omitempty" valid:"email"`
+	Code            string `json:"code,omitempty" valid:"verify_code"`
+	Password        string `json:"password" valid:"password"`
+	PasswordConfirm string `json:"password_confirm" valid:"password_confirm"`
+}
+
 type ParamUpdatePassword struct {
 	Password           string `json:"password" valid:"password"`
 	NewPassword        string `json:"new_password" valid:"new_password"`
@@ -107,6 +157,70 @@ type ParamCommunity struct {
 	Introduction string `json:"introduction,omitempty" valid:"introduction"`
 }
 
+// ParamUpdatePost: 编辑帖子
+type ParamUpdatePost struct {
+	Title   string `json:"title" valid:"title"`
+	Content string `json:"content" valid:"content"`
+}
+
+// ParamCommunityRequirements: 设置在社区中发帖的门槛， 为0时不限制
+type ParamCommunityRequirements struct {
+	MinKarma          int64 `json:"min_karma"`
+	MinAccountAgeDays int64 `json:"min_account_age_days"`
+	// 新用户的帖子需要审核
+	ReviewAccountAgeDays int64 `json:"review_account_age_days"`
+}
+
+// ParamSchedulePost: 设置草稿定时发布的时间， 为空时取消定时发布
+type ParamSchedulePost struct {
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// ParamUploadPostImage: 上传图片帖子的图片
+type ParamUploadPostImage struct {
+	Image *multipart.FileHeader `valid:"image" form:"image"`
+}
+
+// ParamVotePoll: 投票帖子投票， 单选时只能选择一个选项
+type ParamVotePoll struct {
+	Options []int `json:"options"` // 选项的编号
+}
+
+// ParamRejectPost: 版主拒绝或移除帖子， 原因会显示给作者
+type ParamRejectPost struct {
+	Reason string `json:"reason" valid:"reason"`
+	Spam   bool   `json:"spam"` // 标记为垃圾内容
+}
+
+// ParamCommunityMemberRole: 设置用户在社区中的角色
+type ParamCommunityMemberRole struct {
+	Role string `json:"role" valid:"role"` // moderator 或者 member
+}
+
 type ParamCreateNewComment struct {
 	Content string `json:"content" valid:"content"`
 }
+
+// ParamCreateInvitation: 管理员生成邀请码
+type ParamCreateInvitation struct {
+	MaxUses   int `json:"max_uses" valid:"max_uses"`     // 最多可以注册的用户数量
+	ExpiresIn int `json:"expires_in" valid:"expires_in"` // 有效天数， 0 表示永不过期
+}
+
+// ParamCreateAccessToken: 创建个人访问令牌
+type ParamCreateAccessToken struct {
+	Name      string   `json:"name" valid:"name"`
+	Scopes    []string `json:"scopes" valid:"scopes"`         // read, post, vote, moderate
+	ExpiresIn int      `json:"expires_in" valid:"expires_in"` // 有效天数， 0表示永不过期
+}
+
+// ParamOIDCCallback: 第三方登录之后， 前端回调页面拿到的code和state
+type ParamOIDCCallback struct {
+	Code  string `json:"code" valid:"code"`
+	State string `json:"state" valid:"state"`
+}
+
+// ParamPrivacy: 修改隐私设置
+type ParamPrivacy struct {
+	HideVotes bool `json:"hide_votes"`
+}
@@ -1,18 +1,71 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DeletedPlaceholder: 已经删除的帖子和评论在讨论串中显示的内容
+const DeletedPlaceholder = "[deleted]"
+
+// 帖子的审核状态， 以前的帖子status都是0， 所以0表示已发布
+const (
+	PostStatusPublished int32 = iota // 已发布， 出现在各个列表中
+	PostStatusPending                // 待审核， 只有作者和版主可以查看
+	PostStatusRemoved                // 被版主移除
+	PostStatusSpam                   // 被版主标记为垃圾内容
+	PostStatusLocked                 // 已锁定， 仍然出现在列表中， 但是不能评论
+	PostStatusDraft                  // 草稿， 只有作者可以查看， 设置了发布时间时到时间之后自动发布
+)
 
 type Post struct {
 	ID          int64     `json:"id" gorm:"column:post_id"`
 	AuthorID    int64     `json:"author_id" gorm:"column:author_id"`
-	CommunityID int64     `json:"community_id" gorm:"column:community_id;not null"`
-	Status      int32     `json:"status" gorm:"column:status"` // 没有实现， 本来想要看能不能实现待审核状态等
+	CommunityID int64     `json:"community_id" gorm:"column:community_id;not null;uniqueIndex:idx_community_url_hash"`
+	Status      int32     `json:"status" gorm:"column:status"` // 审核状态， PostStatusXxx
 	Title       string    `json:"title" gorm:"column:title;not null"`
 	Content     string    `json:"content" gorm:"column:content;not null"`
 	CreateTime  time.Time `json:"-" gorm:"column:create_time;autoCreateTime"`
 	UpdatedTime time.Time `json:"-" gorm:"column:updated_time;autoUpdateTime"`
 	Community   Community `json:"-" gorm:"foreignKey:CommunityID"`
 	User        User      `json:"-" gorm:"foreignKey:AuthorID"`
+	// 最后一次编辑的时间， 没有编辑过时为空
+	EditedTime *time.Time `json:"edited_time,omitempty" gorm:"column:edited_time"`
+	// 软删除， 查询时默认会过滤掉已经删除的帖子， 在恢复期限内作者和版主可以恢复
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"column:deleted_at;index"`
+	DeletedBy int64          `json:"-" gorm:"column:deleted_by;default:0"`
+	Deleted   bool           `json:"deleted,omitempty" gorm:"-"` // 讨论串中显示为占位符时为true
+	// 版主移除帖子的原因， 只有作者和版主可以看到这个帖子
+	RemovalReason string `json:"removal_reason,omitempty" gorm:"column:removal_reason"`
+	// 第一次发布的时间， 需要审核的帖子是审核通过的时间， 以前的帖子为空
+	PublishedTime *time.Time `json:"-" gorm:"column:published_time"`
+	// 草稿定时发布的时间， 为空时需要作者手动发布
+	PublishAt *time.Time `json:"publish_at,omitempty" gorm:"column:publish_at;index"`
+	Draft     bool       `json:"draft,omitempty" gorm:"-"` // 创建帖子时保存为草稿， 设置了publish_at时也是草稿
+	// 帖子的类型， 链接帖子的地址保存在帖子中， 图片和投票保存在单独的表中
+	Kind     string       `json:"kind" gorm:"column:kind;size:16;default:text"`
+	URL      string       `json:"url,omitempty" gorm:"column:url;size:2048"` // 规范化之后的链接地址
+	ImageIDs []string     `json:"image_ids,omitempty" gorm:"-"`              // 创建图片帖子时上传的图片id
+	Images   []*PostImage `json:"images,omitempty" gorm:"-"`
+	Poll     *Poll        `json:"poll,omitempty" gorm:"-"`
+	// 链接地址的sha1， 同一个社区中不能重复， 不是链接帖子、被移除和已经删除的帖子为空， 不占用这个链接
+	URLHash *string `json:"-" gorm:"column:url_hash;size:40;uniqueIndex:idx_community_url_hash"`
+	// content是markdown源码， content_html是渲染和过滤之后的HTML， 不保存在数据库中
+	ContentHTML string `json:"content_html" gorm:"-"`
+}
+
+// Listed: 帖子是否出现在redis的各个列表中
+func (p *Post) Listed() bool {
+	return p.Status == PostStatusPublished || p.Status == PostStatusLocked
+}
+
+// ListedTime: 帖子在按时间排序的列表中使用的时间
+func (p *Post) ListedTime() time.Time {
+	if p.PublishedTime != nil {
+		return *p.PublishedTime
+	}
+	return p.CreateTime
 }
 
 // 帖子详情结构的结构体 设置api接口专用的模型
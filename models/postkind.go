@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// 帖子的类型， 以前的帖子都是文字帖子
+const (
+	PostKindText  = "text"
+	PostKindLink  = "link"
+	PostKindImage = "image"
+	PostKindPoll  = "poll"
+)
+
+// PostImage: 图片帖子的图片， 先上传图片， 创建帖子时再关联到帖子， PostID为0表示还没有关联
+type PostImage struct {
+	ID         int64     `json:"image_id,string" gorm:"primaryKey;column:image_id"`
+	PostID     int64     `json:"-" gorm:"column:post_id;index"`
+	UploaderID int64     `json:"-" gorm:"column:uploader_id;index"`
+	Position   int       `json:"-" gorm:"column:position"` // 在帖子中的顺序
+	Path       string    `json:"path" gorm:"column:path;not null"`
+	Width      int       `json:"width" gorm:"column:width"`
+	Height     int       `json:"height" gorm:"column:height"`
+	CreateTime time.Time `json:"-" gorm:"column:create_time;autoCreateTime"`
+}
+
+// Poll: 投票帖子的设置， 每个用户只能投一次， 投票记录保存在redis中
+type Poll struct {
+	PostID    int64         `json:"-" gorm:"primaryKey;autoIncrement:false;column:post_id"`
+	Multiple  bool          `json:"multiple" gorm:"column:multiple"` // 是否可以多选
+	CloseTime time.Time     `json:"close_time" gorm:"column:close_time"`
+	Options   []*PollOption `json:"options" gorm:"-"`
+	Voters    int64         `json:"voters" gorm:"-"`          // 参与投票的人数
+	Voted     []int         `json:"voted,omitempty" gorm:"-"` // 当前用户选择的选项
+	Closed    bool          `json:"closed" gorm:"-"`
+}
+
+// PollOption: 投票的选项， 使用在投票中的位置作为选项的编号， 从0开始
+type PollOption struct {
+	PostID   int64  `json:"-" gorm:"primaryKey;autoIncrement:false;column:post_id"`
+	Position int    `json:"option" gorm:"primaryKey;autoIncrement:false;column:position"`
+	Text     string `json:"text" gorm:"column:text;size:100;not null"`
+	Votes    int64  `json:"votes" gorm:"-"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// PublicProfile: 其他用户可以看到的个人信息， 不包括邮箱、手机号码等隐私信息
+type PublicProfile struct {
+	ID           int64     `json:"user_id,string"`
+	Username     string    `json:"username"`
+	Avatar       string    `json:"avatar"`
+	City         string    `json:"city"`
+	Introduction string    `json:"introduction"`
+	JoinedAt     time.Time `json:"joined_at"`
+	Karma        int64     `json:"karma"` // 帖子karma和评论karma的和
+	PostKarma    int64     `json:"post_karma"`
+	CommentKarma int64     `json:"comment_karma"`
+	Followers    int64     `json:"followers"`  // 粉丝数量
+	Following    int64     `json:"following"`  // 关注的用户数量
+	HideVotes    bool      `json:"hide_votes"` // 为true时其他用户不能查看点赞过的帖子
+}
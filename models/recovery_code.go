@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// RecoveryCode: 两步验证的恢复码， 丢失验证器时用来代替TOTP验证码， 每个只能使用一次
+type RecoveryCode struct {
+	ID         int64      `gorm:"primaryKey;autoIncrement;column:id"`
+	UserID     int64      `gorm:"column:user_id;index"`
+	CodeHash   string     `gorm:"column:code_hash;size:64"` // 只保存sha256之后的结果
+	UsedAt     *time.Time `gorm:"column:used_at"`
+	CreateTime time.Time  `gorm:"column:create_time;autoCreateTime"`
+}
+
+// TOTPEnrollment: 开启两步验证时返回给前端的信息
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"` // otpauth://地址， 前端渲染成二维码
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/diff"
+)
+
+// PostRevision: 帖子的每一个版本， 第一次编辑时会先保存原始的版本
+type PostRevision struct {
+	ID         int64     `json:"revision_id,string" gorm:"primaryKey;column:revision_id"`
+	PostID     int64     `json:"post_id,string" gorm:"column:post_id;uniqueIndex:idx_post_version"`
+	Version    int       `json:"version" gorm:"column:version;uniqueIndex:idx_post_version"` // 从1开始， 1是原始版本
+	EditorID   int64     `json:"editor_id,string" gorm:"column:editor_id"`
+	Title      string    `json:"title" gorm:"column:title;not null"`
+	Content    string    `json:"content" gorm:"column:content;type:text;not null"`
+	VoteNum    int64     `json:"vote_num" gorm:"column:vote_num"` // 保存这个版本时帖子的赞成票数量
+	CreateTime time.Time `json:"create_time" gorm:"column:create_time"`
+}
+
+// PostRevisionDiff: 某个版本和上一个版本的区别
+type PostRevisionDiff struct {
+	From    *PostRevision `json:"from"`
+	To      *PostRevision `json:"to"`
+	Title   []diff.Line   `json:"title"`
+	Content []diff.Line   `json:"content"`
+}
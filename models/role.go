@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// 站点级别的角色， 保存在用户表中
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin" // 站点管理员， 拥有所有社区的全部权限
+)
+
+// 社区级别的角色， 权限从低到高
+const (
+	CommunityRoleMember    = "member"
+	CommunityRoleModerator = "moderator"
+	CommunityRoleOwner     = "owner" // 社区的创建者
+)
+
+// communityRoleLevel: 社区角色的等级， 高等级拥有低等级的所有权限
+var communityRoleLevel = map[string]int{
+	CommunityRoleMember:    1,
+	CommunityRoleModerator: 2,
+	CommunityRoleOwner:     3,
+}
+
+// CommunityRoleLevel: 返回社区角色的等级， 未知的角色返回0
+func CommunityRoleLevel(role string) int {
+	return communityRoleLevel[role]
+}
+
+// CommunityMember: 用户在某个社区中的角色
+type CommunityMember struct {
+	CommunityID int64     `json:"community_id,string" gorm:"primaryKey;autoIncrement:false;column:community_id"`
+	UserID      int64     `json:"user_id,string" gorm:"primaryKey;autoIncrement:false;column:user_id;index"`
+	Role        string    `json:"role" gorm:"column:role;size:16"`
+	CreateTime  time.Time `json:"-" gorm:"column:create_time;autoCreateTime"`
+	UpdatedTime time.Time `json:"-" gorm:"column:updated_time;autoUpdateTime"`
+}
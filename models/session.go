@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Device: 登录时客户端的设备信息
+type Device struct {
+	UserAgent string
+	IP        string
+}
+
+// Session: 用户的一个登录会话， 对应一台登录的设备
+type Session struct {
+	ID         string    `json:"session_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"` // 是否是发起当前请求的会话
+}
@@ -2,16 +2,37 @@ package models
 
 import "time"
 
+// DeletedUserID: 注销账号之后， 帖子和评论都转给这个用户， 不再保留原来的作者id
+const DeletedUserID int64 = 1
+
 type User struct {
 	ID           int64     `json:"user_id,string" gorm:"primaryKey;column:user_id"`
 	Username     string    `json:"username" gorm:"column:username"`
 	Password     string    `json:"-" gorm:"column:password"` //  使用 “- ” 使得在序列化的结果中不会出现当前字段
+	Salt         string    `json:"-" gorm:"column:salt"`     // 每个用户单独的盐， 旧的md5密码没有盐
 	Email        string    `json:"email" gorm:"column:email"`
 	Phone        string    `json:"phone" gorm:"column:phone"`
 	City         string    `json:"city" gorm:"column:city"`
 	Introduction string    `json:"introduction" gorm:"column:introduction"`
 	Avatar       string    `json:"avatar" gorm:"column:avatar"`
-	Token        string    // 注意：此字段没有json或gorm标签
+	TOTPSecret   string    `json:"-" gorm:"column:totp_secret"`             // 两步验证的密钥， 确认之前也会先保存在这里
+	TOTPEnabled  bool      `json:"totp_enabled" gorm:"column:totp_enabled"` // 是否开启了两步验证
+	Role         string    `json:"role" gorm:"column:role;default:user"`    // 站点级别的角色
+	Token        string    `json:"-" gorm:"-"`                              // 只在旧的登录接口中临时保存access token， 不能序列化， 也不保存到数据库
 	CreateTime   time.Time `json:"-" gorm:"column:create_time;autoCreateTime"`
 	UpdatedTime  time.Time `json:"-" gorm:"column:updated_time;autoUpdateTime"`
+	// 申请注销账号之后的删除时间， 在这之前重新登录会取消注销
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty" gorm:"column:deletion_scheduled_at;index"`
+	// 账号完成匿名化的时间， 关注、邀请等记录还会指向这条记录， 所以不能直接删除
+	DeletedTime *time.Time `json:"-" gorm:"column:deleted_time"`
+	// 邮箱和手机号码通过验证码验证的时间， 没有验证过时为空
+	EmailVerifiedAt *time.Time `json:"email_verified_at" gorm:"column:email_verified_at"`
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at" gorm:"column:phone_verified_at"`
+	// 隐私设置： 是否对其他用户隐藏点赞过的帖子
+	HideVotes bool `json:"hide_votes" gorm:"column:hide_votes;default:false"`
+	// 从投票统计的karma， 实时的值保存在redis中， 定期同步到这里
+	PostKarma    int64 `json:"post_karma" gorm:"column:post_karma;default:0"`
+	CommentKarma int64 `json:"comment_karma" gorm:"column:comment_karma;default:0"`
+	// 使用邀请码注册时， 生成邀请码的用户
+	InviterID int64 `json:"-" gorm:"column:inviter_id;default:0"`
 }
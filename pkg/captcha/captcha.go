@@ -52,3 +52,8 @@ func (c *Captcha) GenerateCaptcha() (id, b64s, answer string, err error) {
 func (c *Captcha) VerifyCaptcha(id, answer string) (match bool) {
 	return c.Base64Captcha.Verify(id, answer, false)
 }
+
+// VerifyCaptchaOnce: 验证之后删除图形验证码， 不管是否正确都只能使用一次
+func (c *Captcha) VerifyCaptchaOnce(id, answer string) (match bool) {
+	return c.Base64Captcha.Verify(id, answer, true)
+}
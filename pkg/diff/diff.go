@@ -0,0 +1,121 @@
+package diff
+
+import "strings"
+
+/*
+	按行比较两段文本， 使用最长公共子序列(LCS)， 结果和 diff 命令的输出类似
+	帖子一般不会很长， 行数过多时不再逐行比较， 直接返回整段删除和整段新增
+*/
+
+// 每一行的操作
+const (
+	OpEqual  = "="
+	OpDelete = "-"
+	OpInsert = "+"
+)
+
+const maxCells = 4_000_000 // LCS表格的最大大小， 大约 2000 行 x 2000 行
+
+// Line: 比较结果中的一行
+type Line struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Lines: 比较a和b， 返回把a变成b需要的逐行操作
+func Lines(a, b string) []Line {
+	return compare(splitLines(a), splitLines(b))
+}
+
+// Changed: 比较结果中是否有修改
+func Changed(lines []Line) bool {
+	for _, l := range lines {
+		if l.Op != OpEqual {
+			return true
+		}
+	}
+	return false
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
+
+func compare(a, b []string) []Line {
+	// 去掉相同的开头和结尾， 减小LCS表格
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	result := make([]Line, 0, len(a)+len(b))
+	for _, s := range a[:prefix] {
+		result = append(result, Line{Op: OpEqual, Text: s})
+	}
+	result = append(result, lcs(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, s := range a[len(a)-suffix:] {
+		result = append(result, Line{Op: OpEqual, Text: s})
+	}
+	return result
+}
+
+func lcs(a, b []string) []Line {
+	n, m := len(a), len(b)
+	result := make([]Line, 0, n+m)
+	if n*m > maxCells {
+		for _, s := range a {
+			result = append(result, Line{Op: OpDelete, Text: s})
+		}
+		for _, s := range b {
+			result = append(result, Line{Op: OpInsert, Text: s})
+		}
+		return result
+	}
+
+	// table[i][j]: a[i:]和b[j:]的最长公共子序列长度
+	table := make([][]int, n+1)
+	for i := range table {
+		table[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else if table[i+1][j] >= table[i][j+1] {
+				table[i][j] = table[i+1][j]
+			} else {
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			result = append(result, Line{Op: OpEqual, Text: a[i]})
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			result = append(result, Line{Op: OpDelete, Text: a[i]})
+			i++
+		default:
+			result = append(result, Line{Op: OpInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		result = append(result, Line{Op: OpDelete, Text: a[i]})
+	}
+	for ; j < m; j++ {
+		result = append(result, Line{Op: OpInsert, Text: b[j]})
+	}
+	return result
+}
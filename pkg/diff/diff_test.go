@@ -0,0 +1,48 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b string
+		want []Line
+	}{
+		{"equal", "a\nb", "a\nb", []Line{{OpEqual, "a"}, {OpEqual, "b"}}},
+		{"empty to text", "", "a", []Line{{OpInsert, "a"}}},
+		{"text to empty", "a", "", []Line{{OpDelete, "a"}}},
+		{
+			name: "replace middle line",
+			a:    "title\nold line\nend",
+			b:    "title\nnew line\nend",
+			want: []Line{{OpEqual, "title"}, {OpDelete, "old line"}, {OpInsert, "new line"}, {OpEqual, "end"}},
+		},
+		{
+			name: "insert and delete",
+			a:    "a\nb\nc\nd",
+			b:    "a\nc\nd\ne",
+			want: []Line{{OpEqual, "a"}, {OpDelete, "b"}, {OpEqual, "c"}, {OpEqual, "d"}, {OpInsert, "e"}},
+		},
+		{"crlf", "a\r\nb", "a\nb", []Line{{OpEqual, "a"}, {OpEqual, "b"}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := Lines(c.a, c.b)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("want %v, got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestChanged(t *testing.T) {
+	if Changed(Lines("a\nb", "a\nb")) {
+		t.Fatal("same text should not be changed")
+	}
+	if !Changed(Lines("a", "b")) {
+		t.Fatal("different text should be changed")
+	}
+}
@@ -2,9 +2,11 @@ package file
 
 import (
 	"fmt"
+	"image"
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/disintegration/imaging"
@@ -12,38 +14,100 @@ import (
 	"github.com/xiaorui/reddit-async/reddit-backend/pkg/helpers"
 )
 
-// SaveUploadAvatar：保存用户头像
+// 上传的文件都保存在public目录下
+const publicPath = "public"
+
+// SaveUploadAvatar：保存用户头像， 裁剪成256x256的缩略图
 func SaveUploadAvatar(ctx *gin.Context, file *multipart.FileHeader, userID int64) (path string, err error) {
-	publicPath := "public"
-	dirName := fmt.Sprintf("/uploads/avatar/%s/%s/",
-		time.Now().In(time.Local), fmt.Sprintf("%v", userID))
-	os.MkdirAll(publicPath+dirName, 0755)
+	dirName := fmt.Sprintf("/uploads/avatar/%s/%v/", time.Now().Format("2006-01-02"), userID)
+	path, _, _, err = saveResizedImage(ctx, file, dirName, func(img image.Image) *image.NRGBA {
+		return imaging.Thumbnail(img, 256, 256, imaging.Lanczos)
+	})
+	return path, err
+}
+
+// saveResizedImage: 先保存原图， 处理之后保存新的图片， 不管是否成功都删除原图
+// dirName是public目录下的路径， 返回的路径不包含public， 和静态文件的访问路径一致
+func saveResizedImage(ctx *gin.Context, file *multipart.FileHeader, dirName string, resize func(image.Image) *image.NRGBA) (path string, width, height int, err error) {
+	if err = os.MkdirAll(publicPath+dirName, 0755); err != nil {
+		return "", 0, 0, err
+	}
 
 	// 保存文件
-	filename := helpers.RandomString(16) + filepath.Ext(file.Filename)
-	avatarPath := publicPath + dirName + filename
-	if err := ctx.SaveUploadedFile(file, avatarPath); err != nil {
-		return "", err
+	originalPath := publicPath + dirName + helpers.RandomString(16) + filepath.Ext(file.Filename)
+	if err = ctx.SaveUploadedFile(file, originalPath); err != nil {
+		return "", 0, 0, err
 	}
+	defer os.Remove(originalPath)
 
-	// 修剪图片
-	img, err := imaging.Open(avatarPath, imaging.AutoOrientation(true))
+	// 处理图片
+	img, err := imaging.Open(originalPath, imaging.AutoOrientation(true))
 	if err != nil {
-		return "", err
+		return "", 0, 0, err
 	}
-	resizeAvatar := imaging.Thumbnail(img, 256, 256, imaging.Lanczos)
-	resizeFilename := helpers.RandomString(16) + filepath.Ext(file.Filename)
-	resizeAvatarPath := publicPath + dirName + resizeFilename
-	err = imaging.Save(resizeAvatar, resizeAvatarPath)
-	if err != nil {
-		return "", err
+	resized := resize(img)
+	path = dirName + helpers.RandomString(16) + filepath.Ext(file.Filename)
+	if err = imaging.Save(resized, publicPath+path); err != nil {
+		return "", 0, 0, err
 	}
+	bounds := resized.Bounds()
+	return path, bounds.Dx(), bounds.Dy(), nil
+}
+
+// localPath: 把返回给客户端的路径转换成磁盘上的路径， 以前保存的路径带有public前缀
+// 不在public目录下的路径返回空字符串
+func localPath(path string) string {
+	clean := filepath.Clean(path)
+	if !strings.HasPrefix(clean, publicPath+string(filepath.Separator)) {
+		clean = filepath.Join(publicPath, clean)
+	}
+	if !strings.HasPrefix(clean, publicPath+string(filepath.Separator)) {
+		return ""
+	}
+	return clean
+}
 
-	// 如果剪切成功则删除旧的文件
-	err = os.Remove(avatarPath)
+// DeleteUserAvatars: 删除用户上传过的所有头像
+func DeleteUserAvatars(userID int64, avatar string) error {
+	// 头像保存在 public/uploads/avatar/<时间>/<用户id>/ 目录下
+	dirs, err := filepath.Glob(fmt.Sprintf(publicPath+"/uploads/avatar/*/%d", userID))
 	if err != nil {
-		return "", err
+		return err
 	}
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	// 用户信息中记录的头像可能不在上面的目录中， 只删除public目录下的文件
+	if avatar != "" {
+		return removeFile(avatar)
+	}
+	return nil
+}
+
+// DeletePostImage: 删除图片帖子的图片文件
+func DeletePostImage(path string) error {
+	return removeFile(path)
+}
+
+// removeFile: 删除public目录下的文件， 文件不存在时不返回错误
+func removeFile(path string) error {
+	local := localPath(path)
+	if local == "" {
+		return nil
+	}
+	if err := os.Remove(local); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
 
-	return avatarPath, nil
+// SavePostImage: 保存图片帖子的图片， 超过最大尺寸的图片会缩小
+// 返回缩小之后的图片的路径和尺寸
+func SavePostImage(ctx *gin.Context, file *multipart.FileHeader, userID int64) (path string, width, height int, err error) {
+	dirName := fmt.Sprintf("/uploads/posts/%s/%v/", time.Now().Format("2006-01-02"), userID)
+	return saveResizedImage(ctx, file, dirName, func(img image.Image) *image.NRGBA {
+		return imaging.Fit(img, 2048, 2048, imaging.Lanczos)
+	})
 }
@@ -0,0 +1,23 @@
+package file
+
+import "testing"
+
+func TestLocalPath(t *testing.T) {
+	cases := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"url path", "/uploads/posts/2026-10-18/1/a.png", "public/uploads/posts/2026-10-18/1/a.png"},
+		{"old public path", "public/uploads/avatar/x/1/a.png", "public/uploads/avatar/x/1/a.png"},
+		{"escape public", "../etc/passwd", ""},
+		{"escape from public", "public/../../etc/passwd", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := localPath(c.path); got != c.want {
+				t.Fatalf("want %q, got %q", c.want, got)
+			}
+		})
+	}
+}
@@ -1,44 +1,84 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
-	jwtpkg "github.com/golang-jwt/jwt/v5"
-	"strings"
 	"time"
+
+	jwtpkg "github.com/golang-jwt/jwt/v5"
 )
 
-var mySecret = []byte("这是一个加盐句子")
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
 
 type MyClaims struct {
-	UserID   int64  `json:"userID"`
-	Username string `json:"username"`
+	UserID    int64  `json:"userID"`
+	Username  string `json:"username"`
+	SessionID string `json:"sid"` // 登录会话id， 同一次登录刷新出来的token属于同一个会话
+	TokenType string `json:"typ"` // 区分access token和refresh token， 避免refresh token被当作access token使用
+	jwtpkg.RegisteredClaims
+}
+
+// RefreshClaims: refresh token的声明， 绑定了用户和登录会话
+type RefreshClaims struct {
+	UserID    int64  `json:"userID"`
+	SessionID string `json:"sid"`
+	TokenType string `json:"typ"`
 	jwtpkg.RegisteredClaims
 }
 
-func keyFunc(_ *jwtpkg.Token) (i interface{}, err error) {
-	return mySecret, nil
+// NewSessionID: 生成新的登录会话id
+func NewSessionID() string {
+	return newTokenID()
 }
 
-// 生成JWT
-// GenToken 生成JWT
-func GenToken(userID int64, username string) (string, string, error) {
+// GenAccessToken 生成access token
+func GenAccessToken(userID int64, username, sessionID string) (string, error) {
 	// 创建一个我们自己的声明
 	claims := MyClaims{
 		userID,
 		username, // 自定义字段
+		sessionID,
+		tokenTypeAccess,
+		jwtpkg.RegisteredClaims{
+			ExpiresAt: jwtpkg.NewNumericDate(time.Now().Add(AccessTokenExpire())),
+			IssuedAt:  jwtpkg.NewNumericDate(time.Now()), // 签发时间， 用于判断是否早于用户的token水位线
+			ID:        newTokenID(),                      // jti， 注销时根据它把token加入黑名单
+			Issuer:    issuer(),                          // 签发人
+		},
+	}
+	// 使用当前的签名密钥签名并获得完整的编码后的字符串token
+	return sign(claims)
+}
+
+// GenRefreshToken 生成refresh token， 同时返回声明， 调用方需要在服务端记录它的jti
+func GenRefreshToken(userID int64, sessionID string) (string, *RefreshClaims, error) {
+	rc := &RefreshClaims{
+		userID,
+		sessionID,
+		tokenTypeRefresh,
 		jwtpkg.RegisteredClaims{
-			ExpiresAt: jwtpkg.NewNumericDate(time.Now().Add(time.Hour * 24)),
-			Issuer:    "my-project", // 签发人
+			ExpiresAt: jwtpkg.NewNumericDate(time.Now().Add(RefreshTokenExpire())),
+			IssuedAt:  jwtpkg.NewNumericDate(time.Now()),
+			ID:        newTokenID(),
+			Issuer:    issuer(),
 		},
 	}
-	// 使用指定的签名方法创建签名对象
-	accessToken, err := jwtpkg.NewWithClaims(jwtpkg.SigningMethodHS256, claims).SignedString(mySecret)
-	// 使用指定的secret签名并获得完整的编码后的字符串token
-	refreshToken, err := jwtpkg.NewWithClaims(jwtpkg.SigningMethodHS256, jwtpkg.RegisteredClaims{
-		ExpiresAt: jwtpkg.NewNumericDate(time.Now().Add(time.Hour * 30)),
-		Issuer:    "my-project",
-	}).SignedString(mySecret)
-	return accessToken, refreshToken, err
+	refreshToken, err := sign(rc)
+	if err != nil {
+		return "", nil, err
+	}
+	return refreshToken, rc, nil
+}
+
+// newTokenID: 生成随机的token id
+func newTokenID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
 }
 
 // ParseToken 解析JWT
@@ -46,34 +86,27 @@ func ParseToken(tokenString string) (*MyClaims, error) {
 	// 解析token
 	// 如果是自定义Claim结构体则需要使用 ParseWithClaims 方法
 	var mc = new(MyClaims)
-	token, err := jwtpkg.ParseWithClaims(tokenString, mc, func(token *jwtpkg.Token) (i interface{}, err error) {
-		// 直接使用标准的Claim则可以直接使用Parse方法
-		//token, err := jwt.Parse(tokenString, func(token *jwt.Token) (i interface{}, err error) {
-		return mySecret, nil
-	})
+	// 根据token头部的kid选择密钥， 轮换期间新旧密钥签发的token都可以通过验证
+	token, err := jwtpkg.ParseWithClaims(tokenString, mc, keyFunc, jwtpkg.WithIssuer(issuer()))
 	if err != nil {
 		return nil, err
 	}
 	// 对token对象中的Claim进行类型断言
-	if token.Valid { // 校验token
+	if token.Valid && mc.TokenType == tokenTypeAccess { // 校验token
 		return mc, nil
 	}
 	return nil, errors.New("invalid token")
 }
 
-// RefreshToken: 刷新accessToken
-func RefreshToken(at, rt string) (accessToken, refreshToken string, err error) {
-	//  验证rt是否有效
-	if _, err = jwtpkg.Parse(rt, keyFunc); err != nil {
-		return
+// ParseRefreshToken 解析refresh token， 没有绑定用户和会话的旧token视为无效
+func ParseRefreshToken(tokenString string) (*RefreshClaims, error) {
+	var rc = new(RefreshClaims)
+	token, err := jwtpkg.ParseWithClaims(tokenString, rc, keyFunc, jwtpkg.WithIssuer(issuer()))
+	if err != nil {
+		return nil, err
 	}
-	// 从旧的claim中解析出数据
-	var claim MyClaims
-	_, err = jwtpkg.ParseWithClaims(at, &claim, keyFunc)
-	if strings.Contains(err.Error(), jwtpkg.ErrTokenExpired.Error()) { // 如果错误是过期所导致的， 如果不是则pass
-		// 如果是过期了
-		return GenToken(claim.UserID, claim.Username)
+	if !token.Valid || rc.TokenType != tokenTypeRefresh || rc.UserID == 0 || rc.SessionID == "" || rc.IssuedAt == nil {
+		return nil, errors.New("invalid token")
 	}
-
-	return
+	return rc, nil
 }
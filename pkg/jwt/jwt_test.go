@@ -0,0 +1,85 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xiaorui/reddit-async/reddit-backend/settings"
+)
+
+// writePrivateKey: 把私钥写成PKCS8格式的PEM文件
+func writePrivateKey(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("x509.MarshalPKCS8PrivateKey failed, err: %v\n", err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatalf("os.WriteFile failed, err: %v\n", err)
+	}
+	return path
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey := settings.JWTKeyConfig{ID: "old", Algorithm: AlgorithmHS256, Secret: "old-secret"}
+	newKey := settings.JWTKeyConfig{ID: "new", Algorithm: AlgorithmHS256, Secret: "new-secret"}
+
+	// 1. 使用旧密钥签发
+	err := Init(&settings.AuthConfig{SigningKeyID: "old", Keys: []settings.JWTKeyConfig{oldKey}})
+	assert.Nil(t, err)
+	token, err := GenAccessToken(1, "tester", "sid")
+	assert.Nil(t, err)
+
+	// 2. 切换到新密钥之后， 旧密钥签发的token仍然有效
+	err = Init(&settings.AuthConfig{SigningKeyID: "new", Keys: []settings.JWTKeyConfig{oldKey, newKey}})
+	assert.Nil(t, err)
+	mc, err := ParseToken(token)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), mc.UserID)
+
+	// 3. 删除旧密钥之后， 旧token失效
+	err = Init(&settings.AuthConfig{SigningKeyID: "new", Keys: []settings.JWTKeyConfig{newKey}})
+	assert.Nil(t, err)
+	_, err = ParseToken(token)
+	assert.NotNil(t, err)
+}
+
+func TestAsymmetricKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	keys := []settings.JWTKeyConfig{
+		{ID: "hs", Algorithm: AlgorithmHS256, Secret: "secret"},
+		{ID: "rs", Algorithm: AlgorithmRS256, PrivateKeyFile: writePrivateKey(t, rsaKey)},
+		{ID: "ed", Algorithm: AlgorithmEdDSA, PrivateKeyFile: writePrivateKey(t, edKey)},
+	}
+	for _, kid := range []string{"rs", "ed"} {
+		err := Init(&settings.AuthConfig{SigningKeyID: kid, Keys: keys})
+		assert.Nil(t, err)
+
+		refreshToken, rc, err := GenRefreshToken(1, "sid")
+		assert.Nil(t, err)
+		parsed, err := ParseRefreshToken(refreshToken)
+		assert.Nil(t, err)
+		assert.Equal(t, rc.ID, parsed.ID)
+
+		// refresh token不能当作access token使用
+		_, err = ParseToken(refreshToken)
+		assert.NotNil(t, err)
+	}
+
+	// JWKS中只包含非对称密钥
+	jwks := JWKS()
+	assert.Len(t, jwks, 2)
+	assert.Equal(t, "OKP", jwks[0].Kty)
+	assert.Equal(t, "RSA", jwks[1].Kty)
+}
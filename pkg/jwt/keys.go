@@ -0,0 +1,256 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	jwtpkg "github.com/golang-jwt/jwt/v5"
+	"github.com/xiaorui/reddit-async/reddit-backend/settings"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+var (
+	ErrorNoSigningKey = errors.New("没有配置用于签发token的密钥")
+	ErrorUnknownKey   = errors.New("未知的密钥")
+)
+
+// signingKey: 一个可以用来签名或者验证token的密钥
+type signingKey struct {
+	id         string
+	method     jwtpkg.SigningMethod
+	signKey    interface{} // 签名使用， 只用来验证的密钥为nil
+	verifyKey  interface{} // 验证使用
+	publicKey  crypto.PublicKey
+	asymmetric bool
+}
+
+// keySet: 当前生效的所有密钥， 签名只使用signing， 验证接受集合中的任意一个
+type keySet struct {
+	signing            *signingKey
+	keys               map[string]*signingKey
+	issuer             string
+	accessTokenExpire  time.Duration
+	refreshTokenExpire time.Duration
+}
+
+var (
+	mu      sync.RWMutex
+	current *keySet
+)
+
+// Init: 根据配置加载密钥集合
+func Init(cfg *settings.AuthConfig) error {
+	if cfg == nil {
+		return ErrorNoSigningKey
+	}
+	ks := &keySet{
+		keys:               make(map[string]*signingKey, len(cfg.Keys)),
+		issuer:             cfg.Issuer,
+		accessTokenExpire:  cfg.AccessTokenExpire,
+		refreshTokenExpire: cfg.RefreshTokenExpire,
+	}
+	if ks.issuer == "" {
+		ks.issuer = "my-project"
+	}
+	if ks.accessTokenExpire <= 0 {
+		ks.accessTokenExpire = time.Hour * 24
+	}
+	if ks.refreshTokenExpire <= 0 {
+		ks.refreshTokenExpire = time.Hour * 30
+	}
+	for _, kc := range cfg.Keys {
+		key, err := loadKey(kc)
+		if err != nil {
+			return fmt.Errorf("load jwt key %q failed: %w", kc.ID, err)
+		}
+		ks.keys[key.id] = key
+	}
+	signing, ok := ks.keys[cfg.SigningKeyID]
+	if !ok || signing.signKey == nil {
+		return ErrorNoSigningKey
+	}
+	ks.signing = signing
+
+	mu.Lock()
+	current = ks
+	mu.Unlock()
+	return nil
+}
+
+func loadKey(kc settings.JWTKeyConfig) (*signingKey, error) {
+	if kc.ID == "" {
+		return nil, errors.New("key id is required")
+	}
+	key := &signingKey{id: kc.ID}
+	switch kc.Algorithm {
+	case AlgorithmHS256:
+		if kc.Secret == "" {
+			return nil, errors.New("secret is required")
+		}
+		key.method = jwtpkg.SigningMethodHS256
+		key.signKey = []byte(kc.Secret)
+		key.verifyKey = []byte(kc.Secret)
+	case AlgorithmRS256:
+		key.method = jwtpkg.SigningMethodRS256
+		key.asymmetric = true
+		if kc.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			private, err := jwtpkg.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey, key.publicKey = private, &private.PublicKey
+		} else {
+			pem, err := os.ReadFile(kc.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			if key.publicKey, err = jwtpkg.ParseRSAPublicKeyFromPEM(pem); err != nil {
+				return nil, err
+			}
+		}
+		key.verifyKey = key.publicKey
+	case AlgorithmEdDSA:
+		key.method = jwtpkg.SigningMethodEdDSA
+		key.asymmetric = true
+		if kc.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			private, err := jwtpkg.ParseEdPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey, key.publicKey = private, private.(ed25519.PrivateKey).Public()
+		} else {
+			pem, err := os.ReadFile(kc.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			if key.publicKey, err = jwtpkg.ParseEdPublicKeyFromPEM(pem); err != nil {
+				return nil, err
+			}
+		}
+		key.verifyKey = key.publicKey
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", kc.Algorithm)
+	}
+	return key, nil
+}
+
+func getKeySet() *keySet {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// sign: 使用当前的签名密钥签发token， 并且在头部写入kid
+func sign(claims jwtpkg.Claims) (string, error) {
+	ks := getKeySet()
+	if ks == nil {
+		return "", ErrorNoSigningKey
+	}
+	token := jwtpkg.NewWithClaims(ks.signing.method, claims)
+	token.Header["kid"] = ks.signing.id
+	return token.SignedString(ks.signing.signKey)
+}
+
+// keyFunc: 根据头部的kid找到对应的密钥， 并且要求算法和密钥一致， 防止算法混淆攻击
+func keyFunc(token *jwtpkg.Token) (interface{}, error) {
+	ks := getKeySet()
+	if ks == nil {
+		return nil, ErrorUnknownKey
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrorUnknownKey
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+	}
+	return key.verifyKey, nil
+}
+
+// AccessTokenExpire: access token的有效期
+func AccessTokenExpire() time.Duration {
+	if ks := getKeySet(); ks != nil {
+		return ks.accessTokenExpire
+	}
+	return time.Hour * 24
+}
+
+// RefreshTokenExpire: refresh token的有效期
+func RefreshTokenExpire() time.Duration {
+	if ks := getKeySet(); ks != nil {
+		return ks.refreshTokenExpire
+	}
+	return time.Hour * 30
+}
+
+func issuer() string {
+	if ks := getKeySet(); ks != nil {
+		return ks.issuer
+	}
+	return "my-project"
+}
+
+// JWK: JSON Web Key， 只包含公钥信息
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA
+	E   string `json:"e,omitempty"`   // RSA
+	Crv string `json:"crv,omitempty"` // OKP
+	X   string `json:"x,omitempty"`   // OKP
+}
+
+// JWKS: 返回所有非对称密钥的公钥， 供下游服务验证token， HS256的密钥永远不会对外暴露
+func JWKS() []JWK {
+	ks := getKeySet()
+	if ks == nil {
+		return []JWK{}
+	}
+	jwks := make([]JWK, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		if !key.asymmetric {
+			continue
+		}
+		jwk := JWK{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		jwks = append(jwks, jwk)
+	}
+	sort.Slice(jwks, func(i, j int) bool { return jwks[i].Kid < jwks[j].Kid })
+	return jwks
+}
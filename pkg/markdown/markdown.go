@@ -0,0 +1,148 @@
+// Package markdown 把帖子和评论的markdown源码渲染为安全的HTML
+// 支持CommonMark， 以及表格、删除线、自动链接和 >!剧透!< 语法， 原始的HTML不会输出
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/xiaorui/reddit-async/reddit-backend/pkg/urlnorm"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Version: 渲染规则的版本， 修改了渲染或者过滤的规则之后需要加1， 让以前缓存的HTML失效
+const Version = 1
+
+// maxLinks: 每条内容最多提取的链接数量
+const maxLinks = 100
+
+// maxMentions: 每条内容最多提取的提及的用户数量
+const maxMentions = 50
+
+// mentionPattern: @用户名， 用户名和注册时的要求一致， 只有字母和数字， 3~20个字符
+// @前面是字母或数字时是邮件地址， 不算提及
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9])@([A-Za-z0-9]{3,20})\b`)
+
+var (
+	md     = newMarkdown()
+	policy = newPolicy()
+)
+
+func newMarkdown() goldmark.Markdown {
+	// 替换默认的引用块解析器， 行首的 >!剧透!< 不当作引用块
+	blocks := parser.DefaultBlockParsers()
+	for i, v := range blocks {
+		if bytes.Equal(v.Value.(parser.BlockParser).Trigger(), []byte{'>'}) {
+			blocks[i] = util.Prioritized(&blockquoteParser{v.Value.(parser.BlockParser)}, v.Priority)
+		}
+	}
+	p := parser.NewParser(
+		parser.WithBlockParsers(blocks...),
+		parser.WithInlineParsers(parser.DefaultInlineParsers()...),
+		parser.WithParagraphTransformers(parser.DefaultParagraphTransformers()...),
+		parser.WithInlineParsers(util.Prioritized(&spoilerParser{}, 500)),
+	)
+	return goldmark.New(
+		goldmark.WithParser(p),
+		goldmark.WithExtensions(
+			extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+			extension.Strikethrough,
+			extension.Linkify,
+		),
+		goldmark.WithRendererOptions(
+			renderer.WithNodeRenderers(util.Prioritized(&spoilerRenderer{}, 500)),
+		),
+	)
+}
+
+// newPolicy: goldmark默认已经不输出原始的HTML和危险的链接， 这里再过滤一次， 只保留允许的标签和属性
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^md-spoiler$`)).OnElements("span")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// Render: 渲染markdown源码， 返回过滤之后的HTML
+func Render(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return policy.Sanitize(buf.String()), nil
+}
+
+// Links: 提取内容中指向站外的链接和图片地址， 返回规范化之后的地址， 已经去重
+// 相对地址、邮件地址等无效的链接会被忽略
+func Links(source string) []string {
+	links, _ := Extract(source)
+	return links
+}
+
+// Extract: 遍历一次内容， 同时提取站外的链接和提及的用户名， 都已经去重
+// 链接和代码中的@不算提及
+func Extract(source string) (links, mentions []string) {
+	src := []byte(source)
+	doc := md.Parser().Parse(text.NewReader(src))
+	links = make([]string, 0)
+	mentions = make([]string, 0)
+	seen := make(map[string]bool)
+	seenMentions := make(map[string]bool)
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var raw []byte
+		switch node := n.(type) {
+		case *ast.Link:
+			raw = node.Destination
+		case *ast.Image:
+			raw = node.Destination
+		case *ast.AutoLink:
+			if node.AutoLinkType == ast.AutoLinkURL {
+				raw = node.URL(src)
+			}
+		case *ast.CodeSpan:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			if len(mentions) < maxMentions && !inLink(node) {
+				for _, m := range mentionPattern.FindAllSubmatch(node.Segment.Value(src), -1) {
+					name := string(m[1])
+					if !seenMentions[name] && len(mentions) < maxMentions {
+						seenMentions[name] = true
+						mentions = append(mentions, name)
+					}
+				}
+			}
+		}
+		if raw == nil || len(links) >= maxLinks {
+			return ast.WalkContinue, nil
+		}
+		u, err := urlnorm.Normalize(string(raw))
+		if err != nil || seen[u] {
+			return ast.WalkContinue, nil
+		}
+		seen[u] = true
+		links = append(links, u)
+		return ast.WalkContinue, nil
+	})
+	return links, mentions
+}
+
+// inLink: 文字是否是链接的文字
+func inLink(n ast.Node) bool {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if _, ok := p.(*ast.Link); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		source string
+		want   []string
+	}{
+		{"**bold** and *em*", []string{"<strong>bold</strong>", "<em>em</em>"}},
+		{"| a | b |\n|:--|--:|\n| 1 | 2 |", []string{"<table>", `<th align="left">a</th>`, `<td align="right">2</td>`}},
+		{"this is >!a secret!< ok", []string{`<span class="md-spoiler">a secret</span> ok`}},
+		{">!line spoiler!<", []string{`<p><span class="md-spoiler">line spoiler</span></p>`}},
+		{"> quote", []string{"<blockquote>"}},
+		{"visit www.example.com now", []string{`href="http://www.example.com"`, `rel="nofollow noopener"`}},
+		{"<https://example.com/a>", []string{`href="https://example.com/a"`}},
+		{"~~gone~~", []string{"<del>gone</del>"}},
+	}
+	for _, c := range cases {
+		got, err := Render(c.source)
+		if err != nil {
+			t.Fatalf("Render(%q): %v", c.source, err)
+		}
+		for _, want := range c.want {
+			if !strings.Contains(got, want) {
+				t.Fatalf("Render(%q) = %q, want it to contain %q", c.source, got, want)
+			}
+		}
+	}
+}
+
+func TestRenderSanitize(t *testing.T) {
+	cases := []string{
+		"<script>alert(1)</script>",
+		"hello <img src=x onerror=alert(1)>",
+		"[click](javascript:alert(1))",
+		"![x](javascript:alert(1))",
+		`<a href="https://example.com" onclick="alert(1)">x</a>`,
+		">!<script>alert(1)</script>!<",
+		"<span class=\"md-spoiler\" style=\"color:red\">x</span>",
+	}
+	for _, source := range cases {
+		got, err := Render(source)
+		if err != nil {
+			t.Fatalf("Render(%q): %v", source, err)
+		}
+		for _, bad := range []string{"<script", "onerror", "onclick", "javascript:", "style="} {
+			if strings.Contains(got, bad) {
+				t.Fatalf("Render(%q) = %q, contains %q", source, got, bad)
+			}
+		}
+	}
+}
+
+func TestLinks(t *testing.T) {
+	source := "see [a](https://Example.com/a/?utm_source=x) and ![img](http://img.example.com/1.png)\n\n" +
+		"also www.example.org, <https://example.com/a> and [local](/post/1) and <someone@example.com>"
+	want := []string{"https://example.com/a", "http://img.example.com/1.png", "http://www.example.org"}
+	if got := Links(source); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Links() = %q, want %q", got, want)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	source := "hi @alice and @bob, @alice again\n\n" +
+		"mail me at carol@example.com, `@dave` in code, [@erin](https://example.com) in a link, @x too short\n\n" +
+		"    @frank in a code block\n\n" +
+		"> @grace in a quote"
+	want := []string{"alice", "bob", "grace"}
+	if _, got := Extract(source); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Extract() mentions = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,87 @@
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	spoilerOpen  = []byte(">!")
+	spoilerClose = []byte("!<")
+)
+
+// KindSpoiler: 剧透节点的类型
+var KindSpoiler = ast.NewNodeKind("Spoiler")
+
+// Spoiler: >!剧透!< 中的内容， 只能在同一行中， 内容按照纯文本显示
+type Spoiler struct {
+	ast.BaseInline
+}
+
+func (n *Spoiler) Kind() ast.NodeKind {
+	return KindSpoiler
+}
+
+func (n *Spoiler) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// isSpoilerLine: 这一行是否以 >!剧透!< 开头
+func isSpoilerLine(line []byte) bool {
+	line = bytes.TrimLeft(line, " ")
+	return bytes.HasPrefix(line, spoilerOpen) && bytes.Index(line[len(spoilerOpen):], spoilerClose) > 0
+}
+
+// blockquoteParser: 行首是 >!剧透!< 时不当作引用块， 交给段落和spoilerParser处理
+type blockquoteParser struct {
+	parser.BlockParser
+}
+
+func (b *blockquoteParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	if line, _ := reader.PeekLine(); isSpoilerLine(line) {
+		return nil, parser.NoChildren
+	}
+	return b.BlockParser.Open(parent, reader, pc)
+}
+
+type spoilerParser struct{}
+
+func (s *spoilerParser) Trigger() []byte {
+	return []byte{'>'}
+}
+
+func (s *spoilerParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	if !bytes.HasPrefix(line, spoilerOpen) {
+		return nil
+	}
+	end := bytes.Index(line[len(spoilerOpen):], spoilerClose)
+	if end <= 0 {
+		return nil
+	}
+	start := segment.Start + len(spoilerOpen)
+	node := &Spoiler{}
+	node.AppendChild(node, ast.NewTextSegment(text.NewSegment(start, start+end)))
+	block.Advance(len(spoilerOpen) + end + len(spoilerClose))
+	return node
+}
+
+type spoilerRenderer struct{}
+
+func (r *spoilerRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindSpoiler, r.renderSpoiler)
+}
+
+func (r *spoilerRenderer) renderSpoiler(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(`<span class="md-spoiler">`)
+	} else {
+		_, _ = w.WriteString(`</span>`)
+	}
+	return ast.WalkContinue, nil
+}
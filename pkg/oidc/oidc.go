@@ -0,0 +1,295 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwtpkg "github.com/golang-jwt/jwt/v5"
+	"github.com/xiaorui/reddit-async/reddit-backend/settings"
+)
+
+/*
+	OpenID Connect 授权码模式 + PKCE
+	1. AuthCodeURL 生成跳转到提供方的登录地址， state/nonce/code_verifier 由调用方保存
+	2. 用户登录之后提供方带着code和state跳转回redirect_url
+	3. Exchange 使用code和code_verifier换取id_token
+	4. VerifyIDToken 使用提供方公布的公钥验证id_token， 得到用户信息
+*/
+
+const (
+	discoveryPath  = "/.well-known/openid-configuration"
+	keysRefreshGap = time.Minute // 遇到未知的kid时重新获取公钥， 两次获取之间至少间隔这么久
+	clockSkew      = time.Minute
+)
+
+var (
+	ErrorInvalidIDToken = errors.New("id_token无效")
+	ErrorInvalidNonce   = errors.New("id_token中的nonce不匹配")
+	ErrorUnknownKey     = errors.New("id_token使用了未知的密钥")
+)
+
+var defaultScopes = []string{"openid", "email", "profile"}
+
+// Claims: id_token中的用户信息
+type Claims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Picture           string
+}
+
+type idTokenClaims struct {
+	jwtpkg.RegisteredClaims
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     flexBool `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Picture           string   `json:"picture"`
+}
+
+// flexBool: 有些提供方的email_verified是字符串 "true"
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider: 一个OIDC提供方， 端点和公钥在第一次使用时获取并缓存
+type Provider struct {
+	cfg    settings.OIDCProviderConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	discovery     *discovery
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+func NewProvider(cfg settings.OIDCProviderConfig) *Provider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = defaultScopes
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	return &Provider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthCodeURL: 生成跳转到提供方登录页面的地址
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.cfg.ClientID)
+	v.Set("redirect_uri", p.cfg.RedirectURL)
+	v.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", CodeChallengeS256(codeVerifier))
+	v.Set("code_challenge_method", "S256")
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + v.Encode(), nil
+}
+
+// Exchange: 使用授权码换取id_token
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oidc token endpoint returned %d: %s", resp.StatusCode, body)
+	}
+	var res struct {
+		IDToken string `json:"id_token"`
+	}
+	if err = json.Unmarshal(body, &res); err != nil {
+		return "", err
+	}
+	if res.IDToken == "" {
+		return "", ErrorInvalidIDToken
+	}
+	return res.IDToken, nil
+}
+
+// VerifyIDToken: 验证id_token的签名、签发方、受众、有效期和nonce
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*Claims, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := new(idTokenClaims)
+	_, err = jwtpkg.ParseWithClaims(raw, c, func(t *jwtpkg.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.getKey(ctx, d, kid)
+	},
+		jwtpkg.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "EdDSA"}),
+		jwtpkg.WithIssuer(d.Issuer),
+		jwtpkg.WithAudience(p.cfg.ClientID),
+		jwtpkg.WithExpirationRequired(),
+		jwtpkg.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidIDToken, err)
+	}
+	if c.Subject == "" {
+		return nil, ErrorInvalidIDToken
+	}
+	if c.Nonce != nonce {
+		return nil, ErrorInvalidNonce
+	}
+	return &Claims{
+		Subject:           c.Subject,
+		Email:             c.Email,
+		EmailVerified:     bool(c.EmailVerified),
+		Name:              c.Name,
+		PreferredUsername: c.PreferredUsername,
+		Picture:           c.Picture,
+	}, nil
+}
+
+// NewRandomString: 生成state、nonce和code_verifier使用的随机字符串
+func NewRandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallengeS256: PKCE的code_challenge
+func CodeChallengeS256(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (p *Provider) getDiscovery(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	d := new(discovery)
+	if err := p.getJSON(ctx, p.cfg.Issuer+discoveryPath, d); err != nil {
+		return nil, err
+	}
+	// 提供方返回的issuer必须和配置的一致， 否则可能被冒充
+	if strings.TrimSuffix(d.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc issuer mismatch: want %q, got %q", p.cfg.Issuer, d.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("oidc discovery document is incomplete")
+	}
+	p.discovery = d
+	return d, nil
+}
+
+// getKey: 按照kid获取验证id_token的公钥， 提供方轮换密钥之后重新获取
+func (p *Provider) getKey(ctx context.Context, d *discovery, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < keysRefreshGap {
+		return nil, ErrorUnknownKey
+	}
+	set := new(jwkSet)
+	if err := p.getJSON(ctx, d.JWKSURI, set); err != nil {
+		return nil, err
+	}
+	p.keys = set.publicKeys()
+	p.keysFetchedAt = time.Now()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, ErrorUnknownKey
+}
+
+// lookupKey: 没有kid时只有一个密钥才能确定使用哪个
+func (p *Provider) lookupKey(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: GET %s returned %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
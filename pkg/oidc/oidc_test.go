@@ -0,0 +1,177 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	jwtpkg "github.com/golang-jwt/jwt/v5"
+	"github.com/xiaorui/reddit-async/reddit-backend/settings"
+)
+
+// mockProvider: 本地的OIDC提供方， 只实现授权码模式需要的端点
+type mockProvider struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+	// 授权时记录的code_challenge和nonce， 换取token时校验
+	challenge string
+	nonce     string
+	audience  string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockProvider{key: key, clientID: "client-1"}
+	m.audience = m.clientID
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(discovery{
+			Issuer:                m.server.URL,
+			AuthorizationEndpoint: m.server.URL + "/authorize",
+			TokenEndpoint:         m.server.URL + "/token",
+			JWKSURI:               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jwkSet{Keys: []jwk{{
+			Kty: "RSA",
+			Kid: "mock-1",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if id != m.clientID || secret != "secret" || r.FormValue("code") != "code-1" ||
+			CodeChallengeS256(r.FormValue("code_verifier")) != m.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		now := time.Now()
+		token := jwtpkg.NewWithClaims(jwtpkg.SigningMethodRS256, idTokenClaims{
+			RegisteredClaims: jwtpkg.RegisteredClaims{
+				Issuer:    m.server.URL,
+				Subject:   "user-42",
+				Audience:  jwtpkg.ClaimStrings{m.audience},
+				IssuedAt:  jwtpkg.NewNumericDate(now),
+				ExpiresAt: jwtpkg.NewNumericDate(now.Add(time.Hour)),
+			},
+			Nonce:         m.nonce,
+			Email:         "alice@example.com",
+			EmailVerified: true,
+			Name:          "Alice",
+		})
+		token.Header["kid"] = "mock-1"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// authorize: 模拟用户在提供方完成登录
+func (m *mockProvider) authorize(t *testing.T, authURL string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != m.clientID {
+		t.Fatalf("unexpected auth url: %s", authURL)
+	}
+	m.challenge = q.Get("code_challenge")
+	m.nonce = q.Get("nonce")
+}
+
+func (m *mockProvider) provider() *Provider {
+	return NewProvider(settings.OIDCProviderConfig{
+		Name:         "mock",
+		Issuer:       m.server.URL,
+		ClientID:     m.clientID,
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+	})
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	m := newMockProvider(t)
+	p := m.provider()
+	ctx := context.Background()
+
+	verifier, _ := NewRandomString()
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.authorize(t, authURL)
+
+	// 错误的code_verifier不能换取token
+	if _, err = p.Exchange(ctx, "code-1", "wrong-verifier"); err == nil {
+		t.Fatal("exchange with wrong code_verifier should fail")
+	}
+
+	idToken, err := p.Exchange(ctx, "code-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := p.VerifyIDToken(ctx, idToken, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-42" || claims.Email != "alice@example.com" || !claims.EmailVerified || claims.Name != "Alice" {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+
+	if _, err = p.VerifyIDToken(ctx, idToken, "nonce-2"); !errors.Is(err, ErrorInvalidNonce) {
+		t.Fatalf("want ErrorInvalidNonce, got %v", err)
+	}
+}
+
+func TestVerifyIDTokenAudience(t *testing.T) {
+	m := newMockProvider(t)
+	m.audience = "another-client"
+	p := m.provider()
+	ctx := context.Background()
+
+	verifier, _ := NewRandomString()
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.authorize(t, authURL)
+	idToken, err := p.Exchange(ctx, "code-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.VerifyIDToken(ctx, idToken, "nonce-1"); !errors.Is(err, ErrorInvalidIDToken) {
+		t.Fatalf("want ErrorInvalidIDToken, got %v", err)
+	}
+}
+
+func TestCodeChallengeS256(t *testing.T) {
+	// RFC 7636 附录B
+	got := CodeChallengeS256("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Fatalf("want %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,47 @@
+package password
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id: 默认的密码加密算法
+type Argon2id struct {
+	Time    uint32
+	Memory  uint32 // 单位是KiB
+	Threads uint8
+	KeyLen  uint32
+}
+
+func (a *Argon2id) Algorithm() string {
+	return AlgorithmArgon2id
+}
+
+// Hash: 编码格式为 m=<memory>,t=<time>,p=<threads>$<hash>， 参数跟着密码保存， 以后调整参数不影响旧密码
+func (a *Argon2id) Hash(password string, salt []byte) (string, error) {
+	key := argon2.IDKey([]byte(password), salt, a.Time, a.Memory, a.Threads, a.KeyLen)
+	return fmt.Sprintf("m=%d,t=%d,p=%d$%s",
+		a.Memory, a.Time, a.Threads, base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a *Argon2id) Verify(password string, salt []byte, encoded string) (bool, error) {
+	parts := strings.SplitN(encoded, separator, 2)
+	if len(parts) != 2 {
+		return false, fmt.Errorf("invalid argon2id hash")
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[0], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, err
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false, err
+	}
+	got := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(got, want) == 1, nil
+}
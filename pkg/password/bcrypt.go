@@ -0,0 +1,29 @@
+package password
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt: bcrypt会在结果中保存自己的盐， 所以这里不使用外部传入的盐
+type Bcrypt struct {
+	Cost int
+}
+
+func (b *Bcrypt) Algorithm() string {
+	return AlgorithmBcrypt
+}
+
+func (b *Bcrypt) Hash(password string, _ []byte) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	return string(hashed), err
+}
+
+func (b *Bcrypt) Verify(password string, _ []byte, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,27 @@
+package password
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+const legacySecret = "xiaorui.com"
+
+// legacyMD5: 旧版本使用的md5加密， 只用来校验还没有迁移的密码， 校验成功后会使用新算法重新加密
+type legacyMD5 struct{}
+
+func (l *legacyMD5) Algorithm() string {
+	return AlgorithmMD5
+}
+
+func (l *legacyMD5) Hash(password string, _ []byte) (string, error) {
+	h := md5.New()
+	h.Write([]byte(legacySecret))
+	return hex.EncodeToString(h.Sum([]byte(password))), nil
+}
+
+func (l *legacyMD5) Verify(password string, salt []byte, encoded string) (bool, error) {
+	hashed, _ := l.Hash(password, salt)
+	return subtle.ConstantTimeCompare([]byte(hashed), []byte(encoded)) == 1, nil
+}
@@ -0,0 +1,117 @@
+package password
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/xiaorui/reddit-async/reddit-backend/settings"
+)
+
+/*
+	存储格式: <算法名>$<算法自己的编码>, 比如 argon2id$m=65536,t=1,p=4$<hash>
+	盐单独保存在用户表的 salt 字段中, 旧的 md5 密码没有算法前缀
+*/
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmMD5      = "md5" // 旧的加密方式， 只用于校验， 不再用于生成新的密码
+
+	defaultAlgorithm = AlgorithmArgon2id
+	saltLength       = 16
+	separator        = "$"
+)
+
+var ErrorUnknownAlgorithm = errors.New("未知的密码加密算法")
+
+// Hasher: 密码加密算法的接口， 新的算法实现这个接口之后调用Register注册即可
+type Hasher interface {
+	// Algorithm: 算法名， 会作为前缀保存在密码中
+	Algorithm() string
+	// Hash: 对密码进行加密， 返回不带算法前缀的编码结果
+	Hash(password string, salt []byte) (string, error)
+	// Verify: 验证密码是否和编码结果匹配
+	Verify(password string, salt []byte, encoded string) (bool, error)
+}
+
+var (
+	mu      sync.RWMutex
+	hashers = map[string]Hasher{}
+)
+
+func init() {
+	Register(&Argon2id{Time: 1, Memory: 64 * 1024, Threads: 4, KeyLen: 32})
+	Register(&Bcrypt{Cost: 10})
+	Register(&legacyMD5{})
+}
+
+// Register: 注册一个加密算法， 同名的算法会被覆盖
+func Register(h Hasher) {
+	mu.Lock()
+	defer mu.Unlock()
+	hashers[h.Algorithm()] = h
+}
+
+func getHasher(algorithm string) (Hasher, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	h, ok := hashers[algorithm]
+	if !ok {
+		return nil, ErrorUnknownAlgorithm
+	}
+	return h, nil
+}
+
+// currentAlgorithm: 当前用于生成新密码的算法， 可以在配置文件中修改
+func currentAlgorithm() string {
+	if cfg := settings.Conf.PasswordConfig; cfg != nil && cfg.Algorithm != "" && cfg.Algorithm != AlgorithmMD5 {
+		return cfg.Algorithm
+	}
+	return defaultAlgorithm
+}
+
+// Hash: 使用当前算法对密码进行加密， 返回带算法前缀的密码和新生成的盐
+func Hash(password string) (hashed, salt string, err error) {
+	h, err := getHasher(currentAlgorithm())
+	if err != nil {
+		return "", "", err
+	}
+	saltBytes := make([]byte, saltLength)
+	if _, err = rand.Read(saltBytes); err != nil {
+		return "", "", err
+	}
+	encoded, err := h.Hash(password, saltBytes)
+	if err != nil {
+		return "", "", err
+	}
+	return h.Algorithm() + separator + encoded, base64.RawStdEncoding.EncodeToString(saltBytes), nil
+}
+
+// Verify: 验证密码是否正确， needRehash 表示密码是使用旧算法加密的， 需要使用当前算法重新加密
+func Verify(password, salt, hashed string) (ok, needRehash bool, err error) {
+	algorithm, encoded := parse(hashed)
+	h, err := getHasher(algorithm)
+	if err != nil {
+		return false, false, err
+	}
+	saltBytes, err := base64.RawStdEncoding.DecodeString(salt)
+	if err != nil {
+		return false, false, err
+	}
+	if ok, err = h.Verify(password, saltBytes, encoded); err != nil || !ok {
+		return false, false, err
+	}
+	return true, algorithm != currentAlgorithm(), nil
+}
+
+// parse: 拆分出算法名和编码结果， 没有前缀的是旧的md5密码
+func parse(hashed string) (algorithm, encoded string) {
+	idx := strings.Index(hashed, separator)
+	if idx <= 0 {
+		return AlgorithmMD5, hashed
+	}
+	return hashed[:idx], hashed[idx+1:]
+}
@@ -0,0 +1,52 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashAndVerify(t *testing.T) {
+	hashed, salt, err := Hash("secret123")
+	if err != nil {
+		t.Fatalf("Hash failed, err: %v\n", err)
+	}
+	assert.True(t, strings.HasPrefix(hashed, AlgorithmArgon2id+separator))
+	assert.NotEmpty(t, salt)
+
+	ok, needRehash, err := Verify("secret123", salt, hashed)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.False(t, needRehash)
+
+	ok, _, err = Verify("wrong-password", salt, hashed)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerifyLegacyMD5(t *testing.T) {
+	// 旧版本 mysql.encryptPassword 生成的密码， 没有算法前缀也没有盐
+	legacy, _ := (&legacyMD5{}).Hash("secret123", nil)
+
+	ok, needRehash, err := Verify("secret123", "", legacy)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.True(t, needRehash)
+
+	ok, _, err = Verify("wrong-password", "", legacy)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerifyBcrypt(t *testing.T) {
+	encoded, err := (&Bcrypt{Cost: 4}).Hash("secret123", nil)
+	if err != nil {
+		t.Fatalf("bcrypt Hash failed, err: %v\n", err)
+	}
+
+	ok, needRehash, err := Verify("secret123", "", AlgorithmBcrypt+separator+encoded)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.True(t, needRehash) // 默认算法是argon2id
+}
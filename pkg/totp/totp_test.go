@@ -0,0 +1,43 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 6238 附录B中SHA1的测试向量， 取后6位
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, want := range cases {
+		code, err := Code(secret, Step(time.Unix(ts, 0)))
+		assert.Nil(t, err)
+		assert.Equal(t, want, code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.Nil(t, err)
+	now := time.Now()
+
+	code, err := Code(secret, Step(now)-1)
+	assert.Nil(t, err)
+	step, ok := Validate(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now)-1, step)
+
+	code, err = Code(secret, Step(now)-3)
+	assert.Nil(t, err)
+	_, ok = Validate(secret, code, now)
+	assert.False(t, ok)
+}
@@ -0,0 +1,82 @@
+package urlnorm
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+/*
+	规范化链接帖子的地址， 同一个链接的不同写法会得到相同的结果， 用于查找重复的链接
+*/
+
+const maxURLLength = 2048
+
+var ErrorInvalidURL = errors.New("无效的链接地址")
+
+// trackingParams: 不影响页面内容的跟踪参数， 规范化时去掉
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"igshid":  true,
+	"spm":     true,
+}
+
+// Normalize: 规范化链接地址
+// 1. 只支持http和https， scheme和域名转为小写， 去掉默认端口
+// 2. 去掉片段和跟踪参数， 剩下的查询参数按照名称排序
+// 3. 去掉路径末尾的斜杠， 空路径和根路径相同
+func Normalize(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || len(raw) > maxURLLength {
+		return "", ErrorInvalidURL
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", ErrorInvalidURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", ErrorInvalidURL
+	}
+	if u.Opaque != "" || u.Hostname() == "" {
+		return "", ErrorInvalidURL
+	}
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6
+	}
+	if port != "" {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+	}
+	u.Host = host
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	query := u.Query()
+	for key := range query {
+		if trackingParams[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+	for _, values := range query {
+		sort.Strings(values)
+	}
+	// url.Values.Encode会按照名称排序
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+	return u.String(), nil
+}
@@ -9,17 +9,26 @@ import (
 	gs "github.com/swaggo/gin-swagger"
 	"github.com/xiaorui/reddit-async/reddit-backend/controller"
 	"github.com/xiaorui/reddit-async/reddit-backend/logger"
+	"github.com/xiaorui/reddit-async/reddit-backend/logic"
 	"github.com/xiaorui/reddit-async/reddit-backend/middlewares"
+	"github.com/xiaorui/reddit-async/reddit-backend/models"
+	"go.uber.org/zap"
 )
 
-func Setup(mode string) *gin.Engine {
+// Setup: trustedProxies是前面的反向代理的地址， 为空时不信任任何代理， ClientIP直接使用连接的地址
+// 否则客户端可以伪造X-Forwarded-For绕过按照IP的限流
+func Setup(mode string, trustedProxies []string) *gin.Engine {
 	if mode == gin.ReleaseMode {
 		gin.SetMode(gin.ReleaseMode) // 设置为发布模式
 	}
 	r := gin.New() // 我尝试进行新的变化
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		zap.L().Fatal("invalid trusted_proxies", zap.Error(err))
+	}
 	r.Use(logger.GinLogger(), logger.GinRecovery(true))
 	// r.Use(middlewares.RateLimitMiddleware(time.Second*2, 10)) // 2s新增1个令牌， 容量为10
 	r.GET("/swagger/*any", gs.WrapHandler(swaggerFiles.Handler))
+	r.GET("/.well-known/jwks.json", controller.JWKS) // 下游服务通过这个接口获取验证token的公钥
 	r.GET("test", func(ctx *gin.Context) {
 		ctx.String(http.StatusOK, "CD测试成功")
 	})
@@ -47,17 +56,29 @@ func Setup(mode string) *gin.Engine {
 			// 注册方式： 1. 手机+密码 2. 邮箱+密码
 			authGroup.POST("/signup/phone", controller.SignupUsingPhone)
 			authGroup.POST("/signup/email", controller.SignupUsingEmail)
+			// 注册方式： 开放注册、邀请注册或者关闭注册
+			authGroup.GET("/signup/mode", controller.GetRegistrationMode)
 
 			// 登录相关
-			// 登录方式： 1. 手机+验证码， 2. 邮箱——密码
+			// 登录方式： 1. 手机+验证码， 2. 邮箱——密码， 3. 用户名/邮箱/手机号+密码
 			authGroup.POST("/login/phone", controller.LoginUsingPhone)
 			authGroup.POST("/login/email", controller.LoginUsingEmail)
-			//authGroup.POST("/login/username", controller.LoginUsingUsername)
-			authGroup.GET("/login/refresh-token", controller.RefreshToken)
+			authGroup.POST("/login/password", controller.LoginUsingPassword)
+			authGroup.POST("/login/2fa", controller.LoginTwoFactor) // 开启两步验证的用户使用验证码完成登录
+			authGroup.POST("/login/refresh-token", controller.RefreshToken)
+			authGroup.POST("/logout", middlewares.JWTAuthMiddleware(), controller.Logout)
 
 			// 重置密码
-			//authGroup.POST("/password/phone", nil)
-			//authGroup.POST("/password/email", nil)
+			authGroup.POST("/password/phone", controller.ResetPasswordUsingPhone)
+			authGroup.POST("/password/email", controller.ResetPasswordUsingEmail)
+
+			// 第三方登录(OpenID Connect)， 提供方在配置文件中设置
+			authGroup.GET("/oidc/:provider/login", controller.OIDCLogin)
+			authGroup.POST("/oidc/:provider/callback", controller.OIDCCallback)
+
+			// 修改邮箱或手机号码之后， 发给旧联系方式的撤销链接
+			authGroup.GET("/contact/revert", controller.ShowRevertContactChange)
+			authGroup.POST("/contact/revert", controller.RevertContactChange)
 		}
 		// 专门给周报使用的端点
 		v1.GET("/week_report", controller.GetPostListHandler0)
@@ -67,8 +88,14 @@ func Setup(mode string) *gin.Engine {
 
 		// 后面的所有请求都需要使用这个中间件，即需要验证是否进行了登陆
 		v1.Use(middlewares.JWTAuthMiddleware()) // 调用Use这个方法， 传入的中间件会被注入当下这个路由组中
-		// 创建用户相关的路由组
-		usersGroup := v1.Group("/user")
+		// 个人访问令牌只能访问权限范围内的接口， 使用JWT登录时不受限制
+		scopeRead := middlewares.RequireScope(models.ScopeRead)
+		scopePost := middlewares.RequireScope(models.ScopePost)
+		scopeVote := middlewares.RequireScope(models.ScopeVote)
+		scopeModerate := middlewares.RequireScope(models.ScopeModerate)
+
+		// 创建用户相关的路由组， 账号相关的操作不能使用个人访问令牌
+		usersGroup := v1.Group("/user", middlewares.SessionOnly())
 		{
 			usersGroup.GET("", controller.CurrentUser)
 			usersGroup.PUT("", controller.UpdateProfile) // 更新用户信息
@@ -76,33 +103,128 @@ func Setup(mode string) *gin.Engine {
 			usersGroup.PUT("/phone", controller.UpdatePhone)
 			usersGroup.PUT("/password", controller.UpdatePassword) // 更改密码
 			usersGroup.PUT("/avatar", controller.UpdateAvatar)     // 更新头像
+
+			usersGroup.GET("/sessions", controller.GetSessions)          // 查看所有登录的设备
+			usersGroup.DELETE("/sessions/:id", controller.DeleteSession) // 远程注销某个设备
+
+			// 两步验证
+			usersGroup.POST("/2fa/totp", controller.EnrollTOTP)          // 获取密钥
+			usersGroup.POST("/2fa/totp/confirm", controller.ConfirmTOTP) // 确认开启
+			usersGroup.DELETE("/2fa/totp", controller.DisableTOTP)       // 关闭
+
+			usersGroup.PUT("/privacy", controller.UpdatePrivacy) // 隐私设置
+			usersGroup.GET("/export", controller.ExportUserData) // 导出个人数据
+			usersGroup.DELETE("", controller.DeleteAccount)      // 注销账号
+
+			// 个人访问令牌
+			usersGroup.GET("/tokens", controller.GetAccessTokens)
+			usersGroup.POST("/tokens", controller.CreateAccessToken)
+			usersGroup.DELETE("/tokens/:id", controller.RevokeAccessToken)
+
+			// 屏蔽和静音的人
+			usersGroup.GET("/blocks", controller.GetBlockedUsers)
+			usersGroup.GET("/mutes", controller.GetMutedUsers)
+
+			// 草稿， 包括定时发布的草稿
+			usersGroup.GET("/drafts", controller.GetDrafts)
+		}
+
+		// 站点管理员使用的接口
+		adminGroup := v1.Group("/admin", middlewares.SessionOnly(), middlewares.AdminOnly())
+		{
+			adminGroup.GET("/invitations", controller.GetInvitations)
+			adminGroup.POST("/invitations", controller.CreateInvitation)
+			adminGroup.DELETE("/invitations/:id", controller.RevokeInvitation)
+		}
+
+		// 其他用户公开的个人信息和动态
+		profileGroup := v1.Group("/users")
+		{
+			profileGroup.GET("/:id", scopeRead, controller.GetUserProfile)
+			profileGroup.GET("/by-name/:username", scopeRead, controller.GetUserProfileByName)
+			profileGroup.GET("/:id/posts", scopeRead, controller.GetUserPosts)
+			profileGroup.GET("/:id/comments", scopeRead, controller.GetUserComments)
+			profileGroup.GET("/:id/upvoted", scopeRead, controller.GetUserUpvotedPosts) // 点赞过的帖子， 可以在隐私设置中隐藏
+
+			// 关注
+			profileGroup.POST("/:id/follow", middlewares.SessionOnly(), controller.FollowUser)
+			profileGroup.DELETE("/:id/follow", middlewares.SessionOnly(), controller.UnfollowUser)
+			profileGroup.GET("/:id/followers", scopeRead, controller.GetFollowers)
+			profileGroup.GET("/:id/following", scopeRead, controller.GetFollowing)
+
+			// 屏蔽和静音
+			profileGroup.POST("/:id/block", middlewares.SessionOnly(), controller.BlockUser)
+			profileGroup.DELETE("/:id/block", middlewares.SessionOnly(), controller.UnblockUser)
+			profileGroup.POST("/:id/mute", middlewares.SessionOnly(), controller.MuteUser)
+			profileGroup.DELETE("/:id/mute", middlewares.SessionOnly(), controller.UnmuteUser)
+		}
+
+		feedGroup := v1.Group("/feed")
+		{
+			feedGroup.GET("/following", scopeRead, controller.GetFollowingFeed) // 关注的人发布的帖子
 		}
 
 		commGroup := v1.Group("/community")
 		{
-			commGroup.POST("", controller.CreateNewCommunity)        // 新建社区
-			commGroup.GET("", controller.CommunityHandler)           //  获取所有社区信息
-			commGroup.GET("/:id", controller.CommunityDetailHandler) // 获取当个社区的详细信息
-			commGroup.PUT("/:id", controller.UpdateCommunity)        // 更新单个社区的信息
-			commGroup.DELETE("/:id", controller.DeleteCommunity)     // 删除某个社区
+			commGroup.POST("", scopeModerate, controller.CreateNewCommunity)    // 新建社区
+			commGroup.GET("", scopeRead, controller.CommunityHandler)           //  获取所有社区信息
+			commGroup.GET("/:id", scopeRead, controller.CommunityDetailHandler) // 获取当个社区的详细信息
+
+			// 更新单个社区的信息需要版主及以上的角色， 删除社区需要owner
+			commGroup.PUT("/:id", scopeModerate, middlewares.CommunityPermission(logic.PermUpdateCommunity), controller.UpdateCommunity)
+			commGroup.DELETE("/:id", scopeModerate, middlewares.CommunityPermission(logic.PermDeleteCommunity), controller.DeleteCommunity)
+			// 发帖的最低karma和注册天数
+			commGroup.PUT("/:id/requirements", scopeModerate, middlewares.CommunityPermission(logic.PermUpdateCommunity), controller.SetCommunityRequirements)
+			// 待审核的帖子
+			commGroup.GET("/:id/queue", scopeModerate, middlewares.CommunityPermission(logic.PermManagePosts), controller.GetModerationQueue)
+
+			// 社区的角色管理
+			commGroup.GET("/:id/members", scopeRead, controller.GetCommunityMembers)
+			commGroup.PUT("/:id/members/:user_id", scopeModerate, middlewares.CommunityPermission(logic.PermManageCommunityMembers), controller.SetCommunityMemberRole)
+			commGroup.DELETE("/:id/members/:user_id", scopeModerate, middlewares.CommunityPermission(logic.PermManageCommunityMembers), controller.RemoveCommunityMember)
 		}
 
 		postGroup := v1.Group("/post")
 		{
-			postGroup.POST("", controller.CreatePostHandler) // 创建帖子
-			postGroup.GET("/:id", controller.GetPostHandler) // 获取某个具体帖子的信息
+			postGroup.POST("", scopePost, controller.CreatePostHandler) // 创建帖子
+			postGroup.GET("/:id", scopeRead, controller.GetPostHandler) // 获取某个具体帖子的信息
 			//postGroup.GET("/posts", controller.GetPostListHandler)
-			postGroup.GET("/posts2", controller.GetPostListHandler0) // 不定社区
-			postGroup.POST("/vote", controller.PostVoteHandler)      // 对于某个帖子进行投票
-			postGroup.GET("/posts3", controller.GetPostListHandler0) // 给定社区
+			postGroup.GET("/posts2", scopeRead, controller.GetPostListHandler0) // 不定社区
+			postGroup.POST("/vote", scopeVote, controller.PostVoteHandler)      // 对于某个帖子进行投票
+			postGroup.GET("/posts3", scopeRead, controller.GetPostListHandler0) // 给定社区
 
-			postGroup.DELETE("/:id", controller.DeletePost) // 删除删除
+			postGroup.DELETE("/:id", scopePost, controller.DeletePost) // 删除删除
+			postGroup.PUT("/:id", scopePost, controller.UpdatePost)    // 编辑帖子， 每次编辑都会保存一个版本
+
+			// 恢复删除的帖子， 作者和社区的版主在恢复期限内可以恢复
+			postGroup.POST("/:id/restore", scopePost, controller.RestorePost)
+
+			// 图片帖子的图片和投票帖子的投票
+			postGroup.POST("/images", scopePost, controller.UploadPostImage)
+			postGroup.POST("/:id/poll", scopeVote, controller.VotePoll)
+
+			// 草稿的定时发布和立即发布
+			postGroup.PUT("/:id/schedule", scopePost, controller.SchedulePost)
+			postGroup.POST("/:id/publish", scopePost, controller.PublishPost)
+
+			// 帖子的审核， 社区的版主可以通过、拒绝、锁定帖子
+			postGroup.POST("/:id/approve", scopeModerate, controller.ApprovePost)
+			postGroup.POST("/:id/reject", scopeModerate, controller.RejectPost)
+			postGroup.POST("/:id/lock", scopeModerate, controller.LockPost)
+			postGroup.DELETE("/:id/lock", scopeModerate, controller.UnlockPost)
+
+			// 编辑记录， 作者和社区的版主可以查看
+			postGroup.GET("/:id/revisions", scopeRead, controller.GetPostRevisions)
+			postGroup.GET("/:id/revisions/:version/diff", scopeRead, controller.GetPostRevisionDiff)
 
 			commentGroup := postGroup.Group("/comment")
 			{
-				commentGroup.POST("/:post_id", controller.CreateComment)      // 给某个post发送一个comment
-				commentGroup.GET("/:post_id", controller.GetComment)          // 获取某个post的所有comment
-				commentGroup.DELETE("/:comment_id", controller.DeleteComment) // 删除某个comment
+				commentGroup.POST("/:post_id", scopePost, controller.CreateComment)      // 给某个post发送一个comment
+				commentGroup.GET("/:post_id", scopeRead, controller.GetComment)          // 获取某个post的所有comment
+				commentGroup.DELETE("/:comment_id", scopePost, controller.DeleteComment) // 删除某个comment
+
+				// 恢复删除的comment， 和其他路由的参数名不同， 所以放在/restore下面
+				commentGroup.POST("/restore/:comment_id", scopePost, controller.RestoreComment)
 			}
 		}
 	}
@@ -2,6 +2,7 @@ package settings
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
@@ -10,17 +11,24 @@ import (
 var Conf = new(AppConfig)
 
 type AppConfig struct {
-	Name         string `mapstructure:"name"`
-	Mode         string `mapstructure:"mode"`
-	Version      string `mapstructure:"version"`
-	Port         int    `mapstructure:"port"`
-	StartTime    string `mapstructure:"start_time"`
-	MachineID    int64  `mapstructure:"machine_id"`
-	*LogConfig   `mapstructure:"log"`
-	*MySQLConfig `mapstructure:"mysql"`
-	*RedisConfig `mapstructure:"redis"`
-	*SmsConfig   `mapstructure:"sms"`
-	*EmailConfig `mapstructure:"email"`
+	Name            string `mapstructure:"name"`
+	Mode            string `mapstructure:"mode"`
+	Version         string `mapstructure:"version"`
+	Port            int    `mapstructure:"port"`
+	StartTime       string `mapstructure:"start_time"`
+	MachineID       int64  `mapstructure:"machine_id"`
+	*LogConfig      `mapstructure:"log"`
+	*MySQLConfig    `mapstructure:"mysql"`
+	*RedisConfig    `mapstructure:"redis"`
+	*SmsConfig      `mapstructure:"sms"`
+	*EmailConfig    `mapstructure:"email"`
+	*PasswordConfig `mapstructure:"password"`
+	*AuthConfig     `mapstructure:"auth"`
+	*OIDCConfig     `mapstructure:"oidc"`
+	// 注册方式和邀请码
+	*RegistrationConfig `mapstructure:"registration"`
+	// 反向代理的IP或者CIDR， 只有来自这些地址的请求才会使用X-Forwarded-For作为客户端IP， 默认为空
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 type LogConfig struct {
@@ -73,6 +81,53 @@ type FromConfig struct {
 	Name    string `mapstructure:"name"`
 }
 
+type PasswordConfig struct {
+	Algorithm string `mapstructure:"algorithm"` // 新密码使用的加密算法： argon2id 或 bcrypt
+}
+
+type AuthConfig struct {
+	Issuer             string         `mapstructure:"issuer"`
+	AccessTokenExpire  time.Duration  `mapstructure:"access_token_expire"`  // 例如 "24h"
+	RefreshTokenExpire time.Duration  `mapstructure:"refresh_token_expire"` // 例如 "30h"
+	SigningKeyID       string         `mapstructure:"signing_key_id"`       // 用来签发新token的密钥， 其余密钥只用来验证
+	Keys               []JWTKeyConfig `mapstructure:"keys"`
+	PublicURL          string         `mapstructure:"public_url"` // 服务对外的地址， 用于生成邮件中的链接
+}
+
+// JWTKeyConfig: 签名密钥， 轮换时先加入新密钥并切换 signing_key_id， 等旧token全部过期之后再删除旧密钥
+type JWTKeyConfig struct {
+	ID             string `mapstructure:"id"`               // 写入token头部的kid
+	Algorithm      string `mapstructure:"algorithm"`        // HS256, RS256 或 EdDSA
+	Secret         string `mapstructure:"secret"`           // HS256使用
+	PrivateKeyFile string `mapstructure:"private_key_file"` // RS256/EdDSA使用， PEM格式， 只用来验证的密钥可以不配置
+	PublicKeyFile  string `mapstructure:"public_key_file"`  // RS256/EdDSA使用， PEM格式， 配置了私钥时可以省略
+}
+
+// OIDCConfig: 第三方登录， 每个提供方需要先在对方平台注册应用
+type OIDCConfig struct {
+	Providers []OIDCProviderConfig `mapstructure:"providers"`
+}
+
+type OIDCProviderConfig struct {
+	Name         string   `mapstructure:"name"`   // 路由中使用的名称， 例如 google
+	Issuer       string   `mapstructure:"issuer"` // 通过 <issuer>/.well-known/openid-configuration 获取各个端点
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"` // 前端的回调页面， 拿到code和state之后调用 /auth/oidc/:provider/callback
+	Scopes       []string `mapstructure:"scopes"`       // 默认为 openid email profile
+}
+
+// 注册方式
+const (
+	RegistrationOpen   = "open"   // 任何人都可以注册
+	RegistrationInvite = "invite" // 需要管理员生成的邀请码
+	RegistrationClosed = "closed" // 不允许注册新用户
+)
+
+type RegistrationConfig struct {
+	Mode string `mapstructure:"mode"` // open, invite 或 closed， 默认为 open
+}
+
 func Init(filename string) (err error) {
 	// viper.SetConfigName("config")
 	// // viper.SetConfigType("yaml")